@@ -6,34 +6,248 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"text/tabwriter"
 
+	"github.com/collinvandyck/gpterm/lib/credential"
 	"github.com/collinvandyck/gpterm/lib/store"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
+const defaultCredentialBackend = "sqlite"
+
 func Auth() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "auth",
 		Short: "Sets the OpenAPI key",
-		RunE: func(_ *cobra.Command, _ []string) error {
+		Long: `Sets the OpenAPI key.
+
+Credentials are resolved from these backends, in order:
+
+  env      GPTERM_<NAME> environment variables (OPENAI_API_KEY also works for api_key),
+           or GPTERM_<PROFILE>_<NAME> for profiles other than the default
+  command  the output of a configured command, e.g. 'pass show openai'
+  file     a passphrase-encrypted file in the gpterm config dir
+  sqlite   the credential table in the gpterm database
+
+Run without a subcommand to set the API key in the sqlite backend.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return setCredential(cmd, store.CredentialAPIKey, defaultCredentialBackend, false)
+		},
+	}
+	cmd.AddCommand(authList())
+	cmd.AddCommand(authSet())
+	cmd.AddCommand(authRm())
+	cmd.AddCommand(authTest())
+	return cmd
+}
+
+func authList() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List credentials and the backends they are stored in",
+		RunE: func(cmd *cobra.Command, _ []string) error {
 			ctx := context.Background()
-			fmt.Print("OpenAPI key: ")
-			s := bufio.NewScanner(os.Stdin)
-			s.Scan()
-			key := s.Text()
-			if key == "" {
-				return errors.New("no key supplied")
-			}
-			str, err := store.New()
+			str, err := OpenStore(cmd)
 			if err != nil {
 				return fmt.Errorf("store: %w", err)
 			}
-			err = str.SetCredential(ctx, store.CredentialAPIKey, key)
+			defer str.Close()
+			entries, err := credential.New(str).List(ctx)
+			if err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tBACKEND\tVALUE")
+			for _, e := range entries {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Name, e.Backend, e.Description)
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+func authSet() *cobra.Command {
+	var (
+		backend  string
+		skipTest bool
+	)
+	cmd := &cobra.Command{
+		Use:   "set [name]",
+		Short: "Set a credential, validating it first",
+		Long: `Set a credential, validating it first. The name defaults to api_key.
+
+With --backend command, the value is a shell command whose output is the
+credential, e.g. 'pass show openai'.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := store.CredentialAPIKey
+			if len(args) > 0 {
+				name = args[0]
+			}
+			return setCredential(cmd, name, backend, skipTest)
+		},
+	}
+	cmd.Flags().StringVarP(&backend, "backend", "b", defaultCredentialBackend, "backend to store the credential in (command, file, sqlite)")
+	cmd.Flags().BoolVar(&skipTest, "skip-test", false, "save the credential without validating it")
+	return cmd
+}
+
+func authRm() *cobra.Command {
+	var backend string
+	cmd := &cobra.Command{
+		Use:   "rm [name]",
+		Short: "Remove a credential",
+		Long:  "Remove a credential. Without --backend it is removed from every backend that can be written to.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			name := args[0]
+			str, err := OpenStore(cmd)
 			if err != nil {
-				return fmt.Errorf("set api key: %w", err)
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			creds := credential.New(str)
+			backends := creds.Backends()
+			if backend != "" {
+				backends = []string{backend}
+			}
+			for _, bn := range backends {
+				b, err := creds.Backend(bn)
+				if err != nil {
+					return err
+				}
+				names, err := b.List(ctx)
+				if err != nil {
+					return fmt.Errorf("%s: %w", bn, err)
+				}
+				if !contains(names, name) {
+					continue
+				}
+				err = b.Delete(ctx, name)
+				switch {
+				case errors.Is(err, credential.ErrReadOnly):
+					fmt.Printf("%s is set in the %s backend, which is read-only\n", name, bn)
+				case err != nil:
+					return fmt.Errorf("%s: %w", bn, err)
+				default:
+					fmt.Printf("Removed %s from the %s backend\n", name, bn)
+				}
 			}
-			fmt.Println("API key set")
 			return nil
 		},
 	}
+	cmd.Flags().StringVarP(&backend, "backend", "b", "", "only remove the credential from this backend")
+	return cmd
+}
+
+func authTest() *cobra.Command {
+	return &cobra.Command{
+		Use:   "test [name]",
+		Short: "Validate a credential against its provider",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			name := store.CredentialAPIKey
+			if len(args) > 0 {
+				name = args[0]
+			}
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			entry, err := credential.New(str).Lookup(ctx, name)
+			if err != nil {
+				return err
+			}
+			if entry.Value == "" {
+				return fmt.Errorf("%s is not set", name)
+			}
+			err = credential.Validate(ctx, name, entry.Value)
+			if err != nil {
+				return fmt.Errorf("%s from the %s backend is invalid: %w", name, entry.Backend, err)
+			}
+			fmt.Printf("%s from the %s backend is valid\n", name, entry.Backend)
+			return nil
+		},
+	}
+}
+
+func setCredential(cmd *cobra.Command, name string, backend string, skipTest bool) error {
+	ctx := context.Background()
+	str, err := OpenStore(cmd)
+	if err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	defer str.Close()
+	creds := credential.New(str)
+	b, err := creds.Backend(backend)
+	if err != nil {
+		return err
+	}
+	var value string
+	if backend == "command" {
+		value, err = readLine("Command: ")
+	} else {
+		value, err = readSecret(fmt.Sprintf("%s: ", name))
+	}
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return errors.New("no value supplied")
+	}
+	if !skipTest {
+		secret := value
+		if backend == "command" {
+			// validate what the command produces, not the command itself
+			secret, err = credential.RunCommand(ctx, value)
+			if err != nil {
+				return err
+			}
+		}
+		if err := credential.Validate(ctx, name, secret); err != nil {
+			return fmt.Errorf("%s was not saved: %w", name, err)
+		}
+	}
+	err = b.Set(ctx, name, value)
+	if err != nil {
+		return fmt.Errorf("set %s: %w", name, err)
+	}
+	fmt.Printf("%s set in the %s backend\n", name, backend)
+	return nil
+}
+
+func readLine(prompt string) (string, error) {
+	fmt.Print(prompt)
+	s := bufio.NewScanner(os.Stdin)
+	s.Scan()
+	return strings.TrimSpace(s.Text()), s.Err()
+}
+
+// readSecret reads a value without echoing it when stdin is a terminal.
+func readSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return readLine(prompt)
+	}
+	fmt.Print(prompt)
+	bs, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(bs)), nil
+}
+
+func contains(vals []string, val string) bool {
+	for _, v := range vals {
+		if v == val {
+			return true
+		}
+	}
+	return false
 }
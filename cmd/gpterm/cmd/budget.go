@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/collinvandyck/gpterm/lib/pricing"
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/spf13/cobra"
+)
+
+func Budget() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "budget",
+		Short: "Show and manage monthly budgets",
+		Long: `Show this month's spend against the budgets of the current profile.
+
+A budget can be set for the profile as a whole and for individual models.
+Warnings are shown in the status bar once the spend reaches a threshold. A
+request that would go over a budget asks to be sent again, or is refused if
+the hard cap is set to refuse.
+
+Spend is estimated from the usage recorded for each response and the price
+table, which can be changed with the price subcommand.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer str.Close()
+			budget, err := str.Budget(ctx)
+			if err != nil {
+				return err
+			}
+			spend, err := str.MonthSpend(ctx, time.Now())
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Spend since %s\n\n", spend.Since.Format("2006-01-02"))
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "SCOPE\tSPENT\tBUDGET\tUSED")
+			printBudgetRow(tw, "profile", spend.Total, budget.Monthly)
+			models := map[string]bool{}
+			for model := range spend.Models {
+				models[model] = true
+			}
+			for model := range budget.Models {
+				models[model] = true
+			}
+			names := make([]string, 0, len(models))
+			for model := range models {
+				names = append(names, model)
+			}
+			sort.Strings(names)
+			for _, model := range names {
+				printBudgetRow(tw, model, spend.Models[model], budget.Models[model])
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			fmt.Println()
+			fmt.Printf("Thresholds: %s\n", formatThresholds(budget.Thresholds))
+			fmt.Printf("Hard cap:   %s\n", budget.HardCap)
+			if spend.Unpriced > 0 {
+				fmt.Printf("\n%d tokens were used by models without a price and are not included.\n", spend.Unpriced)
+			}
+			return nil
+		},
+	}
+	cmd.AddCommand(budgetSet())
+	cmd.AddCommand(budgetRm())
+	cmd.AddCommand(budgetThresholds())
+	cmd.AddCommand(budgetHardCap())
+	cmd.AddCommand(budgetPrice())
+	return cmd
+}
+
+func budgetSet() *cobra.Command {
+	var model string
+	cmd := &cobra.Command{
+		Use:   "set [usd]",
+		Short: "Set the monthly budget for the profile or a model",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			limit, err := strconv.ParseFloat(args[0], 64)
+			if err != nil || limit <= 0 {
+				return fmt.Errorf("invalid budget %q: use a positive amount in USD", args[0])
+			}
+			return updateBudget(cmd, model, limit)
+		},
+	}
+	cmd.Flags().StringVarP(&model, "model", "m", "", "set the budget for this model instead of the profile")
+	return cmd
+}
+
+func budgetRm() *cobra.Command {
+	var model string
+	cmd := &cobra.Command{
+		Use:   "rm",
+		Short: "Remove the monthly budget for the profile or a model",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return updateBudget(cmd, model, 0)
+		},
+	}
+	cmd.Flags().StringVarP(&model, "model", "m", "", "remove the budget for this model instead of the profile")
+	return cmd
+}
+
+func updateBudget(cmd *cobra.Command, model string, limit float64) error {
+	ctx := context.Background()
+	str, err := OpenStore(cmd)
+	if err != nil {
+		return err
+	}
+	defer str.Close()
+	if err := str.SetBudget(ctx, model, limit); err != nil {
+		return err
+	}
+	scope := "the profile"
+	if model != "" {
+		prices, err := str.PriceTable(ctx)
+		if err != nil {
+			return err
+		}
+		scope = prices.Canonical(model)
+	}
+	if limit == 0 {
+		fmt.Printf("Removed the budget for %s\n", scope)
+		return nil
+	}
+	fmt.Printf("Set the monthly budget for %s to $%.2f\n", scope, limit)
+	return nil
+}
+
+func budgetThresholds() *cobra.Command {
+	return &cobra.Command{
+		Use:   "thresholds [percentages]",
+		Short: "Set the percentages of a budget at which warnings are shown, such as 50,80",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			thresholds, err := store.ParseBudgetThresholds(args[0])
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer str.Close()
+			if err := str.SetBudgetThresholds(ctx, thresholds); err != nil {
+				return err
+			}
+			fmt.Printf("Warnings are shown at %s\n", formatThresholds(thresholds))
+			return nil
+		},
+	}
+}
+
+func budgetHardCap() *cobra.Command {
+	return &cobra.Command{
+		Use:       "hard-cap [confirm|refuse]",
+		Short:     "Set whether requests over budget need confirmation or are refused",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{string(store.HardCapConfirm), string(store.HardCapRefuse)},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			hc, err := store.ParseHardCap(args[0])
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer str.Close()
+			if err := str.SetBudgetHardCap(ctx, hc); err != nil {
+				return err
+			}
+			fmt.Printf("Requests over budget will %s\n", map[store.HardCap]string{
+				store.HardCapConfirm: "need to be sent again",
+				store.HardCapRefuse:  "be refused",
+			}[hc])
+			return nil
+		},
+	}
+}
+
+func budgetPrice() *cobra.Command {
+	var remove bool
+	cmd := &cobra.Command{
+		Use:   "price [model] [input,output]",
+		Short: "List prices, or set the price of a model in USD per million tokens",
+		Args:  cobra.MaximumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer str.Close()
+			switch {
+			case remove && len(args) == 1:
+				return str.DeleteConfig(ctx, store.ConfigPricePrefix+args[0])
+			case len(args) == 2:
+				price, err := pricing.ParsePrice(args[1])
+				if err != nil {
+					return err
+				}
+				return str.SetConfigString(ctx, store.ConfigPricePrefix+args[0], price.String())
+			case len(args) == 1 || remove:
+				return cmd.Usage()
+			}
+			prices, err := str.PriceTable(ctx)
+			if err != nil {
+				return err
+			}
+			models := make([]string, 0, len(prices))
+			for model := range prices {
+				models = append(models, model)
+			}
+			sort.Strings(models)
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "MODEL\tINPUT\tOUTPUT")
+			for _, model := range models {
+				fmt.Fprintf(tw, "%s\t$%g\t$%g\n", model, prices[model].Input, prices[model].Output)
+			}
+			return tw.Flush()
+		},
+	}
+	cmd.Flags().BoolVarP(&remove, "remove", "r", false, "remove a price that was set, restoring the default")
+	return cmd
+}
+
+func printBudgetRow(tw *tabwriter.Writer, scope string, spent, limit float64) {
+	if limit <= 0 {
+		fmt.Fprintf(tw, "%s\t$%.2f\t-\t-\n", scope, spent)
+		return
+	}
+	fmt.Fprintf(tw, "%s\t$%.2f\t$%.2f\t%.0f%%\n", scope, spent, limit, spent/limit*100)
+}
+
+func formatThresholds(thresholds []int) string {
+	if len(thresholds) == 0 {
+		return "none"
+	}
+	res := ""
+	for i, t := range thresholds {
+		if i > 0 {
+			res += ", "
+		}
+		res += fmt.Sprintf("%d%%", t)
+	}
+	return res
+}
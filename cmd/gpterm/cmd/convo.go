@@ -0,0 +1,466 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/collinvandyck/gpterm/lib/persona"
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/spf13/cobra"
+)
+
+func Convo() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "convo",
+		Short: "Manage conversations",
+	}
+	cmd.AddCommand(convoList())
+	cmd.AddCommand(convoFlag("protect", "Protect a conversation from being dropped", func(ctx context.Context, str *store.Store, id int64) error {
+		return str.SetConversationProtected(ctx, id, true)
+	}))
+	cmd.AddCommand(convoFlag("unprotect", "Allow a conversation to be dropped again", func(ctx context.Context, str *store.Store, id int64) error {
+		return str.SetConversationProtected(ctx, id, false)
+	}))
+	cmd.AddCommand(convoFlag("archive", "Hide a conversation from navigation", func(ctx context.Context, str *store.Store, id int64) error {
+		return str.SetConversationArchived(ctx, id, true)
+	}))
+	cmd.AddCommand(convoFlag("unarchive", "Return an archived conversation to navigation", func(ctx context.Context, str *store.Store, id int64) error {
+		return str.SetConversationArchived(ctx, id, false)
+	}))
+	cmd.AddCommand(convoFlag("restore", "Restore a dropped conversation from the trash", func(ctx context.Context, str *store.Store, id int64) error {
+		return str.RestoreConversation(ctx, id)
+	}))
+	cmd.AddCommand(convoSet())
+	cmd.AddCommand(convoTag())
+	cmd.AddCommand(convoTags())
+	cmd.AddCommand(convoSearch())
+	cmd.AddCommand(convoExport())
+	cmd.AddCommand(convoTrash())
+	cmd.AddCommand(convoGC())
+	return cmd
+}
+
+func convoList() *cobra.Command {
+	var (
+		all bool
+		tag string
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List conversations",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			convos, err := str.ListConversations(ctx)
+			if err != nil {
+				return err
+			}
+			tags, err := str.GetAllConversationTags(ctx)
+			if err != nil {
+				return err
+			}
+			tagged, err := taggedConversations(ctx, str, tag)
+			if err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tNAME\tMESSAGES\tTAGS\tFLAGS")
+			for _, c := range convos {
+				if c.DeletedAt.Valid || (c.Archived != 0 && !all) {
+					continue
+				}
+				if tagged != nil && !tagged[c.ID] {
+					continue
+				}
+				var flags []string
+				if c.Selected != 0 {
+					flags = append(flags, "selected")
+				}
+				if c.Protected != 0 {
+					flags = append(flags, "protected")
+				}
+				if c.Archived != 0 {
+					flags = append(flags, "archived")
+				}
+				fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\n", c.ID, c.Name.String, c.MessageCount,
+					strings.Join(tags[c.ID], ","), strings.Join(flags, ","))
+			}
+			return tw.Flush()
+		},
+	}
+	cmd.Flags().BoolVarP(&all, "all", "a", false, "include archived conversations")
+	cmd.Flags().StringVarP(&tag, "tag", "t", "", "only list conversations with this tag")
+	return cmd
+}
+
+func convoTag() *cobra.Command {
+	var remove bool
+	cmd := &cobra.Command{
+		Use:   "tag [id] [tags...]",
+		Short: "Tag a conversation, or show its tags",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid conversation id: %q", args[0])
+			}
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			for _, tag := range args[1:] {
+				if remove {
+					err = str.UntagConversation(ctx, id, tag)
+				} else {
+					err = str.TagConversation(ctx, id, tag)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			tags, err := str.GetConversationTags(ctx, id)
+			if err != nil {
+				return err
+			}
+			fmt.Println(strings.Join(tags, " "))
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&remove, "remove", "r", false, "remove the tags instead of adding them")
+	return cmd
+}
+
+func convoSet() *cobra.Command {
+	var (
+		clientConfig   string
+		messageContext int64
+		personaName    string
+	)
+	cmd := &cobra.Command{
+		Use:   "set [id]",
+		Short: "Change the model, context size or persona of a conversation, or show them",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid conversation id: %q", args[0])
+			}
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			if clientConfig != "" {
+				err = str.SetConversationClientConfig(ctx, id, clientConfig)
+				if err != nil {
+					return err
+				}
+			}
+			if cmd.Flags().Changed("context") {
+				err = str.SetConversationMessageContext(ctx, id, messageContext)
+				if err != nil {
+					return err
+				}
+			}
+			if personaName != "" {
+				if _, err := persona.Load(str.Dir(), personaName); err != nil {
+					return err
+				}
+				err = str.SetConversationPersona(ctx, id, personaName)
+				if err != nil {
+					return err
+				}
+			}
+			settings, err := str.ConversationSettings(ctx, id)
+			if err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "MODEL\tCONTEXT\tPERSONA")
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", settings.ClientConfig.Name, settings.ClientConfig.MessageContext, settings.Persona)
+			return tw.Flush()
+		},
+	}
+	cmd.Flags().StringVarP(&clientConfig, "model", "m", "", "the client config to use, e.g. gpt-4o")
+	cmd.Flags().Int64VarP(&messageContext, "context", "c", 0, "the number of messages to send as context")
+	cmd.Flags().StringVar(&personaName, "persona", "", "the persona to use")
+	return cmd
+}
+
+func convoTags() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tags",
+		Short: "List tags and how many conversations have them",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			tags, err := str.ListTags(ctx)
+			if err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "TAG\tCONVERSATIONS")
+			for _, t := range tags {
+				fmt.Fprintf(tw, "%s\t%d\n", t.Name, t.ConversationCount)
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+func convoSearch() *cobra.Command {
+	var tag string
+	cmd := &cobra.Command{
+		Use:   "search [text]",
+		Short: "Search messages in all conversations",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			text := strings.Join(args, " ")
+			msgs, err := str.SearchMessages(ctx, text, tag)
+			if err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "CONVO\tMESSAGE\tTIME\tROLE\tMATCH")
+			for _, m := range msgs {
+				fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\n", m.ConversationID, m.ID,
+					m.Timestamp.Local().Format(time.DateTime), m.Role, excerpt(m.Content, text, 60))
+			}
+			return tw.Flush()
+		},
+	}
+	cmd.Flags().StringVarP(&tag, "tag", "t", "", "only search conversations with this tag")
+	return cmd
+}
+
+func convoExport() *cobra.Command {
+	var (
+		tag    string
+		format string
+	)
+	cmd := &cobra.Command{
+		Use:   "export [ids...]",
+		Short: "Export conversations as markdown or json",
+		Long: `Export conversations to stdout as markdown or json. The active branch of each
+conversation is exported. Without ids or a tag the selected conversation is
+exported.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			if format != "markdown" && format != "json" {
+				return fmt.Errorf("unknown format %q", format)
+			}
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			var ids []int64
+			for _, arg := range args {
+				id, err := strconv.ParseInt(arg, 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid conversation id: %q", arg)
+				}
+				ids = append(ids, id)
+			}
+			if tag != "" {
+				tagged, err := str.GetConversationIDsForTag(ctx, tag)
+				if err != nil {
+					return err
+				}
+				ids = append(ids, tagged...)
+			}
+			if len(ids) == 0 && tag == "" {
+				convo, err := str.ActiveConversation(ctx)
+				if err != nil {
+					return err
+				}
+				ids = append(ids, convo.ID)
+			}
+			exports := make([]store.Export, 0, len(ids))
+			for _, id := range ids {
+				exp, err := exportConversation(ctx, str, id)
+				if err != nil {
+					return err
+				}
+				exports = append(exports, exp)
+			}
+			if format == "json" {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(exports)
+			}
+			for i, exp := range exports {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Print(exp.Markdown())
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&tag, "tag", "t", "", "export every conversation with this tag")
+	cmd.Flags().StringVarP(&format, "format", "f", "markdown", "output format (markdown, json)")
+	return cmd
+}
+
+func exportConversation(ctx context.Context, str *store.Store, id int64) (store.Export, error) {
+	thread, err := str.GetConversationThread(ctx, id)
+	if err != nil {
+		return store.Export{}, fmt.Errorf("conversation %d: %w", id, err)
+	}
+	convo, err := str.GetConversation(ctx, id)
+	if err != nil {
+		return store.Export{}, err
+	}
+	tags, err := str.GetConversationTags(ctx, id)
+	if err != nil {
+		return store.Export{}, err
+	}
+	return store.NewExport(convo, tags, thread), nil
+}
+
+// taggedConversations returns the set of conversations with the tag, or nil
+// if tag is empty.
+func taggedConversations(ctx context.Context, str *store.Store, tag string) (map[int64]bool, error) {
+	if tag == "" {
+		return nil, nil
+	}
+	ids, err := str.GetConversationIDsForTag(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+	res := map[int64]bool{}
+	for _, id := range ids {
+		res[id] = true
+	}
+	return res, nil
+}
+
+// excerpt returns a single line of content around the first match of text.
+func excerpt(content string, text string, width int) string {
+	content = strings.Join(strings.Fields(content), " ")
+	// lower casing maps rune for rune, so rune offsets line up.
+	lower := strings.ToLower(content)
+	start := 0
+	if idx := strings.Index(lower, strings.ToLower(text)); idx > 0 {
+		start = utf8.RuneCountInString(lower[:idx]) - width/2
+		start = max(start, 0)
+	}
+	runes := []rune(content)[start:]
+	res := string(runes)
+	if len(runes) > width {
+		res = string(runes[:width]) + "…"
+	}
+	if start > 0 {
+		res = "…" + res
+	}
+	return res
+}
+
+func convoFlag(use string, short string, fn func(context.Context, *store.Store, int64) error) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " [id]",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid conversation id: %q", args[0])
+			}
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			return fn(ctx, str, id)
+		},
+	}
+}
+
+func convoTrash() *cobra.Command {
+	return &cobra.Command{
+		Use:   "trash",
+		Short: "List dropped conversations that can still be restored",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			retention, err := str.TrashRetention(ctx)
+			if err != nil {
+				return err
+			}
+			trash, err := str.GetTrash(ctx)
+			if err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tNAME\tDROPPED\tEXPIRES")
+			for _, c := range trash {
+				dropped := c.DeletedAt.Time.Local()
+				expires := dropped.Add(retention)
+				fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", c.ID, c.Name.String,
+					dropped.Format(time.DateTime), expires.Format(time.DateTime))
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+func convoGC() *cobra.Command {
+	var days int
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Permanently delete expired conversations from the trash",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			retention, err := str.TrashRetention(ctx)
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("days") {
+				retention = time.Duration(days) * 24 * time.Hour
+			}
+			purged, err := str.PurgeTrash(ctx, retention)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Purged %d conversation(s)\n", purged)
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&days, "days", store.DefaultTrashRetentionDays, "purge conversations dropped more than this many days ago")
+	return cmd
+}
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/spf13/cobra"
+)
+
+func Backup() *cobra.Command {
+	var list bool
+	cmd := &cobra.Command{
+		Use:   "backup [path]",
+		Short: "Back up the database",
+		Long: `Back up the database using SQLite's online backup API.
+
+Without a path the backup is written to a timestamped file in the backups
+directory inside the gpterm config dir. A backup is also taken there
+automatically before any database migration runs.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := openStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			if list {
+				return printBackups(str)
+			}
+			var path string
+			if len(args) > 0 {
+				path = args[0]
+			}
+			path, err = str.Backup(ctx, path)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Backed up to %s\n", path)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&list, "list", "l", false, "list the backups in the backups directory")
+	return cmd
+}
+
+func Restore() *cobra.Command {
+	var yes bool
+	cmd := &cobra.Command{
+		Use:   "restore [backup]",
+		Short: "Replace the database with a backup",
+		Long: `Replace the database with a backup. The backup may be a path or the name of
+a file in the backups directory. The current database is backed up before it
+is replaced. Without an argument the available backups are listed.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := openStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			if len(args) == 0 {
+				return printBackups(str)
+			}
+			path := args[0]
+			if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) && !strings.ContainsRune(path, os.PathSeparator) {
+				path = filepath.Join(str.BackupDir(), path)
+			}
+			if !yes {
+				ok, err := confirm(fmt.Sprintf("Replace %s with %s?", str.DBPath(), path))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return nil
+				}
+			}
+			saved, err := str.Restore(ctx, path)
+			if saved != "" {
+				fmt.Printf("The previous database was backed up to %s\n", saved)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Restored %s\n", path)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "do not ask for confirmation")
+	return cmd
+}
+
+func Vacuum() *cobra.Command {
+	return &cobra.Command{
+		Use:   "vacuum",
+		Short: "Rebuild the database to reclaim unused space",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := openStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			before, after, err := str.Vacuum(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Vacuumed %s: %s -> %s\n", str.DBPath(), formatBytes(before), formatBytes(after))
+			return nil
+		},
+	}
+}
+
+func Check() *cobra.Command {
+	return &cobra.Command{
+		Use:   "check",
+		Short: "Check the integrity of the database",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := openStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			problems, err := str.Check(ctx)
+			if err != nil {
+				return err
+			}
+			if len(problems) == 0 {
+				fmt.Printf("%s is ok\n", str.DBPath())
+				return nil
+			}
+			for _, p := range problems {
+				fmt.Println(p)
+			}
+			return fmt.Errorf("found %d problem(s), consider restoring a backup", len(problems))
+		},
+	}
+}
+
+func printBackups(str *store.Store) error {
+	backups, err := str.ListBackups()
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		fmt.Printf("No backups in %s\n", str.BackupDir())
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSIZE\tCREATED")
+	for _, b := range backups {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", b.Name, formatBytes(b.Size), b.ModTime.Format(time.DateTime))
+	}
+	return tw.Flush()
+}
+
+func confirm(prompt string) (bool, error) {
+	fmt.Printf("%s [y/N] ", prompt)
+	s := bufio.NewScanner(os.Stdin)
+	s.Scan()
+	if err := s.Err(); err != nil {
+		return false, err
+	}
+	answer := strings.ToLower(strings.TrimSpace(s.Text()))
+	return answer == "y" || answer == "yes", nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	gpterm "github.com/collinvandyck/gpterm/cmd/gpterm/cmd"
+	"github.com/collinvandyck/gpterm/lib/store"
 	"github.com/spf13/cobra"
 )
 
@@ -9,9 +11,24 @@ func DB(deps *cobra.Command) *cobra.Command {
 		Use:   "db",
 		Short: "Run db related commands",
 	}
+	cmd.AddCommand(Backup())
+	cmd.AddCommand(Restore())
+	cmd.AddCommand(Vacuum())
+	cmd.AddCommand(Check())
+	cmd.AddCommand(Prune())
 	cmd.AddCommand(Schema())
 	cmd.AddCommand(SqlC(deps))
 	cmd.AddCommand(Migrate(deps))
 	cmd.AddCommand(Sqlite())
 	return cmd
 }
+
+// openStore opens the store of the profile selected for cmd.
+func openStore(cmd *cobra.Command) (*store.Store, error) {
+	return gpterm.OpenStore(cmd)
+}
+
+// profileDBPath returns the path of the database of the profile selected for cmd.
+func profileDBPath(cmd *cobra.Command) (string, error) {
+	return store.ProfileDBPath(gpterm.ProfileName(cmd))
+}
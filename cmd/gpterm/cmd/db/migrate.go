@@ -11,7 +11,6 @@ import (
 
 	"github.com/collinvandyck/gpterm/db"
 	"github.com/collinvandyck/gpterm/lib/git"
-	"github.com/collinvandyck/gpterm/lib/store"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/spf13/cobra"
@@ -47,7 +46,7 @@ func Migrate(deps *cobra.Command) *cobra.Command {
 		Use:   "up",
 		Short: "bring migrations up to date",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			dbPath, err := store.DefaultDBPath()
+			dbPath, err := profileDBPath(cmd)
 			if err != nil {
 				return err
 			}
@@ -74,7 +73,7 @@ func Migrate(deps *cobra.Command) *cobra.Command {
 		Use:   "down",
 		Short: "undo all migrations",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			dbPath, err := store.DefaultDBPath()
+			dbPath, err := profileDBPath(cmd)
 			if err != nil {
 				return err
 			}
@@ -106,7 +105,7 @@ func Migrate(deps *cobra.Command) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			dbPath, err := store.DefaultDBPath()
+			dbPath, err := profileDBPath(cmd)
 			if err != nil {
 				return err
 			}
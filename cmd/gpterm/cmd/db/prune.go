@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func Prune() *cobra.Command {
+	var (
+		dryRun        bool
+		save          bool
+		maxAgeDays    int
+		maxConvos     int
+		keepProtected bool
+		keepPinned    bool
+	)
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete conversations according to the retention policy",
+		Long: `Permanently delete conversations according to the retention policy.
+
+The policy is read from the config, and can be overridden with flags. Use
+--save to store the policy. A saved policy is also applied every time gpterm
+starts, so try it with --dry-run first, which changes nothing, including the
+saved policy. The selected conversation is never pruned.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dryRun && save {
+				return fmt.Errorf("--save can't be used with --dry-run, which changes nothing")
+			}
+			ctx := context.Background()
+			str, err := openStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			policy, err := str.RetentionPolicy(ctx)
+			if err != nil {
+				return err
+			}
+			flags := cmd.Flags()
+			if flags.Changed("max-age-days") {
+				policy.MaxAge = time.Duration(maxAgeDays) * 24 * time.Hour
+			}
+			if flags.Changed("max-conversations") {
+				policy.MaxConversations = maxConvos
+			}
+			if flags.Changed("keep-protected") {
+				policy.KeepProtected = keepProtected
+			}
+			if flags.Changed("keep-pinned") {
+				policy.KeepPinned = keepPinned
+			}
+			if save {
+				if err := str.SetRetentionPolicy(ctx, policy); err != nil {
+					return err
+				}
+				fmt.Println("Saved retention policy")
+			}
+			if !policy.Enabled() {
+				fmt.Println("No retention policy is set")
+				return nil
+			}
+			res, err := str.Prune(ctx, policy, dryRun)
+			if err != nil {
+				return err
+			}
+			verb := "Pruned"
+			if dryRun {
+				verb = "Would prune"
+			}
+			fmt.Printf("%s %d conversation(s), %d message(s), %s\n",
+				verb, len(res.Conversations), res.Messages, formatBytes(res.Bytes))
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "report what would be deleted without deleting anything")
+	cmd.Flags().BoolVar(&save, "save", false, "save the policy to the config")
+	cmd.Flags().IntVar(&maxAgeDays, "max-age-days", 0, "prune conversations with no messages in this many days (0 disables)")
+	cmd.Flags().IntVar(&maxConvos, "max-conversations", 0, "keep at most this many conversations (0 disables)")
+	cmd.Flags().BoolVar(&keepProtected, "keep-protected", true, "never prune protected conversations")
+	cmd.Flags().BoolVar(&keepPinned, "keep-pinned", true, "never prune conversations with pinned messages")
+	return cmd
+}
@@ -6,7 +6,6 @@ import (
 	"os/exec"
 	"strings"
 
-	"github.com/collinvandyck/gpterm/lib/store"
 	"github.com/spf13/cobra"
 )
 
@@ -25,7 +24,7 @@ func Sqlite() *cobra.Command {
 		DisableFlagsInUseLine: true,
 		DisableAutoGenTag:     true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			dbPath, err := store.DefaultDBPath()
+			dbPath, err := profileDBPath(cmd)
 			if err != nil {
 				return err
 			}
@@ -7,9 +7,7 @@ func Exp(deps *cobra.Command) *cobra.Command {
 		Use:   "exp",
 		Short: "Home for experiments",
 	}
-	exp.AddCommand(scrollCmd())
 	exp.AddCommand(scrollbackCmd())
-	exp.AddCommand(altScreenCmd())
 	exp.AddCommand(markdownCmd())
 	exp.AddCommand(lipglossCmd())
 	return exp
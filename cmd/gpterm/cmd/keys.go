@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/collinvandyck/gpterm/lib/keymap"
+	"github.com/spf13/cobra"
+)
+
+func Keys() *cobra.Command {
+	return &cobra.Command{
+		Use:   "keys",
+		Short: "Show the key bindings",
+		Long: `Show the key bindings, after checking the keymap file for mistakes.
+
+Keys are changed in keymap.json in the profile directory, e.g.
+~/.config/gpterm/keymap.json, which maps actions to lists of keys:
+
+  {
+    "history": ["f7", "ctrl+o"],
+    "next-conversation": ["ctrl+k n"],
+    "details": []
+  }
+
+Listing an action replaces its default keys, and an empty list unbinds it.
+Keys are named the way bubbletea names them, such as "ctrl+p", "alt+x",
+"f1" or "space". Keys separated by spaces form a chord, pressed one after
+the other. A binding can't start with a key that types into the prompt, such
+as "q" or "space", or one that edits it, such as "tab" or "left".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			keys, err := keymap.Load(str.Dir())
+			if err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "ACTION\tKEYS\tDESCRIPTION")
+			for _, info := range keymap.Actions {
+				var bound []string
+				for _, key := range keys.Keys(info.Action) {
+					bound = append(bound, keymap.Display(key))
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", info.Action, strings.Join(bound, ", "), info.Description)
+			}
+			fmt.Fprintf(tw, "\nKeys are read from %s\n", keymap.Path(str.Dir()))
+			return tw.Flush()
+		},
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/collinvandyck/gpterm/lib/term"
+	"github.com/spf13/cobra"
+)
+
+func Notify() *cobra.Command {
+	var after int
+	cmd := &cobra.Command{
+		Use:   "notify [method]",
+		Short: "Show or set how finished responses are notified",
+		Long: fmt.Sprintf(`Show or set how gpterm notifies that a response finished, one of %s.
+
+A response is notified when it took longer than --after seconds, and only if
+the terminal doesn't have focus. The notification has the conversation's title
+and the first line of the response.
+
+osc9 and osc777 ask the terminal to show a desktop notification, which
+terminals support with one or the other. bell rings the terminal bell, and
+notify-send shows the notification with the notify-send command instead. In
+tmux, set focus-events on for gpterm to know when it has focus, and
+allow-passthrough on for the notification to reach the terminal.`, strings.Join(term.NotifyMethods, ", ")),
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			if cmd.Flags().Changed("after") {
+				if after < 0 {
+					return fmt.Errorf("invalid number of seconds: %d", after)
+				}
+				err = str.SetConfigInt(ctx, store.ConfigNotifyAfter, after)
+				if err != nil {
+					return err
+				}
+			}
+			if len(args) == 0 {
+				method, err := str.GetConfigString(ctx, store.ConfigNotify, term.DefaultNotify)
+				if err != nil {
+					return err
+				}
+				after, err := str.GetConfigInt(ctx, store.ConfigNotifyAfter, store.DefaultNotifyAfter)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s after %ds\n", method, after)
+				return nil
+			}
+			method := strings.ToLower(args[0])
+			for _, known := range term.NotifyMethods {
+				if method == known {
+					return str.SetConfigString(ctx, store.ConfigNotify, method)
+				}
+			}
+			return fmt.Errorf("no notification method named %q, choose one of %s", args[0], strings.Join(term.NotifyMethods, ", "))
+		},
+	}
+	cmd.Flags().IntVar(&after, "after", store.DefaultNotifyAfter, "notify responses that take longer than this many seconds")
+	return cmd
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/collinvandyck/gpterm/lib/persona"
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/spf13/cobra"
+)
+
+func Persona() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "persona",
+		Short: "Manage personas",
+		Long: `Manage personas. A persona is the preamble sent ahead of every request, and
+each conversation keeps its own.
+
+Add a persona by writing a markdown file to the personas directory of the
+profile, e.g. ~/.config/gpterm/personas/reviewer.md.`,
+	}
+	cmd.AddCommand(personaList())
+	cmd.AddCommand(personaShow())
+	cmd.AddCommand(personaDefault())
+	return cmd
+}
+
+func personaList() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List personas",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			names, err := persona.List(str.Dir())
+			if err != nil {
+				return err
+			}
+			def, err := str.GetConfigString(ctx, store.ConfigPersona, persona.Default)
+			if err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tDEFAULT")
+			for _, name := range names {
+				current := ""
+				if name == def {
+					current = "*"
+				}
+				fmt.Fprintf(tw, "%s\t%s\n", name, current)
+			}
+			fmt.Fprintf(tw, "\nCustom personas are read from %s\n", persona.Dir(str.Dir()))
+			return tw.Flush()
+		},
+	}
+}
+
+func personaShow() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show [name]",
+		Short: "Show what a persona tells the model",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			preamble, err := persona.Load(str.Dir(), args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Println(preamble)
+			return nil
+		},
+	}
+}
+
+func personaDefault() *cobra.Command {
+	return &cobra.Command{
+		Use:   "default [name]",
+		Short: "Set the persona new conversations start with",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			name := args[0]
+			if _, err := persona.Load(str.Dir(), name); err != nil {
+				return err
+			}
+			return str.SetConfigString(ctx, store.ConfigPersona, name)
+		},
+	}
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/spf13/cobra"
+)
+
+// AnnotationNoProfile marks commands that can run when the selected profile
+// does not exist yet.
+const AnnotationNoProfile = "gpterm.no-profile"
+
+func Profile() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage profiles",
+		Long: `Manage profiles. Each profile has its own history, credentials and personas.
+
+Select a profile with --profile or the GPTERM_PROFILE environment variable.`,
+		Annotations: map[string]string{AnnotationNoProfile: "true"},
+	}
+	cmd.AddCommand(profileList())
+	cmd.AddCommand(profileCreate())
+	cmd.AddCommand(profileRm())
+	return cmd
+}
+
+func profileList() *cobra.Command {
+	return &cobra.Command{
+		Use:         "list",
+		Short:       "List profiles",
+		Annotations: map[string]string{AnnotationNoProfile: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profiles, err := store.ListProfiles(ProfileName(cmd))
+			if err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tCURRENT\tDIR")
+			for _, p := range profiles {
+				current := ""
+				if p.Current {
+					current = "*"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Name, current, p.Dir)
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+func profileCreate() *cobra.Command {
+	return &cobra.Command{
+		Use:         "create [name]",
+		Short:       "Create a profile",
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{AnnotationNoProfile: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			dir, err := store.CreateProfile(name)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Created profile %s in %s\n", name, dir)
+			fmt.Printf("Set its API key with: %s --profile %s auth\n", cmd.Root().Use, name)
+			return nil
+		},
+	}
+}
+
+func profileRm() *cobra.Command {
+	var yes bool
+	cmd := &cobra.Command{
+		Use:         "rm [name]",
+		Short:       "Delete a profile along with its history and credentials",
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{AnnotationNoProfile: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if name == store.DefaultProfile {
+				return errors.New("the default profile cannot be removed")
+			}
+			exists, err := store.ProfileExists(name)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("%w: %s", store.ErrProfileNotFound, name)
+			}
+			if name == ProfileName(cmd) {
+				return fmt.Errorf("profile %s is in use", name)
+			}
+			if !yes {
+				fmt.Printf("Delete profile %s and all of its history? [y/N] ", name)
+				s := bufio.NewScanner(os.Stdin)
+				s.Scan()
+				answer := strings.ToLower(strings.TrimSpace(s.Text()))
+				if answer != "y" && answer != "yes" {
+					return nil
+				}
+			}
+			err = store.RemoveProfile(name)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Removed profile %s\n", name)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "do not ask for confirmation")
+	return cmd
+}
+
+// ProfileName returns the profile selected with --profile, or the one from
+// the environment.
+func ProfileName(cmd *cobra.Command) string {
+	if flag := cmd.Flag("profile"); flag != nil && flag.Value.String() != "" {
+		return flag.Value.String()
+	}
+	return store.CurrentProfile()
+}
+
+// OpenStore opens the store of the profile selected for cmd.
+func OpenStore(cmd *cobra.Command, opts ...store.StoreOpt) (*store.Store, error) {
+	return store.New(append(opts, store.StoreProfile(ProfileName(cmd)))...)
+}
+
+// CheckProfile returns an error if the profile selected for cmd has not been
+// created, unless cmd is annotated with AnnotationNoProfile.
+func CheckProfile(cmd *cobra.Command) error {
+	if cmd.Annotations[AnnotationNoProfile] != "" {
+		return nil
+	}
+	current := ProfileName(cmd)
+	exists, err := store.ProfileExists(current)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("profile %s does not exist. create it with: %s profile create %s",
+			current, cmd.Root().Use, current)
+	}
+	return nil
+}
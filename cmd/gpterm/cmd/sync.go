@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/spf13/cobra"
+)
+
+func Sync() *cobra.Command {
+	var dir string
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Sync conversations with a directory shared between machines",
+		Long: `Sync conversations with a directory shared between machines, such as a synced
+folder or a git repository. Each conversation is kept in its own file.
+
+Changes are merged with the most recent one winning. A change made in both
+places since the last sync is reported as a conflict, and when a message was
+edited in both places the other version is kept as a separate branch.
+
+The directory is remembered, so it only has to be given the first time.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			if dir == "" {
+				dir, err = str.GetConfigString(ctx, store.ConfigSyncDir, "")
+				if err != nil {
+					return err
+				}
+				if dir == "" {
+					return errors.New("no sync directory, set one with --dir")
+				}
+			} else {
+				dir, err = filepath.Abs(dir)
+				if err != nil {
+					return err
+				}
+				err = str.SetConfigString(ctx, store.ConfigSyncDir, dir)
+				if err != nil {
+					return err
+				}
+			}
+			res, err := str.Sync(ctx, dir)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Synced with %s\n", dir)
+			fmt.Printf("Imported %d, updated %d, deleted %d conversations. Wrote %d and removed %d files.\n",
+				res.Imported, res.Updated, res.Deleted, res.Exported, res.Removed)
+			if len(res.Conflicts) > 0 {
+				fmt.Printf("\n%d conflicts:\n", len(res.Conflicts))
+				for _, c := range res.Conflicts {
+					fmt.Printf("  %s\n", c)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&dir, "dir", "d", "", "the directory to sync with")
+	return cmd
+}
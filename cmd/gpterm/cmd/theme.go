@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/collinvandyck/gpterm/lib/theme"
+	"github.com/spf13/cobra"
+)
+
+func Theme() *cobra.Command {
+	return &cobra.Command{
+		Use:   "theme [name]",
+		Short: "Show or set the color theme",
+		Long: fmt.Sprintf(`Show or set the color theme, one of %s.
+
+The auto theme asks the terminal for its background color when gpterm starts
+and picks the dark or light theme to match. Whatever the theme, setting
+NO_COLOR turns colors off.`, strings.Join(theme.Names, ", ")),
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			str, err := OpenStore(cmd)
+			if err != nil {
+				return fmt.Errorf("store: %w", err)
+			}
+			defer str.Close()
+			if len(args) == 0 {
+				name, err := str.GetConfigString(ctx, store.ConfigTheme, theme.Auto)
+				if err != nil {
+					return err
+				}
+				fmt.Println(name)
+				return nil
+			}
+			name := strings.ToLower(args[0])
+			for _, known := range theme.Names {
+				if name == known {
+					return str.SetConfigString(ctx, store.ConfigTheme, name)
+				}
+			}
+			return fmt.Errorf("no theme named %q, choose one of %s", args[0], strings.Join(theme.Names, ", "))
+		},
+	}
+}
@@ -3,32 +3,33 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
 
-	"github.com/collinvandyck/gpterm/lib/store"
 	"github.com/spf13/cobra"
 )
 
 func Usage() *cobra.Command {
 	return &cobra.Command{
-		Use:        "usage",
-		Short:      "Display usage",
-		Deprecated: "stream responses do not yet include usage data",
+		Use:   "usage",
+		Short: "Display token usage and estimated cost",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if cmd.Deprecated != "" {
-				return nil
-			}
 			ctx := context.Background()
-			store, err := store.New()
+			store, err := OpenStore(cmd)
 			if err != nil {
 				return err
 			}
+			defer store.Close()
 			usage, err := store.GetTotalUsage(ctx)
 			if err != nil {
 				return err
 			}
-
-			// gpt-3.5-turbo cost is $0.002 / 1K tokens
-			cost := 0.002 * (float64(usage.TotalTokens) / 1000)
+			spend, err := store.MonthSpend(ctx, time.Now())
+			if err != nil {
+				return err
+			}
 
 			print := func(val string, args ...any) {
 				fmt.Printf(val+"\n", args...)
@@ -37,8 +38,25 @@ func Usage() *cobra.Command {
 			print("Prompt:     %d", usage.PromptTokens)
 			print("Completion: %d", usage.CompletionTokens)
 			print("Total:      %d", usage.TotalTokens)
-			print("Cost:       $%0.02f ($0.002 per 1K tokens)", cost)
-
+			print("")
+			print("Estimated cost since %s: $%.2f", spend.Since.Format("2006-01-02"), spend.Total)
+			if len(spend.Models) > 0 {
+				models := make([]string, 0, len(spend.Models))
+				for model := range spend.Models {
+					models = append(models, model)
+				}
+				sort.Strings(models)
+				tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+				for _, model := range models {
+					fmt.Fprintf(tw, "  %s\t$%.2f\n", model, spend.Models[model])
+				}
+				if err := tw.Flush(); err != nil {
+					return err
+				}
+			}
+			if spend.Unpriced > 0 {
+				print("%d tokens were used by models without a price and are not included.", spend.Unpriced)
+			}
 			return nil
 		},
 	}
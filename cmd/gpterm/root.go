@@ -13,8 +13,11 @@ import (
 	"github.com/collinvandyck/gpterm/cmd/gpterm/cmd/db"
 	"github.com/collinvandyck/gpterm/cmd/gpterm/cmd/exp"
 	"github.com/collinvandyck/gpterm/lib/client"
+	"github.com/collinvandyck/gpterm/lib/credential"
+	"github.com/collinvandyck/gpterm/lib/keymap"
 	"github.com/collinvandyck/gpterm/lib/log"
 	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/collinvandyck/gpterm/lib/theme"
 	"github.com/collinvandyck/gpterm/lib/ui"
 	"github.com/spf13/cobra"
 )
@@ -24,6 +27,7 @@ var (
 	requestLogfile string
 	pprof          bool
 	clientHistory  int
+	profile        string
 )
 
 var root = &cobra.Command{
@@ -31,6 +35,9 @@ var root = &cobra.Command{
 	Short:        "Start an interactive session with ChatGPT",
 	SilenceUsage: true,
 	Aliases:      []string{"repl"},
+	PersistentPreRunE: func(c *cobra.Command, args []string) error {
+		return cmd.CheckProfile(c)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
 
@@ -48,11 +55,16 @@ var root = &cobra.Command{
 		defer rw.Close()
 		requestLogger := log.NewWriter(rw)
 
-		str, err := store.New(store.StoreLog(logger.New("name", "store")))
+		str, err := store.New(store.StoreLog(logger.New("name", "store")), store.StoreProfile(profile))
 		if err != nil {
 			return fmt.Errorf("new store: %w", err)
 		}
-		key, err := str.GetCredential(ctx, store.CredentialAPIKey)
+		err = prune(ctx, str, logger)
+		if err != nil {
+			return fmt.Errorf("prune: %w", err)
+		}
+		creds := credential.New(str)
+		key, err := creds.Get(ctx, store.CredentialAPIKey)
 		if err != nil {
 			return err
 		}
@@ -66,24 +78,61 @@ var root = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("new client: %w", err)
 		}
-		ui := ui.New(str, client, ui.WithLogger(logger))
+		keys, err := keymap.Load(str.Dir())
+		if err != nil {
+			return fmt.Errorf("keymap: %w", err)
+		}
+		name, err := str.GetConfigString(ctx, store.ConfigTheme, theme.Auto)
+		if err != nil {
+			return err
+		}
+		th, err := theme.Get(name)
+		if err != nil {
+			return fmt.Errorf("theme: %w", err)
+		}
+		ui := ui.New(str, client, ui.WithLogger(logger), ui.WithCredentials(creds), ui.WithKeymap(keys), ui.WithTheme(th))
 		return ui.Run(ctx)
 	},
 }
 
 func init() {
+	root.PersistentFlags().StringVarP(&profile, "profile", "p", "", "use a separate profile (default $"+store.ProfileEnv+" or default)")
 	root.Flags().StringVar(&logfile, "log", "", "log to this file")
 	root.Flags().StringVar(&requestLogfile, "request-log", "", "log HTTP requests to this file")
 	root.Flags().BoolVar(&pprof, "pprof", false, "start pprof http server in background")
 	root.Flags().IntVarP(&clientHistory, "context-size", "c", 5, "number of messages to send as context")
 
 	root.AddCommand(cmd.Auth())
+	root.AddCommand(cmd.Budget())
+	root.AddCommand(cmd.Convo())
 	root.AddCommand(cmd.Deps())
+	root.AddCommand(cmd.Keys())
+	root.AddCommand(cmd.Notify())
+	root.AddCommand(cmd.Persona())
+	root.AddCommand(cmd.Profile())
+	root.AddCommand(cmd.Sync())
+	root.AddCommand(cmd.Theme())
 	root.AddCommand(cmd.Usage())
 	root.AddCommand(db.DB(cmd.Deps()))
 	root.AddCommand(exp.Exp(cmd.Deps()))
 }
 
+// prune applies the saved retention policy, if there is one.
+func prune(ctx context.Context, str *store.Store, logger log.Logger) error {
+	policy, err := str.RetentionPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	res, err := str.Prune(ctx, policy, false)
+	if err != nil {
+		return err
+	}
+	if len(res.Conversations) > 0 {
+		logger.Log("pruned conversations", "conversations", len(res.Conversations), "messages", res.Messages, "bytes", res.Bytes)
+	}
+	return nil
+}
+
 func main() {
 	if pprof {
 		go func() {
@@ -0,0 +1,3 @@
+alter table conversation drop column leaf_id;
+drop index message_parent_id;
+alter table message drop column parent_id;
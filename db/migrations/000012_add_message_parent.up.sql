@@ -0,0 +1,18 @@
+alter table message add column parent_id integer;
+
+-- existing conversations are linear, so each message's parent is the
+-- message that came before it in the same conversation.
+update message set parent_id = (
+	select max(m.id) from message m
+	where m.conversation_id = message.conversation_id
+	and m.id < message.id
+);
+
+create index message_parent_id on message (parent_id);
+
+alter table conversation add column leaf_id integer;
+
+update conversation set leaf_id = (
+	select max(m.id) from message m
+	where m.conversation_id = conversation.id
+);
@@ -0,0 +1,8 @@
+-- trashed conversations would become visible again, so purge them first.
+delete from message where conversation_id in (
+	select id from conversation where deleted_at is not null
+);
+delete from conversation where deleted_at is not null;
+
+alter table conversation drop column deleted_at;
+alter table conversation drop column archived;
@@ -0,0 +1,2 @@
+alter table conversation add column archived integer not null default 0;
+alter table conversation add column deleted_at datetime;
@@ -0,0 +1,3 @@
+drop index conversation_tag_tag_id;
+drop table conversation_tag;
+drop table tag;
@@ -0,0 +1,14 @@
+create table tag (
+	id integer primary key,
+	name text not null unique
+);
+
+create table conversation_tag (
+	conversation_id integer not null,
+	tag_id integer not null,
+	primary key (conversation_id, tag_id),
+	FOREIGN KEY (conversation_id) REFERENCES conversation(id),
+	FOREIGN KEY (tag_id) REFERENCES tag(id)
+);
+
+create index conversation_tag_tag_id on conversation_tag (tag_id);
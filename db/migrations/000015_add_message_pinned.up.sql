@@ -0,0 +1 @@
+alter table message add column pinned integer not null default 0;
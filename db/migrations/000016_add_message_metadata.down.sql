@@ -0,0 +1,7 @@
+alter table message drop column finish_reason;
+alter table message drop column completion_tokens;
+alter table message drop column prompt_tokens;
+alter table message drop column duration_ms;
+alter table message drop column ttft_ms;
+alter table message drop column client_config;
+alter table message drop column model;
@@ -0,0 +1,7 @@
+alter table message add column model text;
+alter table message add column client_config text;
+alter table message add column ttft_ms integer;
+alter table message add column duration_ms integer;
+alter table message add column prompt_tokens integer;
+alter table message add column completion_tokens integer;
+alter table message add column finish_reason text;
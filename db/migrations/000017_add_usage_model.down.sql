@@ -0,0 +1 @@
+alter table usage drop column model;
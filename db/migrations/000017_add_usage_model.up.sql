@@ -0,0 +1 @@
+alter table usage add column model text;
@@ -0,0 +1,3 @@
+alter table conversation drop column persona;
+alter table conversation drop column message_context;
+alter table conversation drop column client_config;
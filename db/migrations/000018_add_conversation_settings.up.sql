@@ -0,0 +1,3 @@
+alter table conversation add column client_config text;
+alter table conversation add column message_context integer;
+alter table conversation add column persona text;
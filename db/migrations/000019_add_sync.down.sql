@@ -0,0 +1,12 @@
+drop table sync_tombstone;
+
+drop index message_uuid;
+drop index conversation_uuid;
+
+alter table message drop column synced_at;
+alter table message drop column updated_at;
+alter table message drop column uuid;
+
+alter table conversation drop column synced_at;
+alter table conversation drop column updated_at;
+alter table conversation drop column uuid;
@@ -0,0 +1,33 @@
+alter table conversation add column uuid text;
+alter table conversation add column updated_at datetime;
+alter table conversation add column synced_at datetime;
+
+alter table message add column uuid text;
+alter table message add column updated_at datetime;
+alter table message add column synced_at datetime;
+
+-- random version 4 uuids for the existing rows
+update conversation set uuid = lower(
+	hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' ||
+	substr(hex(randomblob(2)), 2) || '-' ||
+	substr('89ab', 1 + (abs(random()) % 4), 1) ||
+	substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))
+);
+update message set uuid = lower(
+	hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' ||
+	substr(hex(randomblob(2)), 2) || '-' ||
+	substr('89ab', 1 + (abs(random()) % 4), 1) ||
+	substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))
+);
+
+update conversation set updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now');
+
+create unique index conversation_uuid on conversation (uuid);
+create unique index message_uuid on message (uuid);
+
+-- conversations that were synced and have since been deleted, so that the
+-- deletion is synced too.
+create table sync_tombstone (
+	uuid text primary key,
+	deleted_at datetime not null
+);
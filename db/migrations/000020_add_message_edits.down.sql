@@ -0,0 +1,2 @@
+alter table message drop column deleted_at;
+alter table message drop column excluded;
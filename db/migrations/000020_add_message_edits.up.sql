@@ -0,0 +1,2 @@
+alter table message add column excluded integer not null default 0;
+alter table message add column deleted_at datetime;
@@ -8,3 +8,7 @@ WHERE name=?;
 -- name: SetConfigValue :exec
 INSERT OR REPLACE INTO config (name, value) VALUES (?, ?);
 
+
+-- name: DeleteConfigValue :exec
+DELETE FROM config
+WHERE name=?;
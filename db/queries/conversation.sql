@@ -1,6 +1,16 @@
 -- name: GetConversations :many
 SELECT * FROM conversation order by id;
 
+-- name: GetConversation :one
+select * from conversation where id = ?;
+
+-- name: GetConversationSummaries :many
+select c.*, count(m.id) as message_count
+from conversation c
+left join message m on m.conversation_id = c.id
+group by c.id
+order by c.id;
+
 -- name: ConversationCount :one
 select count(*) from conversation;
 
@@ -8,7 +18,15 @@ select count(*) from conversation;
 select * from conversation where selected=true;
 
 -- name: CreateConversation :one
-insert into conversation (name) values (null)
+insert into conversation (uuid, updated_at, name, client_config, message_context, persona)
+values (
+	?,
+	strftime('%Y-%m-%d %H:%M:%f', 'now'),
+	null,
+	(select value from config where name = 'client-config'),
+	(select message_context from client_config where name = (select value from config where name = 'client-config')),
+	(select value from config where name = 'persona')
+)
 returning *;
 
 -- name: UnsetSelectedConversation :exec
@@ -22,19 +40,125 @@ where id=?;
 
 -- name: NextConversation :one
 select * from conversation
-where id > (
-	select id from conversation where selected = true
-)
+where id > ?
+and archived = false
+and deleted_at is null
 order by id
 limit 1;
 
 -- name: PreviousConversation :one
 select * from conversation
-where id < (
-	select id from conversation where selected = true
-)
+where id < ?
+and archived = false
+and deleted_at is null
 order by id desc
 limit 1;
 
 -- name: DeleteConversation :one
 delete from conversation where id = ? returning *;
+
+-- name: SetConversationLeaf :exec
+update conversation
+set leaf_id = ?
+where id = ?;
+
+-- name: SetConversationProtected :exec
+update conversation
+set protected = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?;
+
+-- name: SetConversationArchived :exec
+update conversation
+set archived = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?;
+
+-- name: TrashConversation :exec
+update conversation
+set deleted_at = current_timestamp, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?;
+
+-- name: RestoreConversation :exec
+update conversation
+set deleted_at = null, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?;
+
+-- name: GetTrashedConversations :many
+select * from conversation
+where deleted_at is not null
+order by deleted_at;
+
+-- name: NextConversationWithTag :one
+select * from conversation c
+where c.id > ?
+and c.id in (
+	select ct.conversation_id from conversation_tag ct
+	join tag t on t.id = ct.tag_id
+	where t.name = ?
+)
+and c.archived = false
+and c.deleted_at is null
+order by c.id
+limit 1;
+
+-- name: PreviousConversationWithTag :one
+select * from conversation c
+where c.id < ?
+and c.id in (
+	select ct.conversation_id from conversation_tag ct
+	join tag t on t.id = ct.tag_id
+	where t.name = ?
+)
+and c.archived = false
+and c.deleted_at is null
+order by c.id desc
+limit 1;
+
+-- name: SetConversationClientConfig :exec
+update conversation
+set client_config = ?, message_context = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?;
+
+-- name: SetConversationMessageContext :exec
+update conversation
+set message_context = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?;
+
+-- name: SetConversationPersona :exec
+update conversation
+set persona = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?;
+
+-- name: TouchConversation :exec
+update conversation
+set updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?;
+
+-- name: GetConversationByUUID :one
+select * from conversation where uuid = ?;
+
+-- name: SetSyncedConversation :exec
+update conversation
+set name = ?, protected = ?, archived = ?, deleted_at = ?,
+	client_config = ?, message_context = ?, persona = ?, updated_at = ?
+where id = ?;
+
+-- name: MarkConversationSynced :exec
+update conversation
+set synced_at = updated_at
+where id = ?;
+
+-- name: GetSyncTombstones :many
+select * from sync_tombstone;
+
+-- name: CreateSyncTombstone :exec
+insert or replace into sync_tombstone (uuid, deleted_at)
+select uuid, strftime('%Y-%m-%d %H:%M:%f', 'now') from conversation
+where id = ? and synced_at is not null;
+
+-- name: DeleteSyncTombstone :exec
+delete from sync_tombstone where uuid = ?;
+
+-- name: SetConversationName :exec
+update conversation
+set name = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?;
@@ -5,3 +5,11 @@ WHERE name=? LIMIT 1;
 -- name: UpdateCredential :exec
 INSERT OR REPLACE INTO credential (name, value) VALUES (?, ?);
 
+
+-- name: GetCredentialNames :many
+SELECT name FROM credential
+ORDER BY name;
+
+-- name: DeleteCredential :exec
+DELETE FROM credential
+WHERE name=?;
@@ -6,41 +6,96 @@ where conversation_id = ?;
 -- name: GetMessages :many
 SELECT * FROM message;
 
--- name: GetLatestMessages :many
+-- name: GetMessage :one
+select * from message where id = ?;
+
+-- name: GetMessagesForConversation :many
 select *
 from message
-where id in (
-	select m.id 
-	from message m 
-	join conversation c on m.conversation_id = c.id
-	where c.selected = true
-	order by m.id desc 
-	limit ?
-)
+where conversation_id = ?
 order by id;
 
 -- name: GetPreviousMessageForRole :one
-select m.*
-from message m
-join conversation c on m.conversation_id = c.id
-where m.role = ?
-and c.selected = true
-order by m.id desc
+select *
+from message
+where role = ?
+and conversation_id = ?
+order by id desc
 limit 1 offset ?
 ;
 
--- name: InsertMessage :exec
-INSERT INTO message (role, content, conversation_id) 
-SELECT ?, ?, id
-from conversation
-where selected = true
-;
+-- name: InsertMessage :one
+INSERT INTO message (
+	role, content, model, client_config, ttft_ms, duration_ms,
+	prompt_tokens, completion_tokens, finish_reason,
+	conversation_id, parent_id, uuid
+)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+returning *;
 
--- name: DeleteMessagesForCurrentConversation :exec
+-- name: DeleteMessagesForConversation :exec
 delete from message
+where conversation_id = ?;
+
+-- name: GetLastMessagePerConversation :many
+select * from message
 where id in (
-	select m.id
-	from message m
-	join conversation c on m.conversation_id = c.id
-	where c.selected = true
+	select max(id) from message group by conversation_id
 );
+
+-- name: SearchMessages :many
+select m.* from message m
+join conversation c on c.id = m.conversation_id
+where m.content like ?
+and m.deleted_at is null
+and c.deleted_at is null
+order by m.id;
+
+-- name: SetMessagePinned :exec
+update message
+set pinned = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?;
+
+-- name: SetMessageContent :exec
+update message
+set content = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?;
+
+-- name: SetMessageExcluded :exec
+update message
+set excluded = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?;
+
+-- name: DeleteMessage :exec
+update message
+set content = '', pinned = 0,
+	deleted_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+	updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?;
+
+-- name: GetConversationIDsWithPinnedMessages :many
+select distinct conversation_id from message
+where pinned = true
+order by conversation_id;
+
+-- name: GetMessageByUUID :one
+select * from message where uuid = ?;
+
+-- name: ImportMessage :one
+insert into message (
+	uuid, timestamp, updated_at, role, content, pinned, excluded, deleted_at,
+	model, client_config, ttft_ms, duration_ms, prompt_tokens,
+	completion_tokens, finish_reason, conversation_id, parent_id
+)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+returning *;
+
+-- name: SetSyncedMessage :exec
+update message
+set content = ?, pinned = ?, excluded = ?, deleted_at = ?, updated_at = ?
+where id = ?;
+
+-- name: MarkMessagesSynced :exec
+update message
+set synced_at = coalesce(updated_at, timestamp)
+where conversation_id = ?;
@@ -0,0 +1,45 @@
+-- name: GetTags :many
+select t.*, count(ct.conversation_id) as conversation_count
+from tag t
+left join conversation_tag ct on ct.tag_id = t.id
+group by t.id
+order by t.name;
+
+-- name: GetTagByName :one
+select * from tag where name = ?;
+
+-- name: CreateTag :exec
+insert or ignore into tag (name) values (?);
+
+-- name: TagConversation :exec
+insert or ignore into conversation_tag (conversation_id, tag_id) values (?, ?);
+
+-- name: UntagConversation :exec
+delete from conversation_tag where conversation_id = ? and tag_id = ?;
+
+-- name: DeleteConversationTags :exec
+delete from conversation_tag where conversation_id = ?;
+
+-- name: DeleteUnusedTags :exec
+delete from tag where id not in (
+	select tag_id from conversation_tag
+);
+
+-- name: GetConversationTags :many
+select t.* from tag t
+join conversation_tag ct on ct.tag_id = t.id
+where ct.conversation_id = ?
+order by t.name;
+
+-- name: GetAllConversationTags :many
+select ct.conversation_id, t.name
+from conversation_tag ct
+join tag t on t.id = ct.tag_id
+order by ct.conversation_id, t.name;
+
+-- name: GetConversationIDsForTag :many
+select ct.conversation_id
+from conversation_tag ct
+join tag t on t.id = ct.tag_id
+where t.name = ?
+order by ct.conversation_id;
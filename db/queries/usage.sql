@@ -1,8 +1,8 @@
 -- name: InsertUsage :exec
 INSERT INTO usage 
-(prompt_tokens, completion_tokens, total_tokens)
+(prompt_tokens, completion_tokens, total_tokens, model)
 VALUES
-(?,?,?);
+(?,?,?,?);
 
 -- name: GetTotalTokens :one
 SELECT total(total_tokens) from usage;
@@ -13,4 +13,7 @@ SELECT total(completion_tokens) from usage;
 -- name: GetPromptTokens :one
 SELECT total(prompt_tokens) from usage;
 
-
+-- name: GetUsageSince :many
+SELECT * from usage
+WHERE timestamp >= ?
+ORDER BY id;
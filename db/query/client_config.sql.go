@@ -9,35 +9,6 @@ import (
 	"context"
 )
 
-const cycleClientConfig = `-- name: CycleClientConfig :one
-UPDATE config
-SET value = (
-    SELECT
-        CASE
-            -- Check if current value is less than the maximum value
-            WHEN (SELECT value FROM config WHERE name = 'client-config') < (SELECT MAX(name) FROM client_config)
-            THEN (
-                -- If current value is less than the maximum value, select the next value
-                SELECT MIN(name) FROM client_config
-                WHERE name > (SELECT value FROM config WHERE name = 'client-config')
-            )
-            ELSE (
-                -- If current value is equal to the maximum value, wrap around to the beginning
-                SELECT MIN(name) FROM client_config
-            )
-        END
-)
-WHERE name = 'client-config'
-RETURNING name, value
-`
-
-func (q *Queries) CycleClientConfig(ctx context.Context) (Config, error) {
-	row := q.queryRow(ctx, q.cycleClientConfigStmt, cycleClientConfig)
-	var i Config
-	err := row.Scan(&i.Name, &i.Value)
-	return i, err
-}
-
 const getClientConfig = `-- name: GetClientConfig :one
 SELECT name, model, message_context FROM client_config
 where name = (select value from config where name = 'client-config')
@@ -50,16 +21,56 @@ func (q *Queries) GetClientConfig(ctx context.Context) (ClientConfig, error) {
 	return i, err
 }
 
-const updateClientConfig = `-- name: UpdateClientConfig :one
-update client_config
-set message_context = ?
-where name = (select value from config where name = 'client-config')
-returning name, model, message_context
+const getClientConfigByName = `-- name: GetClientConfigByName :one
+select name, model, message_context from client_config where name = ?
 `
 
-func (q *Queries) UpdateClientConfig(ctx context.Context, messageContext int64) (ClientConfig, error) {
-	row := q.queryRow(ctx, q.updateClientConfigStmt, updateClientConfig, messageContext)
+func (q *Queries) GetClientConfigByName(ctx context.Context, name string) (ClientConfig, error) {
+	row := q.queryRow(ctx, q.getClientConfigByNameStmt, getClientConfigByName, name)
 	var i ClientConfig
 	err := row.Scan(&i.Name, &i.Model, &i.MessageContext)
 	return i, err
 }
+
+const getClientConfigs = `-- name: GetClientConfigs :many
+select name, model, message_context from client_config order by name
+`
+
+func (q *Queries) GetClientConfigs(ctx context.Context) ([]ClientConfig, error) {
+	rows, err := q.query(ctx, q.getClientConfigsStmt, getClientConfigs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClientConfig
+	for rows.Next() {
+		var i ClientConfig
+		if err := rows.Scan(&i.Name, &i.Model, &i.MessageContext); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setClientConfigMessageContext = `-- name: SetClientConfigMessageContext :exec
+update client_config
+set message_context = ?
+where name = ?
+`
+
+type SetClientConfigMessageContextParams struct {
+	MessageContext int64  `json:"message_context"`
+	Name           string `json:"name"`
+}
+
+func (q *Queries) SetClientConfigMessageContext(ctx context.Context, arg SetClientConfigMessageContextParams) error {
+	_, err := q.exec(ctx, q.setClientConfigMessageContextStmt, setClientConfigMessageContext, arg.MessageContext, arg.Name)
+	return err
+}
@@ -9,6 +9,16 @@ import (
 	"context"
 )
 
+const deleteConfigValue = `-- name: DeleteConfigValue :exec
+DELETE FROM config
+WHERE name=?
+`
+
+func (q *Queries) DeleteConfigValue(ctx context.Context, name string) error {
+	_, err := q.exec(ctx, q.deleteConfigValueStmt, deleteConfigValue, name)
+	return err
+}
+
 const getConfig = `-- name: GetConfig :many
 SELECT name, value FROM config
 `
@@ -7,6 +7,7 @@ package query
 
 import (
 	"context"
+	"database/sql"
 )
 
 const conversationCount = `-- name: ConversationCount :one
@@ -21,24 +22,52 @@ func (q *Queries) ConversationCount(ctx context.Context) (int64, error) {
 }
 
 const createConversation = `-- name: CreateConversation :one
-insert into conversation (name) values (null)
-returning id, name, protected, selected
+insert into conversation (uuid, updated_at, name, client_config, message_context, persona)
+values (
+	?,
+	strftime('%Y-%m-%d %H:%M:%f', 'now'),
+	null,
+	(select value from config where name = 'client-config'),
+	(select message_context from client_config where name = (select value from config where name = 'client-config')),
+	(select value from config where name = 'persona')
+)
+returning id, name, protected, selected, leaf_id, archived, deleted_at, client_config, message_context, persona, uuid, updated_at, synced_at
 `
 
-func (q *Queries) CreateConversation(ctx context.Context) (Conversation, error) {
-	row := q.queryRow(ctx, q.createConversationStmt, createConversation)
+func (q *Queries) CreateConversation(ctx context.Context, uuid sql.NullString) (Conversation, error) {
+	row := q.queryRow(ctx, q.createConversationStmt, createConversation, uuid)
 	var i Conversation
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.Protected,
 		&i.Selected,
+		&i.LeafID,
+		&i.Archived,
+		&i.DeletedAt,
+		&i.ClientConfig,
+		&i.MessageContext,
+		&i.Persona,
+		&i.Uuid,
+		&i.UpdatedAt,
+		&i.SyncedAt,
 	)
 	return i, err
 }
 
+const createSyncTombstone = `-- name: CreateSyncTombstone :exec
+insert or replace into sync_tombstone (uuid, deleted_at)
+select uuid, strftime('%Y-%m-%d %H:%M:%f', 'now') from conversation
+where id = ? and synced_at is not null
+`
+
+func (q *Queries) CreateSyncTombstone(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.createSyncTombstoneStmt, createSyncTombstone, id)
+	return err
+}
+
 const deleteConversation = `-- name: DeleteConversation :one
-delete from conversation where id = ? returning id, name, protected, selected
+delete from conversation where id = ? returning id, name, protected, selected, leaf_id, archived, deleted_at, client_config, message_context, persona, uuid, updated_at, synced_at
 `
 
 func (q *Queries) DeleteConversation(ctx context.Context, id int64) (Conversation, error) {
@@ -49,12 +78,30 @@ func (q *Queries) DeleteConversation(ctx context.Context, id int64) (Conversatio
 		&i.Name,
 		&i.Protected,
 		&i.Selected,
+		&i.LeafID,
+		&i.Archived,
+		&i.DeletedAt,
+		&i.ClientConfig,
+		&i.MessageContext,
+		&i.Persona,
+		&i.Uuid,
+		&i.UpdatedAt,
+		&i.SyncedAt,
 	)
 	return i, err
 }
 
+const deleteSyncTombstone = `-- name: DeleteSyncTombstone :exec
+delete from sync_tombstone where uuid = ?
+`
+
+func (q *Queries) DeleteSyncTombstone(ctx context.Context, uuid string) error {
+	_, err := q.exec(ctx, q.deleteSyncTombstoneStmt, deleteSyncTombstone, uuid)
+	return err
+}
+
 const getActiveConversation = `-- name: GetActiveConversation :one
-select id, name, protected, selected from conversation where selected=true
+select id, name, protected, selected, leaf_id, archived, deleted_at, client_config, message_context, persona, uuid, updated_at, synced_at from conversation where selected=true
 `
 
 func (q *Queries) GetActiveConversation(ctx context.Context) (Conversation, error) {
@@ -65,12 +112,134 @@ func (q *Queries) GetActiveConversation(ctx context.Context) (Conversation, erro
 		&i.Name,
 		&i.Protected,
 		&i.Selected,
+		&i.LeafID,
+		&i.Archived,
+		&i.DeletedAt,
+		&i.ClientConfig,
+		&i.MessageContext,
+		&i.Persona,
+		&i.Uuid,
+		&i.UpdatedAt,
+		&i.SyncedAt,
+	)
+	return i, err
+}
+
+const getConversation = `-- name: GetConversation :one
+select id, name, protected, selected, leaf_id, archived, deleted_at, client_config, message_context, persona, uuid, updated_at, synced_at from conversation where id = ?
+`
+
+func (q *Queries) GetConversation(ctx context.Context, id int64) (Conversation, error) {
+	row := q.queryRow(ctx, q.getConversationStmt, getConversation, id)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Protected,
+		&i.Selected,
+		&i.LeafID,
+		&i.Archived,
+		&i.DeletedAt,
+		&i.ClientConfig,
+		&i.MessageContext,
+		&i.Persona,
+		&i.Uuid,
+		&i.UpdatedAt,
+		&i.SyncedAt,
 	)
 	return i, err
 }
 
+const getConversationByUUID = `-- name: GetConversationByUUID :one
+select id, name, protected, selected, leaf_id, archived, deleted_at, client_config, message_context, persona, uuid, updated_at, synced_at from conversation where uuid = ?
+`
+
+func (q *Queries) GetConversationByUUID(ctx context.Context, uuid sql.NullString) (Conversation, error) {
+	row := q.queryRow(ctx, q.getConversationByUUIDStmt, getConversationByUUID, uuid)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Protected,
+		&i.Selected,
+		&i.LeafID,
+		&i.Archived,
+		&i.DeletedAt,
+		&i.ClientConfig,
+		&i.MessageContext,
+		&i.Persona,
+		&i.Uuid,
+		&i.UpdatedAt,
+		&i.SyncedAt,
+	)
+	return i, err
+}
+
+const getConversationSummaries = `-- name: GetConversationSummaries :many
+select c.id, c.name, c.protected, c.selected, c.leaf_id, c.archived, c.deleted_at, c.client_config, c.message_context, c.persona, c.uuid, c.updated_at, c.synced_at, count(m.id) as message_count
+from conversation c
+left join message m on m.conversation_id = c.id
+group by c.id
+order by c.id
+`
+
+type GetConversationSummariesRow struct {
+	ID             int64          `json:"id"`
+	Name           sql.NullString `json:"name"`
+	Protected      int64          `json:"protected"`
+	Selected       int64          `json:"selected"`
+	LeafID         sql.NullInt64  `json:"leaf_id"`
+	Archived       int64          `json:"archived"`
+	DeletedAt      sql.NullTime   `json:"deleted_at"`
+	ClientConfig   sql.NullString `json:"client_config"`
+	MessageContext sql.NullInt64  `json:"message_context"`
+	Persona        sql.NullString `json:"persona"`
+	Uuid           sql.NullString `json:"uuid"`
+	UpdatedAt      sql.NullTime   `json:"updated_at"`
+	SyncedAt       sql.NullTime   `json:"synced_at"`
+	MessageCount   int64          `json:"message_count"`
+}
+
+func (q *Queries) GetConversationSummaries(ctx context.Context) ([]GetConversationSummariesRow, error) {
+	rows, err := q.query(ctx, q.getConversationSummariesStmt, getConversationSummaries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetConversationSummariesRow
+	for rows.Next() {
+		var i GetConversationSummariesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Protected,
+			&i.Selected,
+			&i.LeafID,
+			&i.Archived,
+			&i.DeletedAt,
+			&i.ClientConfig,
+			&i.MessageContext,
+			&i.Persona,
+			&i.Uuid,
+			&i.UpdatedAt,
+			&i.SyncedAt,
+			&i.MessageCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getConversations = `-- name: GetConversations :many
-SELECT id, name, protected, selected FROM conversation order by id
+SELECT id, name, protected, selected, leaf_id, archived, deleted_at, client_config, message_context, persona, uuid, updated_at, synced_at FROM conversation order by id
 `
 
 func (q *Queries) GetConversations(ctx context.Context) ([]Conversation, error) {
@@ -87,6 +256,85 @@ func (q *Queries) GetConversations(ctx context.Context) ([]Conversation, error)
 			&i.Name,
 			&i.Protected,
 			&i.Selected,
+			&i.LeafID,
+			&i.Archived,
+			&i.DeletedAt,
+			&i.ClientConfig,
+			&i.MessageContext,
+			&i.Persona,
+			&i.Uuid,
+			&i.UpdatedAt,
+			&i.SyncedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSyncTombstones = `-- name: GetSyncTombstones :many
+select uuid, deleted_at from sync_tombstone
+`
+
+func (q *Queries) GetSyncTombstones(ctx context.Context) ([]SyncTombstone, error) {
+	rows, err := q.query(ctx, q.getSyncTombstonesStmt, getSyncTombstones)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SyncTombstone
+	for rows.Next() {
+		var i SyncTombstone
+		if err := rows.Scan(&i.Uuid, &i.DeletedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTrashedConversations = `-- name: GetTrashedConversations :many
+select id, name, protected, selected, leaf_id, archived, deleted_at, client_config, message_context, persona, uuid, updated_at, synced_at from conversation
+where deleted_at is not null
+order by deleted_at
+`
+
+func (q *Queries) GetTrashedConversations(ctx context.Context) ([]Conversation, error) {
+	rows, err := q.query(ctx, q.getTrashedConversationsStmt, getTrashedConversations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Conversation
+	for rows.Next() {
+		var i Conversation
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Protected,
+			&i.Selected,
+			&i.LeafID,
+			&i.Archived,
+			&i.DeletedAt,
+			&i.ClientConfig,
+			&i.MessageContext,
+			&i.Persona,
+			&i.Uuid,
+			&i.UpdatedAt,
+			&i.SyncedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -101,48 +349,281 @@ func (q *Queries) GetConversations(ctx context.Context) ([]Conversation, error)
 	return items, nil
 }
 
+const markConversationSynced = `-- name: MarkConversationSynced :exec
+update conversation
+set synced_at = updated_at
+where id = ?
+`
+
+func (q *Queries) MarkConversationSynced(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.markConversationSyncedStmt, markConversationSynced, id)
+	return err
+}
+
 const nextConversation = `-- name: NextConversation :one
-select id, name, protected, selected from conversation
-where id > (
-	select id from conversation where selected = true
-)
+select id, name, protected, selected, leaf_id, archived, deleted_at, client_config, message_context, persona, uuid, updated_at, synced_at from conversation
+where id > ?
+and archived = false
+and deleted_at is null
 order by id
 limit 1
 `
 
-func (q *Queries) NextConversation(ctx context.Context) (Conversation, error) {
-	row := q.queryRow(ctx, q.nextConversationStmt, nextConversation)
+func (q *Queries) NextConversation(ctx context.Context, id int64) (Conversation, error) {
+	row := q.queryRow(ctx, q.nextConversationStmt, nextConversation, id)
 	var i Conversation
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.Protected,
 		&i.Selected,
+		&i.LeafID,
+		&i.Archived,
+		&i.DeletedAt,
+		&i.ClientConfig,
+		&i.MessageContext,
+		&i.Persona,
+		&i.Uuid,
+		&i.UpdatedAt,
+		&i.SyncedAt,
 	)
 	return i, err
 }
 
-const previousConversation = `-- name: PreviousConversation :one
-select id, name, protected, selected from conversation
-where id < (
-	select id from conversation where selected = true
+const nextConversationWithTag = `-- name: NextConversationWithTag :one
+select id, name, protected, selected, leaf_id, archived, deleted_at, client_config, message_context, persona, uuid, updated_at, synced_at from conversation c
+where c.id > ?
+and c.id in (
+	select ct.conversation_id from conversation_tag ct
+	join tag t on t.id = ct.tag_id
+	where t.name = ?
 )
+and c.archived = false
+and c.deleted_at is null
+order by c.id
+limit 1
+`
+
+type NextConversationWithTagParams struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func (q *Queries) NextConversationWithTag(ctx context.Context, arg NextConversationWithTagParams) (Conversation, error) {
+	row := q.queryRow(ctx, q.nextConversationWithTagStmt, nextConversationWithTag, arg.ID, arg.Name)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Protected,
+		&i.Selected,
+		&i.LeafID,
+		&i.Archived,
+		&i.DeletedAt,
+		&i.ClientConfig,
+		&i.MessageContext,
+		&i.Persona,
+		&i.Uuid,
+		&i.UpdatedAt,
+		&i.SyncedAt,
+	)
+	return i, err
+}
+
+const previousConversation = `-- name: PreviousConversation :one
+select id, name, protected, selected, leaf_id, archived, deleted_at, client_config, message_context, persona, uuid, updated_at, synced_at from conversation
+where id < ?
+and archived = false
+and deleted_at is null
 order by id desc
 limit 1
 `
 
-func (q *Queries) PreviousConversation(ctx context.Context) (Conversation, error) {
-	row := q.queryRow(ctx, q.previousConversationStmt, previousConversation)
+func (q *Queries) PreviousConversation(ctx context.Context, id int64) (Conversation, error) {
+	row := q.queryRow(ctx, q.previousConversationStmt, previousConversation, id)
 	var i Conversation
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.Protected,
 		&i.Selected,
+		&i.LeafID,
+		&i.Archived,
+		&i.DeletedAt,
+		&i.ClientConfig,
+		&i.MessageContext,
+		&i.Persona,
+		&i.Uuid,
+		&i.UpdatedAt,
+		&i.SyncedAt,
 	)
 	return i, err
 }
 
+const previousConversationWithTag = `-- name: PreviousConversationWithTag :one
+select id, name, protected, selected, leaf_id, archived, deleted_at, client_config, message_context, persona, uuid, updated_at, synced_at from conversation c
+where c.id < ?
+and c.id in (
+	select ct.conversation_id from conversation_tag ct
+	join tag t on t.id = ct.tag_id
+	where t.name = ?
+)
+and c.archived = false
+and c.deleted_at is null
+order by c.id desc
+limit 1
+`
+
+type PreviousConversationWithTagParams struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func (q *Queries) PreviousConversationWithTag(ctx context.Context, arg PreviousConversationWithTagParams) (Conversation, error) {
+	row := q.queryRow(ctx, q.previousConversationWithTagStmt, previousConversationWithTag, arg.ID, arg.Name)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Protected,
+		&i.Selected,
+		&i.LeafID,
+		&i.Archived,
+		&i.DeletedAt,
+		&i.ClientConfig,
+		&i.MessageContext,
+		&i.Persona,
+		&i.Uuid,
+		&i.UpdatedAt,
+		&i.SyncedAt,
+	)
+	return i, err
+}
+
+const restoreConversation = `-- name: RestoreConversation :exec
+update conversation
+set deleted_at = null, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?
+`
+
+func (q *Queries) RestoreConversation(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.restoreConversationStmt, restoreConversation, id)
+	return err
+}
+
+const setConversationArchived = `-- name: SetConversationArchived :exec
+update conversation
+set archived = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?
+`
+
+type SetConversationArchivedParams struct {
+	Archived int64 `json:"archived"`
+	ID       int64 `json:"id"`
+}
+
+func (q *Queries) SetConversationArchived(ctx context.Context, arg SetConversationArchivedParams) error {
+	_, err := q.exec(ctx, q.setConversationArchivedStmt, setConversationArchived, arg.Archived, arg.ID)
+	return err
+}
+
+const setConversationClientConfig = `-- name: SetConversationClientConfig :exec
+update conversation
+set client_config = ?, message_context = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?
+`
+
+type SetConversationClientConfigParams struct {
+	ClientConfig   sql.NullString `json:"client_config"`
+	MessageContext sql.NullInt64  `json:"message_context"`
+	ID             int64          `json:"id"`
+}
+
+func (q *Queries) SetConversationClientConfig(ctx context.Context, arg SetConversationClientConfigParams) error {
+	_, err := q.exec(ctx, q.setConversationClientConfigStmt, setConversationClientConfig, arg.ClientConfig, arg.MessageContext, arg.ID)
+	return err
+}
+
+const setConversationLeaf = `-- name: SetConversationLeaf :exec
+update conversation
+set leaf_id = ?
+where id = ?
+`
+
+type SetConversationLeafParams struct {
+	LeafID sql.NullInt64 `json:"leaf_id"`
+	ID     int64         `json:"id"`
+}
+
+func (q *Queries) SetConversationLeaf(ctx context.Context, arg SetConversationLeafParams) error {
+	_, err := q.exec(ctx, q.setConversationLeafStmt, setConversationLeaf, arg.LeafID, arg.ID)
+	return err
+}
+
+const setConversationMessageContext = `-- name: SetConversationMessageContext :exec
+update conversation
+set message_context = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?
+`
+
+type SetConversationMessageContextParams struct {
+	MessageContext sql.NullInt64 `json:"message_context"`
+	ID             int64         `json:"id"`
+}
+
+func (q *Queries) SetConversationMessageContext(ctx context.Context, arg SetConversationMessageContextParams) error {
+	_, err := q.exec(ctx, q.setConversationMessageContextStmt, setConversationMessageContext, arg.MessageContext, arg.ID)
+	return err
+}
+
+const setConversationName = `-- name: SetConversationName :exec
+update conversation
+set name = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?
+`
+
+type SetConversationNameParams struct {
+	Name sql.NullString `json:"name"`
+	ID   int64          `json:"id"`
+}
+
+func (q *Queries) SetConversationName(ctx context.Context, arg SetConversationNameParams) error {
+	_, err := q.exec(ctx, q.setConversationNameStmt, setConversationName, arg.Name, arg.ID)
+	return err
+}
+
+const setConversationPersona = `-- name: SetConversationPersona :exec
+update conversation
+set persona = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?
+`
+
+type SetConversationPersonaParams struct {
+	Persona sql.NullString `json:"persona"`
+	ID      int64          `json:"id"`
+}
+
+func (q *Queries) SetConversationPersona(ctx context.Context, arg SetConversationPersonaParams) error {
+	_, err := q.exec(ctx, q.setConversationPersonaStmt, setConversationPersona, arg.Persona, arg.ID)
+	return err
+}
+
+const setConversationProtected = `-- name: SetConversationProtected :exec
+update conversation
+set protected = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?
+`
+
+type SetConversationProtectedParams struct {
+	Protected int64 `json:"protected"`
+	ID        int64 `json:"id"`
+}
+
+func (q *Queries) SetConversationProtected(ctx context.Context, arg SetConversationProtectedParams) error {
+	_, err := q.exec(ctx, q.setConversationProtectedStmt, setConversationProtected, arg.Protected, arg.ID)
+	return err
+}
+
 const setSelectedConversation = `-- name: SetSelectedConversation :exec
 update conversation
 set selected = true
@@ -154,6 +635,62 @@ func (q *Queries) SetSelectedConversation(ctx context.Context, id int64) error {
 	return err
 }
 
+const setSyncedConversation = `-- name: SetSyncedConversation :exec
+update conversation
+set name = ?, protected = ?, archived = ?, deleted_at = ?,
+	client_config = ?, message_context = ?, persona = ?, updated_at = ?
+where id = ?
+`
+
+type SetSyncedConversationParams struct {
+	Name           sql.NullString `json:"name"`
+	Protected      int64          `json:"protected"`
+	Archived       int64          `json:"archived"`
+	DeletedAt      sql.NullTime   `json:"deleted_at"`
+	ClientConfig   sql.NullString `json:"client_config"`
+	MessageContext sql.NullInt64  `json:"message_context"`
+	Persona        sql.NullString `json:"persona"`
+	UpdatedAt      sql.NullTime   `json:"updated_at"`
+	ID             int64          `json:"id"`
+}
+
+func (q *Queries) SetSyncedConversation(ctx context.Context, arg SetSyncedConversationParams) error {
+	_, err := q.exec(ctx, q.setSyncedConversationStmt, setSyncedConversation,
+		arg.Name,
+		arg.Protected,
+		arg.Archived,
+		arg.DeletedAt,
+		arg.ClientConfig,
+		arg.MessageContext,
+		arg.Persona,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const touchConversation = `-- name: TouchConversation :exec
+update conversation
+set updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?
+`
+
+func (q *Queries) TouchConversation(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.touchConversationStmt, touchConversation, id)
+	return err
+}
+
+const trashConversation = `-- name: TrashConversation :exec
+update conversation
+set deleted_at = current_timestamp, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?
+`
+
+func (q *Queries) TrashConversation(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.trashConversationStmt, trashConversation, id)
+	return err
+}
+
 const unsetSelectedConversation = `-- name: UnsetSelectedConversation :exec
 update conversation
 set selected = false
@@ -9,6 +9,16 @@ import (
 	"context"
 )
 
+const deleteCredential = `-- name: DeleteCredential :exec
+DELETE FROM credential
+WHERE name=?
+`
+
+func (q *Queries) DeleteCredential(ctx context.Context, name string) error {
+	_, err := q.exec(ctx, q.deleteCredentialStmt, deleteCredential, name)
+	return err
+}
+
 const getCredential = `-- name: GetCredential :one
 SELECT value FROM credential
 WHERE name=? LIMIT 1
@@ -21,6 +31,34 @@ func (q *Queries) GetCredential(ctx context.Context, name string) (string, error
 	return value, err
 }
 
+const getCredentialNames = `-- name: GetCredentialNames :many
+SELECT name FROM credential
+ORDER BY name
+`
+
+func (q *Queries) GetCredentialNames(ctx context.Context) ([]string, error) {
+	rows, err := q.query(ctx, q.getCredentialNamesStmt, getCredentialNames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateCredential = `-- name: UpdateCredential :exec
 INSERT OR REPLACE INTO credential (name, value) VALUES (?, ?)
 `
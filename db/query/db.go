@@ -33,21 +33,51 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.createConversationStmt, err = db.PrepareContext(ctx, createConversation); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateConversation: %w", err)
 	}
-	if q.cycleClientConfigStmt, err = db.PrepareContext(ctx, cycleClientConfig); err != nil {
-		return nil, fmt.Errorf("error preparing query CycleClientConfig: %w", err)
+	if q.createSyncTombstoneStmt, err = db.PrepareContext(ctx, createSyncTombstone); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateSyncTombstone: %w", err)
+	}
+	if q.createTagStmt, err = db.PrepareContext(ctx, createTag); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateTag: %w", err)
+	}
+	if q.deleteConfigValueStmt, err = db.PrepareContext(ctx, deleteConfigValue); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteConfigValue: %w", err)
 	}
 	if q.deleteConversationStmt, err = db.PrepareContext(ctx, deleteConversation); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteConversation: %w", err)
 	}
-	if q.deleteMessagesForCurrentConversationStmt, err = db.PrepareContext(ctx, deleteMessagesForCurrentConversation); err != nil {
-		return nil, fmt.Errorf("error preparing query DeleteMessagesForCurrentConversation: %w", err)
+	if q.deleteConversationTagsStmt, err = db.PrepareContext(ctx, deleteConversationTags); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteConversationTags: %w", err)
+	}
+	if q.deleteCredentialStmt, err = db.PrepareContext(ctx, deleteCredential); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteCredential: %w", err)
+	}
+	if q.deleteMessageStmt, err = db.PrepareContext(ctx, deleteMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteMessage: %w", err)
+	}
+	if q.deleteMessagesForConversationStmt, err = db.PrepareContext(ctx, deleteMessagesForConversation); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteMessagesForConversation: %w", err)
+	}
+	if q.deleteSyncTombstoneStmt, err = db.PrepareContext(ctx, deleteSyncTombstone); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteSyncTombstone: %w", err)
+	}
+	if q.deleteUnusedTagsStmt, err = db.PrepareContext(ctx, deleteUnusedTags); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUnusedTags: %w", err)
 	}
 	if q.getActiveConversationStmt, err = db.PrepareContext(ctx, getActiveConversation); err != nil {
 		return nil, fmt.Errorf("error preparing query GetActiveConversation: %w", err)
 	}
+	if q.getAllConversationTagsStmt, err = db.PrepareContext(ctx, getAllConversationTags); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAllConversationTags: %w", err)
+	}
 	if q.getClientConfigStmt, err = db.PrepareContext(ctx, getClientConfig); err != nil {
 		return nil, fmt.Errorf("error preparing query GetClientConfig: %w", err)
 	}
+	if q.getClientConfigByNameStmt, err = db.PrepareContext(ctx, getClientConfigByName); err != nil {
+		return nil, fmt.Errorf("error preparing query GetClientConfigByName: %w", err)
+	}
+	if q.getClientConfigsStmt, err = db.PrepareContext(ctx, getClientConfigs); err != nil {
+		return nil, fmt.Errorf("error preparing query GetClientConfigs: %w", err)
+	}
 	if q.getCompletionTokensStmt, err = db.PrepareContext(ctx, getCompletionTokens); err != nil {
 		return nil, fmt.Errorf("error preparing query GetCompletionTokens: %w", err)
 	}
@@ -57,50 +87,164 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getConfigValueStmt, err = db.PrepareContext(ctx, getConfigValue); err != nil {
 		return nil, fmt.Errorf("error preparing query GetConfigValue: %w", err)
 	}
+	if q.getConversationStmt, err = db.PrepareContext(ctx, getConversation); err != nil {
+		return nil, fmt.Errorf("error preparing query GetConversation: %w", err)
+	}
+	if q.getConversationByUUIDStmt, err = db.PrepareContext(ctx, getConversationByUUID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetConversationByUUID: %w", err)
+	}
+	if q.getConversationIDsForTagStmt, err = db.PrepareContext(ctx, getConversationIDsForTag); err != nil {
+		return nil, fmt.Errorf("error preparing query GetConversationIDsForTag: %w", err)
+	}
+	if q.getConversationIDsWithPinnedMessagesStmt, err = db.PrepareContext(ctx, getConversationIDsWithPinnedMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query GetConversationIDsWithPinnedMessages: %w", err)
+	}
+	if q.getConversationSummariesStmt, err = db.PrepareContext(ctx, getConversationSummaries); err != nil {
+		return nil, fmt.Errorf("error preparing query GetConversationSummaries: %w", err)
+	}
+	if q.getConversationTagsStmt, err = db.PrepareContext(ctx, getConversationTags); err != nil {
+		return nil, fmt.Errorf("error preparing query GetConversationTags: %w", err)
+	}
 	if q.getConversationsStmt, err = db.PrepareContext(ctx, getConversations); err != nil {
 		return nil, fmt.Errorf("error preparing query GetConversations: %w", err)
 	}
 	if q.getCredentialStmt, err = db.PrepareContext(ctx, getCredential); err != nil {
 		return nil, fmt.Errorf("error preparing query GetCredential: %w", err)
 	}
-	if q.getLatestMessagesStmt, err = db.PrepareContext(ctx, getLatestMessages); err != nil {
-		return nil, fmt.Errorf("error preparing query GetLatestMessages: %w", err)
+	if q.getCredentialNamesStmt, err = db.PrepareContext(ctx, getCredentialNames); err != nil {
+		return nil, fmt.Errorf("error preparing query GetCredentialNames: %w", err)
+	}
+	if q.getLastMessagePerConversationStmt, err = db.PrepareContext(ctx, getLastMessagePerConversation); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLastMessagePerConversation: %w", err)
+	}
+	if q.getMessageStmt, err = db.PrepareContext(ctx, getMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessage: %w", err)
+	}
+	if q.getMessageByUUIDStmt, err = db.PrepareContext(ctx, getMessageByUUID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessageByUUID: %w", err)
 	}
 	if q.getMessagesStmt, err = db.PrepareContext(ctx, getMessages); err != nil {
 		return nil, fmt.Errorf("error preparing query GetMessages: %w", err)
 	}
+	if q.getMessagesForConversationStmt, err = db.PrepareContext(ctx, getMessagesForConversation); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessagesForConversation: %w", err)
+	}
 	if q.getPreviousMessageForRoleStmt, err = db.PrepareContext(ctx, getPreviousMessageForRole); err != nil {
 		return nil, fmt.Errorf("error preparing query GetPreviousMessageForRole: %w", err)
 	}
 	if q.getPromptTokensStmt, err = db.PrepareContext(ctx, getPromptTokens); err != nil {
 		return nil, fmt.Errorf("error preparing query GetPromptTokens: %w", err)
 	}
+	if q.getSyncTombstonesStmt, err = db.PrepareContext(ctx, getSyncTombstones); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSyncTombstones: %w", err)
+	}
+	if q.getTagByNameStmt, err = db.PrepareContext(ctx, getTagByName); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTagByName: %w", err)
+	}
+	if q.getTagsStmt, err = db.PrepareContext(ctx, getTags); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTags: %w", err)
+	}
 	if q.getTotalTokensStmt, err = db.PrepareContext(ctx, getTotalTokens); err != nil {
 		return nil, fmt.Errorf("error preparing query GetTotalTokens: %w", err)
 	}
+	if q.getTrashedConversationsStmt, err = db.PrepareContext(ctx, getTrashedConversations); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTrashedConversations: %w", err)
+	}
+	if q.getUsageSinceStmt, err = db.PrepareContext(ctx, getUsageSince); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUsageSince: %w", err)
+	}
+	if q.importMessageStmt, err = db.PrepareContext(ctx, importMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query ImportMessage: %w", err)
+	}
 	if q.insertMessageStmt, err = db.PrepareContext(ctx, insertMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query InsertMessage: %w", err)
 	}
 	if q.insertUsageStmt, err = db.PrepareContext(ctx, insertUsage); err != nil {
 		return nil, fmt.Errorf("error preparing query InsertUsage: %w", err)
 	}
+	if q.markConversationSyncedStmt, err = db.PrepareContext(ctx, markConversationSynced); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkConversationSynced: %w", err)
+	}
+	if q.markMessagesSyncedStmt, err = db.PrepareContext(ctx, markMessagesSynced); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkMessagesSynced: %w", err)
+	}
 	if q.nextConversationStmt, err = db.PrepareContext(ctx, nextConversation); err != nil {
 		return nil, fmt.Errorf("error preparing query NextConversation: %w", err)
 	}
+	if q.nextConversationWithTagStmt, err = db.PrepareContext(ctx, nextConversationWithTag); err != nil {
+		return nil, fmt.Errorf("error preparing query NextConversationWithTag: %w", err)
+	}
 	if q.previousConversationStmt, err = db.PrepareContext(ctx, previousConversation); err != nil {
 		return nil, fmt.Errorf("error preparing query PreviousConversation: %w", err)
 	}
+	if q.previousConversationWithTagStmt, err = db.PrepareContext(ctx, previousConversationWithTag); err != nil {
+		return nil, fmt.Errorf("error preparing query PreviousConversationWithTag: %w", err)
+	}
+	if q.restoreConversationStmt, err = db.PrepareContext(ctx, restoreConversation); err != nil {
+		return nil, fmt.Errorf("error preparing query RestoreConversation: %w", err)
+	}
+	if q.searchMessagesStmt, err = db.PrepareContext(ctx, searchMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchMessages: %w", err)
+	}
+	if q.setClientConfigMessageContextStmt, err = db.PrepareContext(ctx, setClientConfigMessageContext); err != nil {
+		return nil, fmt.Errorf("error preparing query SetClientConfigMessageContext: %w", err)
+	}
 	if q.setConfigValueStmt, err = db.PrepareContext(ctx, setConfigValue); err != nil {
 		return nil, fmt.Errorf("error preparing query SetConfigValue: %w", err)
 	}
+	if q.setConversationArchivedStmt, err = db.PrepareContext(ctx, setConversationArchived); err != nil {
+		return nil, fmt.Errorf("error preparing query SetConversationArchived: %w", err)
+	}
+	if q.setConversationClientConfigStmt, err = db.PrepareContext(ctx, setConversationClientConfig); err != nil {
+		return nil, fmt.Errorf("error preparing query SetConversationClientConfig: %w", err)
+	}
+	if q.setConversationLeafStmt, err = db.PrepareContext(ctx, setConversationLeaf); err != nil {
+		return nil, fmt.Errorf("error preparing query SetConversationLeaf: %w", err)
+	}
+	if q.setConversationMessageContextStmt, err = db.PrepareContext(ctx, setConversationMessageContext); err != nil {
+		return nil, fmt.Errorf("error preparing query SetConversationMessageContext: %w", err)
+	}
+	if q.setConversationNameStmt, err = db.PrepareContext(ctx, setConversationName); err != nil {
+		return nil, fmt.Errorf("error preparing query SetConversationName: %w", err)
+	}
+	if q.setConversationPersonaStmt, err = db.PrepareContext(ctx, setConversationPersona); err != nil {
+		return nil, fmt.Errorf("error preparing query SetConversationPersona: %w", err)
+	}
+	if q.setConversationProtectedStmt, err = db.PrepareContext(ctx, setConversationProtected); err != nil {
+		return nil, fmt.Errorf("error preparing query SetConversationProtected: %w", err)
+	}
+	if q.setMessageContentStmt, err = db.PrepareContext(ctx, setMessageContent); err != nil {
+		return nil, fmt.Errorf("error preparing query SetMessageContent: %w", err)
+	}
+	if q.setMessageExcludedStmt, err = db.PrepareContext(ctx, setMessageExcluded); err != nil {
+		return nil, fmt.Errorf("error preparing query SetMessageExcluded: %w", err)
+	}
+	if q.setMessagePinnedStmt, err = db.PrepareContext(ctx, setMessagePinned); err != nil {
+		return nil, fmt.Errorf("error preparing query SetMessagePinned: %w", err)
+	}
 	if q.setSelectedConversationStmt, err = db.PrepareContext(ctx, setSelectedConversation); err != nil {
 		return nil, fmt.Errorf("error preparing query SetSelectedConversation: %w", err)
 	}
+	if q.setSyncedConversationStmt, err = db.PrepareContext(ctx, setSyncedConversation); err != nil {
+		return nil, fmt.Errorf("error preparing query SetSyncedConversation: %w", err)
+	}
+	if q.setSyncedMessageStmt, err = db.PrepareContext(ctx, setSyncedMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query SetSyncedMessage: %w", err)
+	}
+	if q.tagConversationStmt, err = db.PrepareContext(ctx, tagConversation); err != nil {
+		return nil, fmt.Errorf("error preparing query TagConversation: %w", err)
+	}
+	if q.touchConversationStmt, err = db.PrepareContext(ctx, touchConversation); err != nil {
+		return nil, fmt.Errorf("error preparing query TouchConversation: %w", err)
+	}
+	if q.trashConversationStmt, err = db.PrepareContext(ctx, trashConversation); err != nil {
+		return nil, fmt.Errorf("error preparing query TrashConversation: %w", err)
+	}
 	if q.unsetSelectedConversationStmt, err = db.PrepareContext(ctx, unsetSelectedConversation); err != nil {
 		return nil, fmt.Errorf("error preparing query UnsetSelectedConversation: %w", err)
 	}
-	if q.updateClientConfigStmt, err = db.PrepareContext(ctx, updateClientConfig); err != nil {
-		return nil, fmt.Errorf("error preparing query UpdateClientConfig: %w", err)
+	if q.untagConversationStmt, err = db.PrepareContext(ctx, untagConversation); err != nil {
+		return nil, fmt.Errorf("error preparing query UntagConversation: %w", err)
 	}
 	if q.updateCredentialStmt, err = db.PrepareContext(ctx, updateCredential); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateCredential: %w", err)
@@ -125,9 +269,19 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing createConversationStmt: %w", cerr)
 		}
 	}
-	if q.cycleClientConfigStmt != nil {
-		if cerr := q.cycleClientConfigStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing cycleClientConfigStmt: %w", cerr)
+	if q.createSyncTombstoneStmt != nil {
+		if cerr := q.createSyncTombstoneStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createSyncTombstoneStmt: %w", cerr)
+		}
+	}
+	if q.createTagStmt != nil {
+		if cerr := q.createTagStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createTagStmt: %w", cerr)
+		}
+	}
+	if q.deleteConfigValueStmt != nil {
+		if cerr := q.deleteConfigValueStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteConfigValueStmt: %w", cerr)
 		}
 	}
 	if q.deleteConversationStmt != nil {
@@ -135,9 +289,34 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteConversationStmt: %w", cerr)
 		}
 	}
-	if q.deleteMessagesForCurrentConversationStmt != nil {
-		if cerr := q.deleteMessagesForCurrentConversationStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing deleteMessagesForCurrentConversationStmt: %w", cerr)
+	if q.deleteConversationTagsStmt != nil {
+		if cerr := q.deleteConversationTagsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteConversationTagsStmt: %w", cerr)
+		}
+	}
+	if q.deleteCredentialStmt != nil {
+		if cerr := q.deleteCredentialStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteCredentialStmt: %w", cerr)
+		}
+	}
+	if q.deleteMessageStmt != nil {
+		if cerr := q.deleteMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteMessageStmt: %w", cerr)
+		}
+	}
+	if q.deleteMessagesForConversationStmt != nil {
+		if cerr := q.deleteMessagesForConversationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteMessagesForConversationStmt: %w", cerr)
+		}
+	}
+	if q.deleteSyncTombstoneStmt != nil {
+		if cerr := q.deleteSyncTombstoneStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteSyncTombstoneStmt: %w", cerr)
+		}
+	}
+	if q.deleteUnusedTagsStmt != nil {
+		if cerr := q.deleteUnusedTagsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUnusedTagsStmt: %w", cerr)
 		}
 	}
 	if q.getActiveConversationStmt != nil {
@@ -145,11 +324,26 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getActiveConversationStmt: %w", cerr)
 		}
 	}
+	if q.getAllConversationTagsStmt != nil {
+		if cerr := q.getAllConversationTagsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAllConversationTagsStmt: %w", cerr)
+		}
+	}
 	if q.getClientConfigStmt != nil {
 		if cerr := q.getClientConfigStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getClientConfigStmt: %w", cerr)
 		}
 	}
+	if q.getClientConfigByNameStmt != nil {
+		if cerr := q.getClientConfigByNameStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getClientConfigByNameStmt: %w", cerr)
+		}
+	}
+	if q.getClientConfigsStmt != nil {
+		if cerr := q.getClientConfigsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getClientConfigsStmt: %w", cerr)
+		}
+	}
 	if q.getCompletionTokensStmt != nil {
 		if cerr := q.getCompletionTokensStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getCompletionTokensStmt: %w", cerr)
@@ -165,6 +359,36 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getConfigValueStmt: %w", cerr)
 		}
 	}
+	if q.getConversationStmt != nil {
+		if cerr := q.getConversationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getConversationStmt: %w", cerr)
+		}
+	}
+	if q.getConversationByUUIDStmt != nil {
+		if cerr := q.getConversationByUUIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getConversationByUUIDStmt: %w", cerr)
+		}
+	}
+	if q.getConversationIDsForTagStmt != nil {
+		if cerr := q.getConversationIDsForTagStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getConversationIDsForTagStmt: %w", cerr)
+		}
+	}
+	if q.getConversationIDsWithPinnedMessagesStmt != nil {
+		if cerr := q.getConversationIDsWithPinnedMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getConversationIDsWithPinnedMessagesStmt: %w", cerr)
+		}
+	}
+	if q.getConversationSummariesStmt != nil {
+		if cerr := q.getConversationSummariesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getConversationSummariesStmt: %w", cerr)
+		}
+	}
+	if q.getConversationTagsStmt != nil {
+		if cerr := q.getConversationTagsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getConversationTagsStmt: %w", cerr)
+		}
+	}
 	if q.getConversationsStmt != nil {
 		if cerr := q.getConversationsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getConversationsStmt: %w", cerr)
@@ -175,9 +399,24 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getCredentialStmt: %w", cerr)
 		}
 	}
-	if q.getLatestMessagesStmt != nil {
-		if cerr := q.getLatestMessagesStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing getLatestMessagesStmt: %w", cerr)
+	if q.getCredentialNamesStmt != nil {
+		if cerr := q.getCredentialNamesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getCredentialNamesStmt: %w", cerr)
+		}
+	}
+	if q.getLastMessagePerConversationStmt != nil {
+		if cerr := q.getLastMessagePerConversationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLastMessagePerConversationStmt: %w", cerr)
+		}
+	}
+	if q.getMessageStmt != nil {
+		if cerr := q.getMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessageStmt: %w", cerr)
+		}
+	}
+	if q.getMessageByUUIDStmt != nil {
+		if cerr := q.getMessageByUUIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessageByUUIDStmt: %w", cerr)
 		}
 	}
 	if q.getMessagesStmt != nil {
@@ -185,6 +424,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getMessagesStmt: %w", cerr)
 		}
 	}
+	if q.getMessagesForConversationStmt != nil {
+		if cerr := q.getMessagesForConversationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessagesForConversationStmt: %w", cerr)
+		}
+	}
 	if q.getPreviousMessageForRoleStmt != nil {
 		if cerr := q.getPreviousMessageForRoleStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getPreviousMessageForRoleStmt: %w", cerr)
@@ -195,11 +439,41 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getPromptTokensStmt: %w", cerr)
 		}
 	}
+	if q.getSyncTombstonesStmt != nil {
+		if cerr := q.getSyncTombstonesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSyncTombstonesStmt: %w", cerr)
+		}
+	}
+	if q.getTagByNameStmt != nil {
+		if cerr := q.getTagByNameStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTagByNameStmt: %w", cerr)
+		}
+	}
+	if q.getTagsStmt != nil {
+		if cerr := q.getTagsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTagsStmt: %w", cerr)
+		}
+	}
 	if q.getTotalTokensStmt != nil {
 		if cerr := q.getTotalTokensStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getTotalTokensStmt: %w", cerr)
 		}
 	}
+	if q.getTrashedConversationsStmt != nil {
+		if cerr := q.getTrashedConversationsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTrashedConversationsStmt: %w", cerr)
+		}
+	}
+	if q.getUsageSinceStmt != nil {
+		if cerr := q.getUsageSinceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUsageSinceStmt: %w", cerr)
+		}
+	}
+	if q.importMessageStmt != nil {
+		if cerr := q.importMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing importMessageStmt: %w", cerr)
+		}
+	}
 	if q.insertMessageStmt != nil {
 		if cerr := q.insertMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing insertMessageStmt: %w", cerr)
@@ -210,34 +484,144 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing insertUsageStmt: %w", cerr)
 		}
 	}
+	if q.markConversationSyncedStmt != nil {
+		if cerr := q.markConversationSyncedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markConversationSyncedStmt: %w", cerr)
+		}
+	}
+	if q.markMessagesSyncedStmt != nil {
+		if cerr := q.markMessagesSyncedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markMessagesSyncedStmt: %w", cerr)
+		}
+	}
 	if q.nextConversationStmt != nil {
 		if cerr := q.nextConversationStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing nextConversationStmt: %w", cerr)
 		}
 	}
+	if q.nextConversationWithTagStmt != nil {
+		if cerr := q.nextConversationWithTagStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing nextConversationWithTagStmt: %w", cerr)
+		}
+	}
 	if q.previousConversationStmt != nil {
 		if cerr := q.previousConversationStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing previousConversationStmt: %w", cerr)
 		}
 	}
+	if q.previousConversationWithTagStmt != nil {
+		if cerr := q.previousConversationWithTagStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing previousConversationWithTagStmt: %w", cerr)
+		}
+	}
+	if q.restoreConversationStmt != nil {
+		if cerr := q.restoreConversationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing restoreConversationStmt: %w", cerr)
+		}
+	}
+	if q.searchMessagesStmt != nil {
+		if cerr := q.searchMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchMessagesStmt: %w", cerr)
+		}
+	}
+	if q.setClientConfigMessageContextStmt != nil {
+		if cerr := q.setClientConfigMessageContextStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setClientConfigMessageContextStmt: %w", cerr)
+		}
+	}
 	if q.setConfigValueStmt != nil {
 		if cerr := q.setConfigValueStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing setConfigValueStmt: %w", cerr)
 		}
 	}
+	if q.setConversationArchivedStmt != nil {
+		if cerr := q.setConversationArchivedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setConversationArchivedStmt: %w", cerr)
+		}
+	}
+	if q.setConversationClientConfigStmt != nil {
+		if cerr := q.setConversationClientConfigStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setConversationClientConfigStmt: %w", cerr)
+		}
+	}
+	if q.setConversationLeafStmt != nil {
+		if cerr := q.setConversationLeafStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setConversationLeafStmt: %w", cerr)
+		}
+	}
+	if q.setConversationMessageContextStmt != nil {
+		if cerr := q.setConversationMessageContextStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setConversationMessageContextStmt: %w", cerr)
+		}
+	}
+	if q.setConversationNameStmt != nil {
+		if cerr := q.setConversationNameStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setConversationNameStmt: %w", cerr)
+		}
+	}
+	if q.setConversationPersonaStmt != nil {
+		if cerr := q.setConversationPersonaStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setConversationPersonaStmt: %w", cerr)
+		}
+	}
+	if q.setConversationProtectedStmt != nil {
+		if cerr := q.setConversationProtectedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setConversationProtectedStmt: %w", cerr)
+		}
+	}
+	if q.setMessageContentStmt != nil {
+		if cerr := q.setMessageContentStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setMessageContentStmt: %w", cerr)
+		}
+	}
+	if q.setMessageExcludedStmt != nil {
+		if cerr := q.setMessageExcludedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setMessageExcludedStmt: %w", cerr)
+		}
+	}
+	if q.setMessagePinnedStmt != nil {
+		if cerr := q.setMessagePinnedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setMessagePinnedStmt: %w", cerr)
+		}
+	}
 	if q.setSelectedConversationStmt != nil {
 		if cerr := q.setSelectedConversationStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing setSelectedConversationStmt: %w", cerr)
 		}
 	}
+	if q.setSyncedConversationStmt != nil {
+		if cerr := q.setSyncedConversationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setSyncedConversationStmt: %w", cerr)
+		}
+	}
+	if q.setSyncedMessageStmt != nil {
+		if cerr := q.setSyncedMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setSyncedMessageStmt: %w", cerr)
+		}
+	}
+	if q.tagConversationStmt != nil {
+		if cerr := q.tagConversationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing tagConversationStmt: %w", cerr)
+		}
+	}
+	if q.touchConversationStmt != nil {
+		if cerr := q.touchConversationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing touchConversationStmt: %w", cerr)
+		}
+	}
+	if q.trashConversationStmt != nil {
+		if cerr := q.trashConversationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing trashConversationStmt: %w", cerr)
+		}
+	}
 	if q.unsetSelectedConversationStmt != nil {
 		if cerr := q.unsetSelectedConversationStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing unsetSelectedConversationStmt: %w", cerr)
 		}
 	}
-	if q.updateClientConfigStmt != nil {
-		if cerr := q.updateClientConfigStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing updateClientConfigStmt: %w", cerr)
+	if q.untagConversationStmt != nil {
+		if cerr := q.untagConversationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing untagConversationStmt: %w", cerr)
 		}
 	}
 	if q.updateCredentialStmt != nil {
@@ -287,29 +671,77 @@ type Queries struct {
 	conversationCountStmt                    *sql.Stmt
 	countMessagesForConversationStmt         *sql.Stmt
 	createConversationStmt                   *sql.Stmt
-	cycleClientConfigStmt                    *sql.Stmt
+	createSyncTombstoneStmt                  *sql.Stmt
+	createTagStmt                            *sql.Stmt
+	deleteConfigValueStmt                    *sql.Stmt
 	deleteConversationStmt                   *sql.Stmt
-	deleteMessagesForCurrentConversationStmt *sql.Stmt
+	deleteConversationTagsStmt               *sql.Stmt
+	deleteCredentialStmt                     *sql.Stmt
+	deleteMessageStmt                        *sql.Stmt
+	deleteMessagesForConversationStmt        *sql.Stmt
+	deleteSyncTombstoneStmt                  *sql.Stmt
+	deleteUnusedTagsStmt                     *sql.Stmt
 	getActiveConversationStmt                *sql.Stmt
+	getAllConversationTagsStmt               *sql.Stmt
 	getClientConfigStmt                      *sql.Stmt
+	getClientConfigByNameStmt                *sql.Stmt
+	getClientConfigsStmt                     *sql.Stmt
 	getCompletionTokensStmt                  *sql.Stmt
 	getConfigStmt                            *sql.Stmt
 	getConfigValueStmt                       *sql.Stmt
+	getConversationStmt                      *sql.Stmt
+	getConversationByUUIDStmt                *sql.Stmt
+	getConversationIDsForTagStmt             *sql.Stmt
+	getConversationIDsWithPinnedMessagesStmt *sql.Stmt
+	getConversationSummariesStmt             *sql.Stmt
+	getConversationTagsStmt                  *sql.Stmt
 	getConversationsStmt                     *sql.Stmt
 	getCredentialStmt                        *sql.Stmt
-	getLatestMessagesStmt                    *sql.Stmt
+	getCredentialNamesStmt                   *sql.Stmt
+	getLastMessagePerConversationStmt        *sql.Stmt
+	getMessageStmt                           *sql.Stmt
+	getMessageByUUIDStmt                     *sql.Stmt
 	getMessagesStmt                          *sql.Stmt
+	getMessagesForConversationStmt           *sql.Stmt
 	getPreviousMessageForRoleStmt            *sql.Stmt
 	getPromptTokensStmt                      *sql.Stmt
+	getSyncTombstonesStmt                    *sql.Stmt
+	getTagByNameStmt                         *sql.Stmt
+	getTagsStmt                              *sql.Stmt
 	getTotalTokensStmt                       *sql.Stmt
+	getTrashedConversationsStmt              *sql.Stmt
+	getUsageSinceStmt                        *sql.Stmt
+	importMessageStmt                        *sql.Stmt
 	insertMessageStmt                        *sql.Stmt
 	insertUsageStmt                          *sql.Stmt
+	markConversationSyncedStmt               *sql.Stmt
+	markMessagesSyncedStmt                   *sql.Stmt
 	nextConversationStmt                     *sql.Stmt
+	nextConversationWithTagStmt              *sql.Stmt
 	previousConversationStmt                 *sql.Stmt
+	previousConversationWithTagStmt          *sql.Stmt
+	restoreConversationStmt                  *sql.Stmt
+	searchMessagesStmt                       *sql.Stmt
+	setClientConfigMessageContextStmt        *sql.Stmt
 	setConfigValueStmt                       *sql.Stmt
+	setConversationArchivedStmt              *sql.Stmt
+	setConversationClientConfigStmt          *sql.Stmt
+	setConversationLeafStmt                  *sql.Stmt
+	setConversationMessageContextStmt        *sql.Stmt
+	setConversationNameStmt                  *sql.Stmt
+	setConversationPersonaStmt               *sql.Stmt
+	setConversationProtectedStmt             *sql.Stmt
+	setMessageContentStmt                    *sql.Stmt
+	setMessageExcludedStmt                   *sql.Stmt
+	setMessagePinnedStmt                     *sql.Stmt
 	setSelectedConversationStmt              *sql.Stmt
+	setSyncedConversationStmt                *sql.Stmt
+	setSyncedMessageStmt                     *sql.Stmt
+	tagConversationStmt                      *sql.Stmt
+	touchConversationStmt                    *sql.Stmt
+	trashConversationStmt                    *sql.Stmt
 	unsetSelectedConversationStmt            *sql.Stmt
-	updateClientConfigStmt                   *sql.Stmt
+	untagConversationStmt                    *sql.Stmt
 	updateCredentialStmt                     *sql.Stmt
 }
 
@@ -320,29 +752,77 @@ func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 		conversationCountStmt:                    q.conversationCountStmt,
 		countMessagesForConversationStmt:         q.countMessagesForConversationStmt,
 		createConversationStmt:                   q.createConversationStmt,
-		cycleClientConfigStmt:                    q.cycleClientConfigStmt,
+		createSyncTombstoneStmt:                  q.createSyncTombstoneStmt,
+		createTagStmt:                            q.createTagStmt,
+		deleteConfigValueStmt:                    q.deleteConfigValueStmt,
 		deleteConversationStmt:                   q.deleteConversationStmt,
-		deleteMessagesForCurrentConversationStmt: q.deleteMessagesForCurrentConversationStmt,
+		deleteConversationTagsStmt:               q.deleteConversationTagsStmt,
+		deleteCredentialStmt:                     q.deleteCredentialStmt,
+		deleteMessageStmt:                        q.deleteMessageStmt,
+		deleteMessagesForConversationStmt:        q.deleteMessagesForConversationStmt,
+		deleteSyncTombstoneStmt:                  q.deleteSyncTombstoneStmt,
+		deleteUnusedTagsStmt:                     q.deleteUnusedTagsStmt,
 		getActiveConversationStmt:                q.getActiveConversationStmt,
+		getAllConversationTagsStmt:               q.getAllConversationTagsStmt,
 		getClientConfigStmt:                      q.getClientConfigStmt,
+		getClientConfigByNameStmt:                q.getClientConfigByNameStmt,
+		getClientConfigsStmt:                     q.getClientConfigsStmt,
 		getCompletionTokensStmt:                  q.getCompletionTokensStmt,
 		getConfigStmt:                            q.getConfigStmt,
 		getConfigValueStmt:                       q.getConfigValueStmt,
+		getConversationStmt:                      q.getConversationStmt,
+		getConversationByUUIDStmt:                q.getConversationByUUIDStmt,
+		getConversationIDsForTagStmt:             q.getConversationIDsForTagStmt,
+		getConversationIDsWithPinnedMessagesStmt: q.getConversationIDsWithPinnedMessagesStmt,
+		getConversationSummariesStmt:             q.getConversationSummariesStmt,
+		getConversationTagsStmt:                  q.getConversationTagsStmt,
 		getConversationsStmt:                     q.getConversationsStmt,
 		getCredentialStmt:                        q.getCredentialStmt,
-		getLatestMessagesStmt:                    q.getLatestMessagesStmt,
+		getCredentialNamesStmt:                   q.getCredentialNamesStmt,
+		getLastMessagePerConversationStmt:        q.getLastMessagePerConversationStmt,
+		getMessageStmt:                           q.getMessageStmt,
+		getMessageByUUIDStmt:                     q.getMessageByUUIDStmt,
 		getMessagesStmt:                          q.getMessagesStmt,
+		getMessagesForConversationStmt:           q.getMessagesForConversationStmt,
 		getPreviousMessageForRoleStmt:            q.getPreviousMessageForRoleStmt,
 		getPromptTokensStmt:                      q.getPromptTokensStmt,
+		getSyncTombstonesStmt:                    q.getSyncTombstonesStmt,
+		getTagByNameStmt:                         q.getTagByNameStmt,
+		getTagsStmt:                              q.getTagsStmt,
 		getTotalTokensStmt:                       q.getTotalTokensStmt,
+		getTrashedConversationsStmt:              q.getTrashedConversationsStmt,
+		getUsageSinceStmt:                        q.getUsageSinceStmt,
+		importMessageStmt:                        q.importMessageStmt,
 		insertMessageStmt:                        q.insertMessageStmt,
 		insertUsageStmt:                          q.insertUsageStmt,
+		markConversationSyncedStmt:               q.markConversationSyncedStmt,
+		markMessagesSyncedStmt:                   q.markMessagesSyncedStmt,
 		nextConversationStmt:                     q.nextConversationStmt,
+		nextConversationWithTagStmt:              q.nextConversationWithTagStmt,
 		previousConversationStmt:                 q.previousConversationStmt,
+		previousConversationWithTagStmt:          q.previousConversationWithTagStmt,
+		restoreConversationStmt:                  q.restoreConversationStmt,
+		searchMessagesStmt:                       q.searchMessagesStmt,
+		setClientConfigMessageContextStmt:        q.setClientConfigMessageContextStmt,
 		setConfigValueStmt:                       q.setConfigValueStmt,
+		setConversationArchivedStmt:              q.setConversationArchivedStmt,
+		setConversationClientConfigStmt:          q.setConversationClientConfigStmt,
+		setConversationLeafStmt:                  q.setConversationLeafStmt,
+		setConversationMessageContextStmt:        q.setConversationMessageContextStmt,
+		setConversationNameStmt:                  q.setConversationNameStmt,
+		setConversationPersonaStmt:               q.setConversationPersonaStmt,
+		setConversationProtectedStmt:             q.setConversationProtectedStmt,
+		setMessageContentStmt:                    q.setMessageContentStmt,
+		setMessageExcludedStmt:                   q.setMessageExcludedStmt,
+		setMessagePinnedStmt:                     q.setMessagePinnedStmt,
 		setSelectedConversationStmt:              q.setSelectedConversationStmt,
+		setSyncedConversationStmt:                q.setSyncedConversationStmt,
+		setSyncedMessageStmt:                     q.setSyncedMessageStmt,
+		tagConversationStmt:                      q.tagConversationStmt,
+		touchConversationStmt:                    q.touchConversationStmt,
+		trashConversationStmt:                    q.trashConversationStmt,
 		unsetSelectedConversationStmt:            q.unsetSelectedConversationStmt,
-		updateClientConfigStmt:                   q.updateClientConfigStmt,
+		untagConversationStmt:                    q.untagConversationStmt,
 		updateCredentialStmt:                     q.updateCredentialStmt,
 	}
 }
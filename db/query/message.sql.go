@@ -7,6 +7,8 @@ package query
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const countMessagesForConversation = `-- name: CountMessagesForConversation :one
@@ -22,39 +24,67 @@ func (q *Queries) CountMessagesForConversation(ctx context.Context, conversation
 	return count, err
 }
 
-const deleteMessagesForCurrentConversation = `-- name: DeleteMessagesForCurrentConversation :exec
-;
+const deleteMessage = `-- name: DeleteMessage :exec
+update message
+set content = '', pinned = 0,
+	deleted_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+	updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?
+`
+
+func (q *Queries) DeleteMessage(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.deleteMessageStmt, deleteMessage, id)
+	return err
+}
 
+const deleteMessagesForConversation = `-- name: DeleteMessagesForConversation :exec
 delete from message
-where id in (
-	select m.id
-	from message m
-	join conversation c on m.conversation_id = c.id
-	where c.selected = true
-)
+where conversation_id = ?
 `
 
-func (q *Queries) DeleteMessagesForCurrentConversation(ctx context.Context) error {
-	_, err := q.exec(ctx, q.deleteMessagesForCurrentConversationStmt, deleteMessagesForCurrentConversation)
+func (q *Queries) DeleteMessagesForConversation(ctx context.Context, conversationID int64) error {
+	_, err := q.exec(ctx, q.deleteMessagesForConversationStmt, deleteMessagesForConversation, conversationID)
 	return err
 }
 
-const getLatestMessages = `-- name: GetLatestMessages :many
-select id, timestamp, role, content, conversation_id
-from message
+const getConversationIDsWithPinnedMessages = `-- name: GetConversationIDsWithPinnedMessages :many
+select distinct conversation_id from message
+where pinned = true
+order by conversation_id
+`
+
+func (q *Queries) GetConversationIDsWithPinnedMessages(ctx context.Context) ([]int64, error) {
+	rows, err := q.query(ctx, q.getConversationIDsWithPinnedMessagesStmt, getConversationIDsWithPinnedMessages)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var conversation_id int64
+		if err := rows.Scan(&conversation_id); err != nil {
+			return nil, err
+		}
+		items = append(items, conversation_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLastMessagePerConversation = `-- name: GetLastMessagePerConversation :many
+select id, timestamp, role, content, conversation_id, parent_id, pinned, model, client_config, ttft_ms, duration_ms, prompt_tokens, completion_tokens, finish_reason, uuid, updated_at, synced_at, excluded, deleted_at from message
 where id in (
-	select m.id 
-	from message m 
-	join conversation c on m.conversation_id = c.id
-	where c.selected = true
-	order by m.id desc 
-	limit ?
+	select max(id) from message group by conversation_id
 )
-order by id
 `
 
-func (q *Queries) GetLatestMessages(ctx context.Context, limit int64) ([]Message, error) {
-	rows, err := q.query(ctx, q.getLatestMessagesStmt, getLatestMessages, limit)
+func (q *Queries) GetLastMessagePerConversation(ctx context.Context) ([]Message, error) {
+	rows, err := q.query(ctx, q.getLastMessagePerConversationStmt, getLastMessagePerConversation)
 	if err != nil {
 		return nil, err
 	}
@@ -68,6 +98,20 @@ func (q *Queries) GetLatestMessages(ctx context.Context, limit int64) ([]Message
 			&i.Role,
 			&i.Content,
 			&i.ConversationID,
+			&i.ParentID,
+			&i.Pinned,
+			&i.Model,
+			&i.ClientConfig,
+			&i.TtftMs,
+			&i.DurationMs,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.FinishReason,
+			&i.Uuid,
+			&i.UpdatedAt,
+			&i.SyncedAt,
+			&i.Excluded,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -82,8 +126,70 @@ func (q *Queries) GetLatestMessages(ctx context.Context, limit int64) ([]Message
 	return items, nil
 }
 
+const getMessage = `-- name: GetMessage :one
+select id, timestamp, role, content, conversation_id, parent_id, pinned, model, client_config, ttft_ms, duration_ms, prompt_tokens, completion_tokens, finish_reason, uuid, updated_at, synced_at, excluded, deleted_at from message where id = ?
+`
+
+func (q *Queries) GetMessage(ctx context.Context, id int64) (Message, error) {
+	row := q.queryRow(ctx, q.getMessageStmt, getMessage, id)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.Timestamp,
+		&i.Role,
+		&i.Content,
+		&i.ConversationID,
+		&i.ParentID,
+		&i.Pinned,
+		&i.Model,
+		&i.ClientConfig,
+		&i.TtftMs,
+		&i.DurationMs,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.FinishReason,
+		&i.Uuid,
+		&i.UpdatedAt,
+		&i.SyncedAt,
+		&i.Excluded,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getMessageByUUID = `-- name: GetMessageByUUID :one
+select id, timestamp, role, content, conversation_id, parent_id, pinned, model, client_config, ttft_ms, duration_ms, prompt_tokens, completion_tokens, finish_reason, uuid, updated_at, synced_at, excluded, deleted_at from message where uuid = ?
+`
+
+func (q *Queries) GetMessageByUUID(ctx context.Context, uuid sql.NullString) (Message, error) {
+	row := q.queryRow(ctx, q.getMessageByUUIDStmt, getMessageByUUID, uuid)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.Timestamp,
+		&i.Role,
+		&i.Content,
+		&i.ConversationID,
+		&i.ParentID,
+		&i.Pinned,
+		&i.Model,
+		&i.ClientConfig,
+		&i.TtftMs,
+		&i.DurationMs,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.FinishReason,
+		&i.Uuid,
+		&i.UpdatedAt,
+		&i.SyncedAt,
+		&i.Excluded,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
 const getMessages = `-- name: GetMessages :many
-SELECT id, timestamp, role, content, conversation_id FROM message
+SELECT id, timestamp, role, content, conversation_id, parent_id, pinned, model, client_config, ttft_ms, duration_ms, prompt_tokens, completion_tokens, finish_reason, uuid, updated_at, synced_at, excluded, deleted_at FROM message
 `
 
 func (q *Queries) GetMessages(ctx context.Context) ([]Message, error) {
@@ -101,6 +207,70 @@ func (q *Queries) GetMessages(ctx context.Context) ([]Message, error) {
 			&i.Role,
 			&i.Content,
 			&i.ConversationID,
+			&i.ParentID,
+			&i.Pinned,
+			&i.Model,
+			&i.ClientConfig,
+			&i.TtftMs,
+			&i.DurationMs,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.FinishReason,
+			&i.Uuid,
+			&i.UpdatedAt,
+			&i.SyncedAt,
+			&i.Excluded,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessagesForConversation = `-- name: GetMessagesForConversation :many
+select id, timestamp, role, content, conversation_id, parent_id, pinned, model, client_config, ttft_ms, duration_ms, prompt_tokens, completion_tokens, finish_reason, uuid, updated_at, synced_at, excluded, deleted_at
+from message
+where conversation_id = ?
+order by id
+`
+
+func (q *Queries) GetMessagesForConversation(ctx context.Context, conversationID int64) ([]Message, error) {
+	rows, err := q.query(ctx, q.getMessagesForConversationStmt, getMessagesForConversation, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.Timestamp,
+			&i.Role,
+			&i.Content,
+			&i.ConversationID,
+			&i.ParentID,
+			&i.Pinned,
+			&i.Model,
+			&i.ClientConfig,
+			&i.TtftMs,
+			&i.DurationMs,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.FinishReason,
+			&i.Uuid,
+			&i.UpdatedAt,
+			&i.SyncedAt,
+			&i.Excluded,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -116,22 +286,22 @@ func (q *Queries) GetMessages(ctx context.Context) ([]Message, error) {
 }
 
 const getPreviousMessageForRole = `-- name: GetPreviousMessageForRole :one
-select m.id, m.timestamp, m.role, m.content, m.conversation_id
-from message m
-join conversation c on m.conversation_id = c.id
-where m.role = ?
-and c.selected = true
-order by m.id desc
+select id, timestamp, role, content, conversation_id, parent_id, pinned, model, client_config, ttft_ms, duration_ms, prompt_tokens, completion_tokens, finish_reason, uuid, updated_at, synced_at, excluded, deleted_at
+from message
+where role = ?
+and conversation_id = ?
+order by id desc
 limit 1 offset ?
 `
 
 type GetPreviousMessageForRoleParams struct {
-	Role   string `json:"role"`
-	Offset int64  `json:"offset"`
+	Role           string `json:"role"`
+	ConversationID int64  `json:"conversation_id"`
+	Offset         int64  `json:"offset"`
 }
 
 func (q *Queries) GetPreviousMessageForRole(ctx context.Context, arg GetPreviousMessageForRoleParams) (Message, error) {
-	row := q.queryRow(ctx, q.getPreviousMessageForRoleStmt, getPreviousMessageForRole, arg.Role, arg.Offset)
+	row := q.queryRow(ctx, q.getPreviousMessageForRoleStmt, getPreviousMessageForRole, arg.Role, arg.ConversationID, arg.Offset)
 	var i Message
 	err := row.Scan(
 		&i.ID,
@@ -139,25 +309,300 @@ func (q *Queries) GetPreviousMessageForRole(ctx context.Context, arg GetPrevious
 		&i.Role,
 		&i.Content,
 		&i.ConversationID,
+		&i.ParentID,
+		&i.Pinned,
+		&i.Model,
+		&i.ClientConfig,
+		&i.TtftMs,
+		&i.DurationMs,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.FinishReason,
+		&i.Uuid,
+		&i.UpdatedAt,
+		&i.SyncedAt,
+		&i.Excluded,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const insertMessage = `-- name: InsertMessage :exec
+const importMessage = `-- name: ImportMessage :one
+insert into message (
+	uuid, timestamp, updated_at, role, content, pinned, excluded, deleted_at,
+	model, client_config, ttft_ms, duration_ms, prompt_tokens,
+	completion_tokens, finish_reason, conversation_id, parent_id
+)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+returning id, timestamp, role, content, conversation_id, parent_id, pinned, model, client_config, ttft_ms, duration_ms, prompt_tokens, completion_tokens, finish_reason, uuid, updated_at, synced_at, excluded, deleted_at
+`
+
+type ImportMessageParams struct {
+	Uuid             sql.NullString `json:"uuid"`
+	Timestamp        time.Time      `json:"timestamp"`
+	UpdatedAt        sql.NullTime   `json:"updated_at"`
+	Role             string         `json:"role"`
+	Content          string         `json:"content"`
+	Pinned           int64          `json:"pinned"`
+	Excluded         int64          `json:"excluded"`
+	DeletedAt        sql.NullTime   `json:"deleted_at"`
+	Model            sql.NullString `json:"model"`
+	ClientConfig     sql.NullString `json:"client_config"`
+	TtftMs           sql.NullInt64  `json:"ttft_ms"`
+	DurationMs       sql.NullInt64  `json:"duration_ms"`
+	PromptTokens     sql.NullInt64  `json:"prompt_tokens"`
+	CompletionTokens sql.NullInt64  `json:"completion_tokens"`
+	FinishReason     sql.NullString `json:"finish_reason"`
+	ConversationID   int64          `json:"conversation_id"`
+	ParentID         sql.NullInt64  `json:"parent_id"`
+}
+
+func (q *Queries) ImportMessage(ctx context.Context, arg ImportMessageParams) (Message, error) {
+	row := q.queryRow(ctx, q.importMessageStmt, importMessage,
+		arg.Uuid,
+		arg.Timestamp,
+		arg.UpdatedAt,
+		arg.Role,
+		arg.Content,
+		arg.Pinned,
+		arg.Excluded,
+		arg.DeletedAt,
+		arg.Model,
+		arg.ClientConfig,
+		arg.TtftMs,
+		arg.DurationMs,
+		arg.PromptTokens,
+		arg.CompletionTokens,
+		arg.FinishReason,
+		arg.ConversationID,
+		arg.ParentID,
+	)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.Timestamp,
+		&i.Role,
+		&i.Content,
+		&i.ConversationID,
+		&i.ParentID,
+		&i.Pinned,
+		&i.Model,
+		&i.ClientConfig,
+		&i.TtftMs,
+		&i.DurationMs,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.FinishReason,
+		&i.Uuid,
+		&i.UpdatedAt,
+		&i.SyncedAt,
+		&i.Excluded,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const insertMessage = `-- name: InsertMessage :one
 ;
 
-INSERT INTO message (role, content, conversation_id) 
-SELECT ?, ?, id
-from conversation
-where selected = true
+INSERT INTO message (
+	role, content, model, client_config, ttft_ms, duration_ms,
+	prompt_tokens, completion_tokens, finish_reason,
+	conversation_id, parent_id, uuid
+)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+returning id, timestamp, role, content, conversation_id, parent_id, pinned, model, client_config, ttft_ms, duration_ms, prompt_tokens, completion_tokens, finish_reason, uuid, updated_at, synced_at, excluded, deleted_at
 `
 
 type InsertMessageParams struct {
-	Role    string `json:"role"`
+	Role             string         `json:"role"`
+	Content          string         `json:"content"`
+	Model            sql.NullString `json:"model"`
+	ClientConfig     sql.NullString `json:"client_config"`
+	TtftMs           sql.NullInt64  `json:"ttft_ms"`
+	DurationMs       sql.NullInt64  `json:"duration_ms"`
+	PromptTokens     sql.NullInt64  `json:"prompt_tokens"`
+	CompletionTokens sql.NullInt64  `json:"completion_tokens"`
+	FinishReason     sql.NullString `json:"finish_reason"`
+	ConversationID   int64          `json:"conversation_id"`
+	ParentID         sql.NullInt64  `json:"parent_id"`
+	Uuid             sql.NullString `json:"uuid"`
+}
+
+func (q *Queries) InsertMessage(ctx context.Context, arg InsertMessageParams) (Message, error) {
+	row := q.queryRow(ctx, q.insertMessageStmt, insertMessage,
+		arg.Role,
+		arg.Content,
+		arg.Model,
+		arg.ClientConfig,
+		arg.TtftMs,
+		arg.DurationMs,
+		arg.PromptTokens,
+		arg.CompletionTokens,
+		arg.FinishReason,
+		arg.ConversationID,
+		arg.ParentID,
+		arg.Uuid,
+	)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.Timestamp,
+		&i.Role,
+		&i.Content,
+		&i.ConversationID,
+		&i.ParentID,
+		&i.Pinned,
+		&i.Model,
+		&i.ClientConfig,
+		&i.TtftMs,
+		&i.DurationMs,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.FinishReason,
+		&i.Uuid,
+		&i.UpdatedAt,
+		&i.SyncedAt,
+		&i.Excluded,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const markMessagesSynced = `-- name: MarkMessagesSynced :exec
+update message
+set synced_at = coalesce(updated_at, timestamp)
+where conversation_id = ?
+`
+
+func (q *Queries) MarkMessagesSynced(ctx context.Context, conversationID int64) error {
+	_, err := q.exec(ctx, q.markMessagesSyncedStmt, markMessagesSynced, conversationID)
+	return err
+}
+
+const searchMessages = `-- name: SearchMessages :many
+select m.id, m.timestamp, m.role, m.content, m.conversation_id, m.parent_id, m.pinned, m.model, m.client_config, m.ttft_ms, m.duration_ms, m.prompt_tokens, m.completion_tokens, m.finish_reason, m.uuid, m.updated_at, m.synced_at, m.excluded, m.deleted_at from message m
+join conversation c on c.id = m.conversation_id
+where m.content like ?
+and m.deleted_at is null
+and c.deleted_at is null
+order by m.id
+`
+
+func (q *Queries) SearchMessages(ctx context.Context, content string) ([]Message, error) {
+	rows, err := q.query(ctx, q.searchMessagesStmt, searchMessages, content)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.Timestamp,
+			&i.Role,
+			&i.Content,
+			&i.ConversationID,
+			&i.ParentID,
+			&i.Pinned,
+			&i.Model,
+			&i.ClientConfig,
+			&i.TtftMs,
+			&i.DurationMs,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.FinishReason,
+			&i.Uuid,
+			&i.UpdatedAt,
+			&i.SyncedAt,
+			&i.Excluded,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setMessageContent = `-- name: SetMessageContent :exec
+update message
+set content = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?
+`
+
+type SetMessageContentParams struct {
 	Content string `json:"content"`
+	ID      int64  `json:"id"`
 }
 
-func (q *Queries) InsertMessage(ctx context.Context, arg InsertMessageParams) error {
-	_, err := q.exec(ctx, q.insertMessageStmt, insertMessage, arg.Role, arg.Content)
+func (q *Queries) SetMessageContent(ctx context.Context, arg SetMessageContentParams) error {
+	_, err := q.exec(ctx, q.setMessageContentStmt, setMessageContent, arg.Content, arg.ID)
+	return err
+}
+
+const setMessageExcluded = `-- name: SetMessageExcluded :exec
+update message
+set excluded = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?
+`
+
+type SetMessageExcludedParams struct {
+	Excluded int64 `json:"excluded"`
+	ID       int64 `json:"id"`
+}
+
+func (q *Queries) SetMessageExcluded(ctx context.Context, arg SetMessageExcludedParams) error {
+	_, err := q.exec(ctx, q.setMessageExcludedStmt, setMessageExcluded, arg.Excluded, arg.ID)
+	return err
+}
+
+const setMessagePinned = `-- name: SetMessagePinned :exec
+update message
+set pinned = ?, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+where id = ?
+`
+
+type SetMessagePinnedParams struct {
+	Pinned int64 `json:"pinned"`
+	ID     int64 `json:"id"`
+}
+
+func (q *Queries) SetMessagePinned(ctx context.Context, arg SetMessagePinnedParams) error {
+	_, err := q.exec(ctx, q.setMessagePinnedStmt, setMessagePinned, arg.Pinned, arg.ID)
+	return err
+}
+
+const setSyncedMessage = `-- name: SetSyncedMessage :exec
+update message
+set content = ?, pinned = ?, excluded = ?, deleted_at = ?, updated_at = ?
+where id = ?
+`
+
+type SetSyncedMessageParams struct {
+	Content   string       `json:"content"`
+	Pinned    int64        `json:"pinned"`
+	Excluded  int64        `json:"excluded"`
+	DeletedAt sql.NullTime `json:"deleted_at"`
+	UpdatedAt sql.NullTime `json:"updated_at"`
+	ID        int64        `json:"id"`
+}
+
+func (q *Queries) SetSyncedMessage(ctx context.Context, arg SetSyncedMessageParams) error {
+	_, err := q.exec(ctx, q.setSyncedMessageStmt, setSyncedMessage,
+		arg.Content,
+		arg.Pinned,
+		arg.Excluded,
+		arg.DeletedAt,
+		arg.UpdatedAt,
+		arg.ID,
+	)
 	return err
 }
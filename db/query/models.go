@@ -21,10 +21,24 @@ type Config struct {
 }
 
 type Conversation struct {
-	ID        int64          `json:"id"`
-	Name      sql.NullString `json:"name"`
-	Protected int64          `json:"protected"`
-	Selected  int64          `json:"selected"`
+	ID             int64          `json:"id"`
+	Name           sql.NullString `json:"name"`
+	Protected      int64          `json:"protected"`
+	Selected       int64          `json:"selected"`
+	LeafID         sql.NullInt64  `json:"leaf_id"`
+	Archived       int64          `json:"archived"`
+	DeletedAt      sql.NullTime   `json:"deleted_at"`
+	ClientConfig   sql.NullString `json:"client_config"`
+	MessageContext sql.NullInt64  `json:"message_context"`
+	Persona        sql.NullString `json:"persona"`
+	Uuid           sql.NullString `json:"uuid"`
+	UpdatedAt      sql.NullTime   `json:"updated_at"`
+	SyncedAt       sql.NullTime   `json:"synced_at"`
+}
+
+type ConversationTag struct {
+	ConversationID int64 `json:"conversation_id"`
+	TagID          int64 `json:"tag_id"`
 }
 
 type Credential struct {
@@ -33,17 +47,42 @@ type Credential struct {
 }
 
 type Message struct {
-	ID             int64     `json:"id"`
-	Timestamp      time.Time `json:"timestamp"`
-	Role           string    `json:"role"`
-	Content        string    `json:"content"`
-	ConversationID int64     `json:"conversation_id"`
+	ID               int64          `json:"id"`
+	Timestamp        time.Time      `json:"timestamp"`
+	Role             string         `json:"role"`
+	Content          string         `json:"content"`
+	ConversationID   int64          `json:"conversation_id"`
+	ParentID         sql.NullInt64  `json:"parent_id"`
+	Pinned           int64          `json:"pinned"`
+	Model            sql.NullString `json:"model"`
+	ClientConfig     sql.NullString `json:"client_config"`
+	TtftMs           sql.NullInt64  `json:"ttft_ms"`
+	DurationMs       sql.NullInt64  `json:"duration_ms"`
+	PromptTokens     sql.NullInt64  `json:"prompt_tokens"`
+	CompletionTokens sql.NullInt64  `json:"completion_tokens"`
+	FinishReason     sql.NullString `json:"finish_reason"`
+	Uuid             sql.NullString `json:"uuid"`
+	UpdatedAt        sql.NullTime   `json:"updated_at"`
+	SyncedAt         sql.NullTime   `json:"synced_at"`
+	Excluded         int64          `json:"excluded"`
+	DeletedAt        sql.NullTime   `json:"deleted_at"`
+}
+
+type SyncTombstone struct {
+	Uuid      string    `json:"uuid"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type Tag struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
 }
 
 type Usage struct {
-	ID               int64     `json:"id"`
-	Timestamp        time.Time `json:"timestamp"`
-	PromptTokens     int64     `json:"prompt_tokens"`
-	CompletionTokens int64     `json:"completion_tokens"`
-	TotalTokens      int64     `json:"total_tokens"`
+	ID               int64          `json:"id"`
+	Timestamp        time.Time      `json:"timestamp"`
+	PromptTokens     int64          `json:"prompt_tokens"`
+	CompletionTokens int64          `json:"completion_tokens"`
+	TotalTokens      int64          `json:"total_tokens"`
+	Model            sql.NullString `json:"model"`
 }
@@ -0,0 +1,211 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.17.2
+// source: tag.sql
+
+package query
+
+import (
+	"context"
+)
+
+const createTag = `-- name: CreateTag :exec
+insert or ignore into tag (name) values (?)
+`
+
+func (q *Queries) CreateTag(ctx context.Context, name string) error {
+	_, err := q.exec(ctx, q.createTagStmt, createTag, name)
+	return err
+}
+
+const deleteConversationTags = `-- name: DeleteConversationTags :exec
+delete from conversation_tag where conversation_id = ?
+`
+
+func (q *Queries) DeleteConversationTags(ctx context.Context, conversationID int64) error {
+	_, err := q.exec(ctx, q.deleteConversationTagsStmt, deleteConversationTags, conversationID)
+	return err
+}
+
+const deleteUnusedTags = `-- name: DeleteUnusedTags :exec
+delete from tag where id not in (
+	select tag_id from conversation_tag
+)
+`
+
+func (q *Queries) DeleteUnusedTags(ctx context.Context) error {
+	_, err := q.exec(ctx, q.deleteUnusedTagsStmt, deleteUnusedTags)
+	return err
+}
+
+const getAllConversationTags = `-- name: GetAllConversationTags :many
+select ct.conversation_id, t.name
+from conversation_tag ct
+join tag t on t.id = ct.tag_id
+order by ct.conversation_id, t.name
+`
+
+type GetAllConversationTagsRow struct {
+	ConversationID int64  `json:"conversation_id"`
+	Name           string `json:"name"`
+}
+
+func (q *Queries) GetAllConversationTags(ctx context.Context) ([]GetAllConversationTagsRow, error) {
+	rows, err := q.query(ctx, q.getAllConversationTagsStmt, getAllConversationTags)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllConversationTagsRow
+	for rows.Next() {
+		var i GetAllConversationTagsRow
+		if err := rows.Scan(&i.ConversationID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getConversationIDsForTag = `-- name: GetConversationIDsForTag :many
+select ct.conversation_id
+from conversation_tag ct
+join tag t on t.id = ct.tag_id
+where t.name = ?
+order by ct.conversation_id
+`
+
+func (q *Queries) GetConversationIDsForTag(ctx context.Context, name string) ([]int64, error) {
+	rows, err := q.query(ctx, q.getConversationIDsForTagStmt, getConversationIDsForTag, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var conversation_id int64
+		if err := rows.Scan(&conversation_id); err != nil {
+			return nil, err
+		}
+		items = append(items, conversation_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getConversationTags = `-- name: GetConversationTags :many
+select t.id, t.name from tag t
+join conversation_tag ct on ct.tag_id = t.id
+where ct.conversation_id = ?
+order by t.name
+`
+
+func (q *Queries) GetConversationTags(ctx context.Context, conversationID int64) ([]Tag, error) {
+	rows, err := q.query(ctx, q.getConversationTagsStmt, getConversationTags, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tag
+	for rows.Next() {
+		var i Tag
+		if err := rows.Scan(&i.ID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTagByName = `-- name: GetTagByName :one
+select id, name from tag where name = ?
+`
+
+func (q *Queries) GetTagByName(ctx context.Context, name string) (Tag, error) {
+	row := q.queryRow(ctx, q.getTagByNameStmt, getTagByName, name)
+	var i Tag
+	err := row.Scan(&i.ID, &i.Name)
+	return i, err
+}
+
+const getTags = `-- name: GetTags :many
+select t.id, t.name, count(ct.conversation_id) as conversation_count
+from tag t
+left join conversation_tag ct on ct.tag_id = t.id
+group by t.id
+order by t.name
+`
+
+type GetTagsRow struct {
+	ID                int64  `json:"id"`
+	Name              string `json:"name"`
+	ConversationCount int64  `json:"conversation_count"`
+}
+
+func (q *Queries) GetTags(ctx context.Context) ([]GetTagsRow, error) {
+	rows, err := q.query(ctx, q.getTagsStmt, getTags)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTagsRow
+	for rows.Next() {
+		var i GetTagsRow
+		if err := rows.Scan(&i.ID, &i.Name, &i.ConversationCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const tagConversation = `-- name: TagConversation :exec
+insert or ignore into conversation_tag (conversation_id, tag_id) values (?, ?)
+`
+
+type TagConversationParams struct {
+	ConversationID int64 `json:"conversation_id"`
+	TagID          int64 `json:"tag_id"`
+}
+
+func (q *Queries) TagConversation(ctx context.Context, arg TagConversationParams) error {
+	_, err := q.exec(ctx, q.tagConversationStmt, tagConversation, arg.ConversationID, arg.TagID)
+	return err
+}
+
+const untagConversation = `-- name: UntagConversation :exec
+delete from conversation_tag where conversation_id = ? and tag_id = ?
+`
+
+type UntagConversationParams struct {
+	ConversationID int64 `json:"conversation_id"`
+	TagID          int64 `json:"tag_id"`
+}
+
+func (q *Queries) UntagConversation(ctx context.Context, arg UntagConversationParams) error {
+	_, err := q.exec(ctx, q.untagConversationStmt, untagConversation, arg.ConversationID, arg.TagID)
+	return err
+}
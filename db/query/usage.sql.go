@@ -7,6 +7,8 @@ package query
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const getCompletionTokens = `-- name: GetCompletionTokens :one
@@ -42,20 +44,62 @@ func (q *Queries) GetTotalTokens(ctx context.Context) (float64, error) {
 	return total, err
 }
 
+const getUsageSince = `-- name: GetUsageSince :many
+SELECT id, timestamp, prompt_tokens, completion_tokens, total_tokens, model from usage
+WHERE timestamp >= ?
+ORDER BY id
+`
+
+func (q *Queries) GetUsageSince(ctx context.Context, timestamp time.Time) ([]Usage, error) {
+	rows, err := q.query(ctx, q.getUsageSinceStmt, getUsageSince, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Usage
+	for rows.Next() {
+		var i Usage
+		if err := rows.Scan(
+			&i.ID,
+			&i.Timestamp,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.TotalTokens,
+			&i.Model,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const insertUsage = `-- name: InsertUsage :exec
 INSERT INTO usage 
-(prompt_tokens, completion_tokens, total_tokens)
+(prompt_tokens, completion_tokens, total_tokens, model)
 VALUES
-(?,?,?)
+(?,?,?,?)
 `
 
 type InsertUsageParams struct {
-	PromptTokens     int64 `json:"prompt_tokens"`
-	CompletionTokens int64 `json:"completion_tokens"`
-	TotalTokens      int64 `json:"total_tokens"`
+	PromptTokens     int64          `json:"prompt_tokens"`
+	CompletionTokens int64          `json:"completion_tokens"`
+	TotalTokens      int64          `json:"total_tokens"`
+	Model            sql.NullString `json:"model"`
 }
 
 func (q *Queries) InsertUsage(ctx context.Context, arg InsertUsageParams) error {
-	_, err := q.exec(ctx, q.insertUsageStmt, insertUsage, arg.PromptTokens, arg.CompletionTokens, arg.TotalTokens)
+	_, err := q.exec(ctx, q.insertUsageStmt, insertUsage,
+		arg.PromptTokens,
+		arg.CompletionTokens,
+		arg.TotalTokens,
+		arg.Model,
+	)
 	return err
 }
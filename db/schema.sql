@@ -8,19 +8,19 @@ CREATE TABLE usage (
 	prompt_tokens integer not null,
 	completion_tokens integer not null,
 	total_tokens integer not null
-);
+, model text);
 CREATE TABLE conversation (
 	id integer primary key,
 	name text,
 	protected integer not null default 0,
 	selected integer not null default 0
-);
+, leaf_id integer, archived integer not null default 0, deleted_at datetime, client_config text, message_context integer, persona text, uuid text, updated_at datetime, synced_at datetime);
 CREATE TABLE message (
 	id integer primary key,
 	timestamp datetime not null default current_timestamp,
 	role text not null,
 	content text not null, 
-	conversation_id integer not null default 0,
+	conversation_id integer not null default 0, parent_id integer, pinned integer not null default 0, model text, client_config text, ttft_ms integer, duration_ms integer, prompt_tokens integer, completion_tokens integer, finish_reason text, uuid text, updated_at datetime, synced_at datetime, excluded integer not null default 0, deleted_at datetime,
 	FOREIGN KEY (conversation_id) REFERENCES conversation(id)
 );
 CREATE INDEX message_conversation_id on message (conversation_id);
@@ -33,3 +33,22 @@ CREATE TABLE client_config (
 	model text not null,
 	message_context int not null
 );
+CREATE INDEX message_parent_id on message (parent_id);
+CREATE TABLE tag (
+	id integer primary key,
+	name text not null unique
+);
+CREATE TABLE conversation_tag (
+	conversation_id integer not null,
+	tag_id integer not null,
+	primary key (conversation_id, tag_id),
+	FOREIGN KEY (conversation_id) REFERENCES conversation(id),
+	FOREIGN KEY (tag_id) REFERENCES tag(id)
+);
+CREATE INDEX conversation_tag_tag_id on conversation_tag (tag_id);
+CREATE UNIQUE INDEX conversation_uuid on conversation (uuid);
+CREATE UNIQUE INDEX message_uuid on message (uuid);
+CREATE TABLE sync_tombstone (
+	uuid text primary key,
+	deleted_at datetime not null
+);
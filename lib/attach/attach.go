@@ -0,0 +1,296 @@
+// Package attach expands the files and directories mentioned in a prompt,
+// such as @main.go or @lib/, into the contents of the files, so that they
+// are sent along with it.
+package attach
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/collinvandyck/gpterm/lib/git"
+)
+
+const (
+	MaxFileSize  = 64 << 10  // the largest file that can be attached
+	MaxTotalSize = 256 << 10 // the most that can be attached to one prompt
+	MaxFiles     = 100       // the most files that can be attached to one prompt
+
+	// the tag around each attached file, which also marks where the
+	// prompt ends
+	attachmentStart = "<attachment path="
+	attachmentEnd   = "</attachment>"
+)
+
+// Expansion is a prompt with the files it mentions attached.
+type Expansion struct {
+	Text    string   // the prompt followed by the attached files
+	Files   []string // the files that were attached
+	Skipped []string // the files in mentioned directories that were not, and why
+}
+
+// Expand attaches the files mentioned in text to it. Paths are relative to
+// dir. A directory attaches the files in it that git doesn't ignore, leaving
+// out binary files and those larger than MaxFileSize. Mentions of paths that
+// don't exist are left alone, since they are more likely to be names. Files
+// that were attached before, such as when a prompt is sent again, are
+// replaced with what they contain now.
+func Expand(dir string, text string) (Expansion, error) {
+	text = Strip(text)
+	res := Expansion{Text: text}
+	var (
+		buf   strings.Builder
+		total int
+		seen  = map[string]bool{}
+	)
+	for _, mention := range Mentions(text) {
+		path, info, ok := resolve(dir, mention)
+		if !ok {
+			continue
+		}
+		files := []string{path}
+		if info.IsDir() {
+			var err error
+			files, err = listFiles(dir, path)
+			if err != nil {
+				return res, fmt.Errorf("@%s: %w", mention, err)
+			}
+		}
+		for _, file := range files {
+			if seen[file] {
+				continue
+			}
+			seen[file] = true
+			data, err := os.ReadFile(join(dir, file))
+			switch {
+			case err != nil && info.IsDir():
+				// gone since it was listed, or a submodule
+				continue
+			case err != nil:
+				return res, err
+			case isBinary(data) && info.IsDir():
+				res.Skipped = append(res.Skipped, file+" (binary)")
+				continue
+			case isBinary(data):
+				return res, fmt.Errorf("%s is not a text file", file)
+			case len(data) > MaxFileSize && info.IsDir():
+				res.Skipped = append(res.Skipped, file+" (too large)")
+				continue
+			case len(data) > MaxFileSize:
+				return res, fmt.Errorf("%s is larger than %s", file, formatSize(MaxFileSize))
+			}
+			total += len(data)
+			if total > MaxTotalSize {
+				return res, fmt.Errorf("the attached files are larger than %s", formatSize(MaxTotalSize))
+			}
+			if len(res.Files) == MaxFiles {
+				return res, fmt.Errorf("more than %d files are attached", MaxFiles)
+			}
+			res.Files = append(res.Files, file)
+			buf.WriteString("\n\n")
+			buf.WriteString(attachment(file, string(data)))
+		}
+	}
+	res.Text += buf.String()
+	return res, nil
+}
+
+// Strip removes the attached files from text, leaving the prompt as it was
+// typed.
+func Strip(text string) string {
+	if i := strings.Index(text, attachmentStart); i == 0 || i > 0 && strings.HasSuffix(text[:i], "\n\n") {
+		return strings.TrimSpace(text[:i])
+	}
+	return text
+}
+
+// Files returns the paths of the files attached to text.
+func Files(text string) []string {
+	if Strip(text) == text {
+		return nil
+	}
+	var res []string
+	for _, line := range strings.Split(text, "\n") {
+		if quoted, ok := strings.CutPrefix(line, attachmentStart); ok {
+			if path, err := strconv.Unquote(strings.TrimSuffix(quoted, ">")); err == nil {
+				res = append(res, path)
+			}
+		}
+	}
+	return res
+}
+
+// Mentions returns the paths mentioned in text, in the order they are
+// mentioned. A mention is an @ at the start of a word followed by the path.
+func Mentions(text string) []string {
+	var res []string
+	for _, field := range strings.Fields(text) {
+		if mention, ok := strings.CutPrefix(field, "@"); ok && mention != "" {
+			res = append(res, mention)
+		}
+	}
+	return res
+}
+
+// Complete returns the paths that the path being typed can be completed to.
+// Directories end in a slash, and hidden files are only offered once their
+// name is started with a dot.
+func Complete(dir string, partial string) []string {
+	i := strings.LastIndex(partial, "/") + 1
+	parent, prefix := partial[:i], partial[i:]
+	entries, err := os.ReadDir(join(dir, parent))
+	if err != nil {
+		return nil
+	}
+	var res []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".") {
+			continue
+		}
+		if entry.IsDir() {
+			name += "/"
+		}
+		res = append(res, parent+name)
+	}
+	return res
+}
+
+// resolve finds the file or directory a mention refers to, relative to dir.
+// Punctuation that ends a sentence is left out if the path doesn't exist
+// with it.
+func resolve(dir string, mention string) (string, fs.FileInfo, bool) {
+	for _, path := range []string{mention, strings.TrimRightFunc(mention, unicode.IsPunct)} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(join(dir, path)); err == nil {
+			return filepath.Clean(path), info, true
+		}
+	}
+	return "", nil, false
+}
+
+// listFiles returns the files in the directory at path, which is relative to
+// dir, that git doesn't ignore. Outside of a repository every file is listed
+// instead. Hidden files and directories are left out either way, since they
+// are where secrets such as .env files are kept. Listing stops as soon as
+// there are more than MaxFiles, so that mentioning a large tree such as a
+// home directory fails quickly.
+func listFiles(dir string, path string) ([]string, error) {
+	full := join(dir, path)
+	var files []string
+	tooMany := fmt.Errorf("more than %d files are attached", MaxFiles)
+	add := func(file string) bool {
+		if isHidden(file) {
+			return true
+		}
+		files = append(files, file)
+		return len(files) <= MaxFiles
+	}
+	err := git.ListFiles(full, add)
+	if err != nil && len(files) == 0 {
+		err = filepath.WalkDir(full, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(d.Name(), ".") && file != full {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(full, file)
+			if err != nil {
+				return err
+			}
+			if !add(filepath.ToSlash(rel)) {
+				return tooMany
+			}
+			return nil
+		})
+	}
+	switch {
+	case len(files) > MaxFiles:
+		return nil, tooMany
+	case err != nil:
+		return nil, err
+	case len(files) == 0:
+		return nil, errors.New("no files to attach")
+	}
+	sort.Strings(files)
+	for i, file := range files {
+		files[i] = filepath.Join(path, filepath.FromSlash(file))
+	}
+	return files, nil
+}
+
+// isHidden reports whether any part of a slash separated path starts with
+// a dot.
+func isHidden(path string) bool {
+	for _, part := range strings.Split(path, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// join returns path, or path in dir if it is relative.
+func join(dir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// attachment formats a file to be sent with the prompt, as a fenced code
+// block in the language of the file.
+func attachment(path string, content string) string {
+	language := ""
+	if lexer := lexers.Match(filepath.Base(path)); lexer != nil {
+		language = strings.ToLower(lexer.Config().Name)
+		if aliases := lexer.Config().Aliases; len(aliases) > 0 {
+			language = aliases[0]
+		}
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	fence := strings.Repeat("`", max(3, longestFence(content)+1))
+	// the blank lines keep the tags from running into the code block when
+	// the prompt is rendered as markdown
+	return fmt.Sprintf("%s%q>\n\n%s%s\n%s%s\n\n%s", attachmentStart, filepath.ToSlash(path), fence, language, content, fence, attachmentEnd)
+}
+
+// longestFence returns the length of the longest run of backticks that
+// starts a line of content, which the fence around it has to be longer than.
+func longestFence(content string) int {
+	var res int
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimLeft(line, " ")
+		res = max(res, len(line)-len(strings.TrimLeft(line, "`")))
+	}
+	return res
+}
+
+// isBinary reports whether data looks like something other than text, the
+// way git decides, by looking for a NUL byte near the start.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
+
+func formatSize(n int) string {
+	return fmt.Sprintf("%d KiB", n>>10)
+}
@@ -0,0 +1,117 @@
+package attach
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpand(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path string, content string) {
+		path = filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	write("main.go", "package main\n")
+	write("README.md", "# Title\n\n```sh\nmake\n```\n")
+	write("lib/a.py", "print('a')")
+	write("lib/b/c.txt", "c\n")
+	write("lib/.hidden", "secret\n")
+	write("lib/image.png", "\x89PNG\x00\x00")
+	write("lib/big.txt", strings.Repeat("x", MaxFileSize+1))
+
+	res, err := Expand(dir, "explain @main.go to @someone.")
+	require.NoError(t, err)
+	require.Equal(t, []string{"main.go"}, res.Files)
+	require.Equal(t, "explain @main.go to @someone.\n\n<attachment path=\"main.go\">\n\n```go\npackage main\n```\n\n</attachment>", res.Text)
+	require.Equal(t, "explain @main.go to @someone.", Strip(res.Text))
+
+	// attached again, the old contents are replaced
+	write("main.go", "package other\n")
+	again, err := Expand(dir, res.Text)
+	require.NoError(t, err)
+	require.Contains(t, again.Text, "package other")
+	require.NotContains(t, again.Text, "package main")
+
+	// the fence is longer than the one in the file, and punctuation after
+	// the path is left out
+	res, err = Expand(dir, "what does @README.md, say?")
+	require.NoError(t, err)
+	require.Equal(t, []string{"README.md"}, res.Files)
+	require.Contains(t, res.Text, "\n````md\n# Title\n")
+
+	res, err = Expand(dir, "look at @lib/ and @lib/a.py")
+	require.NoError(t, err)
+	require.Equal(t, []string{"lib/a.py", "lib/b/c.txt"}, res.Files)
+	require.Equal(t, []string{"lib/big.txt (too large)", "lib/image.png (binary)"}, res.Skipped)
+	require.Equal(t, res.Files, Files(res.Text))
+	require.NotContains(t, res.Text, "secret")
+
+	_, err = Expand(dir, "@lib/big.txt")
+	require.ErrorContains(t, err, "larger than 64 KiB")
+	_, err = Expand(dir, "@lib/image.png")
+	require.ErrorContains(t, err, "not a text file")
+
+	res, err = Expand(dir, "no mentions, me@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "no mentions, me@example.com", res.Text)
+	require.Empty(t, res.Files)
+	require.Empty(t, Files(res.Text))
+}
+
+func TestComplete(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "lib", "store"), 0o755))
+	for _, file := range []string{"lib/log.go", "lib/store/store.go", ".env", "main.go"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), nil, 0o644))
+	}
+	require.Equal(t, []string{"lib/", "main.go"}, Complete(dir, ""))
+	require.Equal(t, []string{"lib/log.go", "lib/store/"}, Complete(dir, "lib/"))
+	require.Equal(t, []string{"lib/store/"}, Complete(dir, "lib/s"))
+	require.Equal(t, []string{".env"}, Complete(dir, "."))
+	require.Empty(t, Complete(dir, "missing/"))
+}
+
+func TestListFiles(t *testing.T) {
+	for _, repo := range []bool{false, true} {
+		t.Run(fmt.Sprintf("repo=%v", repo), func(t *testing.T) {
+			dir := t.TempDir()
+			if repo {
+				out, err := exec.Command("git", "init", dir).CombinedOutput()
+				require.NoError(t, err, string(out))
+			}
+			write := func(path string) {
+				path = filepath.Join(dir, path)
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				require.NoError(t, os.WriteFile(path, []byte(path+"\n"), 0o644))
+			}
+			write("lib/a.go")
+			write("lib/.env")
+			write("lib/.config/token")
+			write(".env")
+
+			// hidden files aren't attached with their directory
+			res, err := Expand(dir, "@lib/ and @./")
+			require.NoError(t, err)
+			require.Equal(t, []string{"lib/a.go"}, res.Files)
+			res, err = Expand(dir, "@lib/.env")
+			require.NoError(t, err)
+			require.Equal(t, []string{"lib/.env"}, res.Files)
+
+			// a tree with too many files fails without listing all of it
+			for i := range MaxFiles * 3 {
+				write(fmt.Sprintf("many/%d.txt", i))
+			}
+			_, err = Expand(dir, "@many")
+			require.ErrorContains(t, err, "more than 100 files")
+			_, err = Expand(dir, "@.")
+			require.ErrorContains(t, err, "more than 100 files")
+		})
+	}
+}
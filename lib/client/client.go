@@ -2,12 +2,12 @@ package client
 
 import (
 	"context"
-	"embed"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/persona"
 	"github.com/sashabaranov/go-openai"
 )
 
@@ -21,6 +21,7 @@ type client struct {
 	openai        openai.Client
 	model         string
 	clientContext int
+	persona       string
 }
 
 func New(apiKey string, opts ...Option) (Client, error) {
@@ -51,22 +52,19 @@ type CompleteResult struct {
 	Response openai.ChatCompletionResponse
 }
 
-//go:embed preambles/*
-var preambles embed.FS
-
-func getPreamble(name string) string {
-	bs, err := preambles.ReadFile(fmt.Sprintf("preambles/%s.md", name))
-	if err != nil {
-		panic(err)
-	}
-	return strings.TrimSpace(string(bs))
-}
-
 func (c *client) preamble() []openai.ChatCompletionMessage {
+	content := c.persona
+	if content == "" {
+		var err error
+		content, err = persona.Load("", persona.Default)
+		if err != nil {
+			panic(err)
+		}
+	}
 	return []openai.ChatCompletionMessage{
 		{
 			Role:    openai.ChatMessageRoleAssistant,
-			Content: getPreamble("default"),
+			Content: content,
 		},
 	}
 
@@ -88,6 +86,8 @@ func (c *client) Stream(ctx context.Context, latest []query.Message, content str
 		Model:    c.model,
 		Messages: messages,
 		Stream:   true,
+		// the final chunk reports token usage for the whole response.
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
 	}
 	resp, err := c.openai.CreateChatCompletionStream(ctx, req)
 	if err != nil {
@@ -130,6 +130,15 @@ func (c *client) Complete(ctx context.Context, latest []query.Message, content s
 	return res, nil
 }
 
+// ValidateKey checks that the API key is accepted by OpenAI.
+func ValidateKey(ctx context.Context, apiKey string) error {
+	_, err := openai.NewClient(apiKey).ListModels(ctx)
+	if err != nil {
+		return fmt.Errorf("validate key: %w", err)
+	}
+	return nil
+}
+
 func (c *client) Update(opts ...Option) {
 	for _, o := range opts {
 		o(c, nil)
@@ -23,3 +23,11 @@ func WithClientContext(clientContext int) Option {
 		c.clientContext = clientContext
 	}
 }
+
+// WithPreamble sets the preamble sent ahead of every request. The default
+// persona is used if it is empty.
+func WithPreamble(preamble string) Option {
+	return func(c *client, rt *roundTripper) {
+		c.persona = preamble
+	}
+}
@@ -0,0 +1,86 @@
+package credential
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/collinvandyck/gpterm/lib/store"
+)
+
+const configCommandPrefix = "credential.command."
+
+// commandBackend runs an external command, such as `pass show openai`, and
+// uses its output as the credential. The commands themselves are stored in
+// the config table, so setting a credential in this backend sets the command
+// rather than the secret.
+type commandBackend struct {
+	store store.Repository
+}
+
+func NewCommand(str store.Repository) Backend {
+	return &commandBackend{store: str}
+}
+
+func (b *commandBackend) Name() string {
+	return "command"
+}
+
+func (b *commandBackend) Get(ctx context.Context, name string) (string, error) {
+	command, err := b.Command(ctx, name)
+	if err != nil || command == "" {
+		return "", err
+	}
+	return RunCommand(ctx, command)
+}
+
+// RunCommand runs a credential command with the shell and returns the first
+// line of its output.
+func RunCommand(ctx context.Context, command string) (string, error) {
+	ec := exec.CommandContext(ctx, "sh", "-c", command)
+	stderr := new(bytes.Buffer)
+	ec.Stderr = stderr
+	out, err := ec.Output()
+	if err != nil {
+		return "", fmt.Errorf("%q failed: %w: %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	// tools like pass print the secret on the first line and may follow it
+	// with other metadata.
+	val, _, _ := strings.Cut(string(out), "\n")
+	return strings.TrimSpace(val), nil
+}
+
+// Command returns the command configured for the credential, if any.
+func (b *commandBackend) Command(ctx context.Context, name string) (string, error) {
+	return b.store.GetConfigString(ctx, configCommandPrefix+name, "")
+}
+
+// Describe returns the command that would be run for the credential.
+func (b *commandBackend) Describe(ctx context.Context, name string) (string, error) {
+	command, err := b.Command(ctx, name)
+	return "$ " + command, err
+}
+
+func (b *commandBackend) Set(ctx context.Context, name string, command string) error {
+	return b.store.SetConfigString(ctx, configCommandPrefix+name, command)
+}
+
+func (b *commandBackend) Delete(ctx context.Context, name string) error {
+	return b.store.DeleteConfig(ctx, configCommandPrefix+name)
+}
+
+func (b *commandBackend) List(ctx context.Context) ([]string, error) {
+	config, err := b.store.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, c := range config {
+		if name, ok := strings.CutPrefix(c.Name, configCommandPrefix); ok {
+			res = append(res, name)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,165 @@
+// Package credential stores and resolves secrets such as the OpenAI API key
+// from a number of pluggable backends.
+package credential
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/collinvandyck/gpterm/lib/store"
+)
+
+var (
+	ErrReadOnly       = errors.New("backend is read-only")
+	ErrUnknownBackend = errors.New("unknown credential backend")
+)
+
+// Backend is a place credentials can be read from and possibly written to.
+// Get returns an empty string without an error if the credential is not set.
+type Backend interface {
+	Name() string
+	Get(ctx context.Context, name string) (string, error)
+	Set(ctx context.Context, name string, value string) error
+	Delete(ctx context.Context, name string) error
+	List(ctx context.Context) ([]string, error)
+}
+
+// Entry describes a credential found in a backend.
+type Entry struct {
+	Name        string
+	Backend     string
+	Value       string
+	Description string // safe to display in place of the value
+}
+
+// describer is implemented by backends that can describe a credential
+// without resolving its value.
+type describer interface {
+	Describe(ctx context.Context, name string) (string, error)
+}
+
+// Credentials resolves credentials by consulting each of its backends in
+// order and returning the first value that is set.
+type Credentials struct {
+	backends []Backend
+}
+
+// New returns the standard credential chain for a store. The environment
+// takes precedence, followed by configured commands, the encrypted file and
+// finally the sqlite credential table. Only the environment variables of the
+// store's profile are read. The encrypted file is left out if the
+// store has no directory to keep it in.
+func New(str store.Repository, opts ...Option) *Credentials {
+	o := options{
+		passphrase: PassphraseFromEnvOrTerminal,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	backends := []Backend{NewEnv(str.Profile()), NewCommand(str)}
+	if dir := str.Dir(); dir != "" {
+		backends = append(backends, NewFile(dir, o.passphrase))
+	}
+	backends = append(backends, NewSQLite(str))
+	return &Credentials{backends: backends}
+}
+
+type options struct {
+	passphrase PassphraseFunc
+}
+
+type Option func(*options)
+
+// WithPassphrase overrides how the passphrase for the encrypted file is
+// obtained.
+func WithPassphrase(fn PassphraseFunc) Option {
+	return func(o *options) {
+		o.passphrase = fn
+	}
+}
+
+// Get returns the value of the first backend that has the credential set,
+// as described by Lookup.
+func (c *Credentials) Get(ctx context.Context, name string) (string, error) {
+	entry, err := c.Lookup(ctx, name)
+	return entry.Value, err
+}
+
+// Lookup is like Get but also reports which backend supplied the value. A
+// backend that fails, such as a command that exits with an error or an
+// encrypted file that can't be unlocked, doesn't stop the backends after it
+// from being consulted. Its error is only returned if none of them has the
+// credential set.
+func (c *Credentials) Lookup(ctx context.Context, name string) (Entry, error) {
+	var errs []error
+	for _, b := range c.backends {
+		val, err := b.Get(ctx, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", b.Name(), err))
+			continue
+		}
+		if val != "" {
+			return Entry{Name: name, Backend: b.Name(), Value: val}, nil
+		}
+	}
+	return Entry{Name: name}, errors.Join(errs...)
+}
+
+// List returns every credential in every backend. A credential that is set in
+// more than one backend is listed once per backend, in resolution order.
+// Values are not resolved for backends that can describe a credential
+// without doing so, such as commands.
+func (c *Credentials) List(ctx context.Context) ([]Entry, error) {
+	var res []Entry
+	for _, b := range c.backends {
+		names, err := b.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			entry := Entry{Name: name, Backend: b.Name()}
+			if d, ok := b.(describer); ok {
+				entry.Description, err = d.Describe(ctx, name)
+			} else {
+				entry.Value, err = b.Get(ctx, name)
+				entry.Description = Mask(entry.Value)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+// Backend returns the backend with the specified name.
+func (c *Credentials) Backend(name string) (Backend, error) {
+	for _, b := range c.backends {
+		if b.Name() == name {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownBackend, name)
+}
+
+// Backends returns the names of the backends in resolution order.
+func (c *Credentials) Backends() []string {
+	res := make([]string, 0, len(c.backends))
+	for _, b := range c.backends {
+		res = append(res, b.Name())
+	}
+	return res
+}
+
+// Mask hides all but the last few characters of a secret.
+func Mask(value string) string {
+	const visible = 4
+	if len(value) <= visible*2 {
+		return "********"
+	}
+	return "********" + value[len(value)-visible:]
+}
@@ -0,0 +1,96 @@
+package credential
+
+import (
+	"context"
+	"testing"
+
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand(t *testing.T) {
+	ctx := context.Background()
+	str := store.NewMemory()
+	b := NewCommand(str)
+
+	val, err := b.Get(ctx, "api_key")
+	require.NoError(t, err)
+	require.Empty(t, val)
+
+	// only the first line of the output is used
+	require.NoError(t, b.Set(ctx, "api_key", "printf '  sk-command  \\nlogin: me\\n'"))
+	val, err = b.Get(ctx, "api_key")
+	require.NoError(t, err)
+	require.Equal(t, "sk-command", val)
+	names, err := b.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"api_key"}, names)
+
+	require.NoError(t, b.Set(ctx, "api_key", "echo locked >&2; exit 3"))
+	_, err = b.Get(ctx, "api_key")
+	require.ErrorContains(t, err, "exit status 3: locked")
+
+	require.NoError(t, b.Delete(ctx, "api_key"))
+	names, err = b.List(ctx)
+	require.NoError(t, err)
+	require.Empty(t, names)
+}
+
+func TestCredentials(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("GPTERM_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	str := store.NewMemory()
+	creds := New(str)
+	require.Equal(t, []string{"env", "command", "sqlite"}, creds.Backends())
+
+	entry, err := creds.Lookup(ctx, store.CredentialAPIKey)
+	require.NoError(t, err)
+	require.Equal(t, Entry{Name: store.CredentialAPIKey}, entry)
+
+	// each backend takes precedence over the ones after it
+	lookup := func(backend string, value string) {
+		t.Helper()
+		entry, err := creds.Lookup(ctx, store.CredentialAPIKey)
+		require.NoError(t, err)
+		require.Equal(t, backend, entry.Backend)
+		require.Equal(t, value, entry.Value)
+	}
+	require.NoError(t, str.SetCredential(ctx, store.CredentialAPIKey, "sk-sqlite"))
+	lookup("sqlite", "sk-sqlite")
+	command, err := creds.Backend("command")
+	require.NoError(t, err)
+	require.NoError(t, command.Set(ctx, store.CredentialAPIKey, "echo sk-command"))
+	lookup("command", "sk-command")
+	t.Setenv("OPENAI_API_KEY", "sk-env")
+	lookup("env", "sk-env")
+
+	entries, err := creds.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []Entry{
+		{Name: store.CredentialAPIKey, Backend: "env", Value: "sk-env", Description: "********"},
+		{Name: store.CredentialAPIKey, Backend: "command", Description: "$ echo sk-command"},
+		{Name: store.CredentialAPIKey, Backend: "sqlite", Value: "sk-sqlite", Description: "********lite"},
+	}, entries)
+
+	// a failing backend is passed over, and only reported if no other has
+	// the credential
+	t.Setenv("OPENAI_API_KEY", "")
+	require.NoError(t, command.Set(ctx, store.CredentialAPIKey, "exit 1"))
+	lookup("sqlite", "sk-sqlite")
+	require.NoError(t, str.DeleteCredential(ctx, store.CredentialAPIKey))
+	_, err = creds.Lookup(ctx, store.CredentialAPIKey)
+	require.ErrorContains(t, err, "command: \"exit 1\" failed")
+
+	// so is an encrypted file that can't be unlocked
+	dir := t.TempDir()
+	require.NoError(t, NewFile(dir, func(string) (string, error) { return "secret", nil }).Set(ctx, "github_token", "gh-file"))
+	creds = &Credentials{backends: []Backend{NewFile(dir, NoPassphrase), NewSQLite(str)}}
+	require.NoError(t, str.SetCredential(ctx, "github_token", "gh-sqlite"))
+	val, err := creds.Get(ctx, "github_token")
+	require.NoError(t, err)
+	require.Equal(t, "gh-sqlite", val)
+
+	_, err = creds.Backend("keychain")
+	require.ErrorIs(t, err, ErrUnknownBackend)
+}
@@ -0,0 +1,84 @@
+package credential
+
+import (
+	"context"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/collinvandyck/gpterm/lib/store"
+)
+
+// knownNames are the credentials gpterm itself uses.
+var knownNames = []string{
+	store.CredentialAPIKey,
+	store.CredentialGithubToken,
+}
+
+// envBackend reads credentials from environment variables. For the default
+// profile a credential named api_key is read from GPTERM_API_KEY, falling
+// back to OPENAI_API_KEY. Other profiles only read variables with their name
+// in them, such as GPTERM_WORK_API_KEY, so that a key exported for one
+// profile is never sent on behalf of another.
+type envBackend struct {
+	profile string
+}
+
+func NewEnv(profile string) Backend {
+	return envBackend{profile: profile}
+}
+
+func (envBackend) Name() string {
+	return "env"
+}
+
+func (b envBackend) Get(_ context.Context, name string) (string, error) {
+	for _, v := range EnvVars(b.profile, name) {
+		if val := strings.TrimSpace(os.Getenv(v)); val != "" {
+			return val, nil
+		}
+	}
+	return "", nil
+}
+
+func (envBackend) Set(context.Context, string, string) error {
+	return ErrReadOnly
+}
+
+func (envBackend) Delete(context.Context, string) error {
+	return ErrReadOnly
+}
+
+func (b envBackend) List(ctx context.Context) ([]string, error) {
+	var res []string
+	for _, name := range knownNames {
+		if val, _ := b.Get(ctx, name); val != "" {
+			res = append(res, name)
+		}
+	}
+	return res, nil
+}
+
+// EnvVars returns the environment variables consulted for a credential of a
+// profile, in order.
+func EnvVars(profile string, name string) []string {
+	if profile != "" && profile != store.DefaultProfile {
+		return []string{"GPTERM_" + envName(profile) + "_" + envName(name)}
+	}
+	res := []string{"GPTERM_" + envName(name)}
+	if name == store.CredentialAPIKey {
+		res = append(res, "OPENAI_API_KEY")
+	}
+	return res
+}
+
+// envName turns a name into the form used in environment variables, such as
+// my-work into MY_WORK.
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,38 @@
+package credential
+
+import (
+	"context"
+	"testing"
+
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnv(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+	t.Setenv("GPTERM_GITHUB_TOKEN", "gh-default")
+	t.Setenv("GPTERM_MY_WORK_API_KEY", "sk-work")
+
+	require.Equal(t, []string{"GPTERM_API_KEY", "OPENAI_API_KEY"}, EnvVars(store.DefaultProfile, store.CredentialAPIKey))
+	require.Equal(t, []string{"GPTERM_MY_WORK_API_KEY"}, EnvVars("my-work", store.CredentialAPIKey))
+
+	env := NewEnv(store.DefaultProfile)
+	val, err := env.Get(ctx, store.CredentialAPIKey)
+	require.NoError(t, err)
+	require.Equal(t, "sk-openai", val)
+	t.Setenv("GPTERM_API_KEY", "sk-gpterm")
+	val, err = env.Get(ctx, store.CredentialAPIKey)
+	require.NoError(t, err)
+	require.Equal(t, "sk-gpterm", val)
+
+	// other profiles don't see the default profile's variables
+	env = NewEnv("my-work")
+	val, err = env.Get(ctx, store.CredentialAPIKey)
+	require.NoError(t, err)
+	require.Equal(t, "sk-work", val)
+	names, err := env.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{store.CredentialAPIKey}, names)
+	require.ErrorIs(t, env.Set(ctx, store.CredentialAPIKey, "x"), ErrReadOnly)
+}
@@ -0,0 +1,210 @@
+package credential
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	FileName = "credentials.enc"
+
+	fileVersion = 1
+	saltSize    = 16
+	keySize     = 32
+)
+
+var ErrBadPassphrase = errors.New("incorrect passphrase or corrupt credentials file")
+
+// fileBackend keeps credentials in a file encrypted with AES-GCM using a key
+// derived from a passphrase with scrypt. The file is only decrypted the first
+// time a credential is needed, and the passphrase is not asked for at all if
+// the file does not exist.
+type fileBackend struct {
+	path       string
+	passphrase PassphraseFunc
+	mu         sync.Mutex
+	loaded     bool
+	key        []byte
+	salt       []byte
+	data       map[string]string
+}
+
+type sealedFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+func NewFile(dir string, passphrase PassphraseFunc) Backend {
+	return &fileBackend{
+		path:       filepath.Join(dir, FileName),
+		passphrase: passphrase,
+	}
+}
+
+func (b *fileBackend) Name() string {
+	return "file"
+}
+
+func (b *fileBackend) Get(_ context.Context, name string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.load(); err != nil {
+		return "", err
+	}
+	return b.data[name], nil
+}
+
+func (b *fileBackend) Set(_ context.Context, name string, value string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.load(); err != nil {
+		return err
+	}
+	b.data[name] = value
+	return b.save()
+}
+
+func (b *fileBackend) Delete(_ context.Context, name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.load(); err != nil {
+		return err
+	}
+	if _, ok := b.data[name]; !ok {
+		return nil
+	}
+	delete(b.data, name)
+	return b.save()
+}
+
+func (b *fileBackend) List(context.Context) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(b.data))
+	for name := range b.data {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+func (b *fileBackend) load() error {
+	if b.loaded {
+		return nil
+	}
+	bs, err := os.ReadFile(b.path)
+	switch {
+	case os.IsNotExist(err):
+		b.data = map[string]string{}
+		b.loaded = true
+		return nil
+	case err != nil:
+		return err
+	}
+	var sealed sealedFile
+	if err := json.Unmarshal(bs, &sealed); err != nil {
+		return fmt.Errorf("%s: %w", b.path, err)
+	}
+	if sealed.Version != fileVersion {
+		return fmt.Errorf("%s: unsupported version %d", b.path, sealed.Version)
+	}
+	passphrase, err := b.passphrase(fmt.Sprintf("Passphrase for %s: ", b.path))
+	if err != nil {
+		return err
+	}
+	key, err := deriveKey(passphrase, sealed.Salt)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	plain, err := gcm.Open(nil, sealed.Nonce, sealed.Data, nil)
+	if err != nil {
+		return ErrBadPassphrase
+	}
+	data := map[string]string{}
+	if err := json.Unmarshal(plain, &data); err != nil {
+		return fmt.Errorf("%s: %w", b.path, err)
+	}
+	b.key, b.salt, b.data = key, sealed.Salt, data
+	b.loaded = true
+	return nil
+}
+
+func (b *fileBackend) save() error {
+	if b.key == nil {
+		// this is a new file, so a passphrase has to be chosen.
+		passphrase, err := b.passphrase(fmt.Sprintf("New passphrase for %s: ", b.path))
+		if err != nil {
+			return err
+		}
+		if passphrase == "" {
+			return errors.New("passphrase must not be empty")
+		}
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		key, err := deriveKey(passphrase, salt)
+		if err != nil {
+			return err
+		}
+		b.key, b.salt = key, salt
+	}
+	plain, err := json.Marshal(b.data)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(b.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	bs, err := json.Marshal(sealedFile{
+		Version: fileVersion,
+		Salt:    b.salt,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, plain, nil),
+	})
+	if err != nil {
+		return err
+	}
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, bs, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.path)
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, keySize)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,68 @@
+package credential
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	var prompts []string
+	passphrase := func(phrase string) PassphraseFunc {
+		return func(prompt string) (string, error) {
+			prompts = append(prompts, prompt)
+			return phrase, nil
+		}
+	}
+
+	// nothing is asked for until there is a file to unlock or create
+	b := NewFile(dir, passphrase("correct horse"))
+	val, err := b.Get(ctx, "api_key")
+	require.NoError(t, err)
+	require.Empty(t, val)
+	require.Empty(t, prompts)
+	require.NoError(t, b.Set(ctx, "api_key", "sk-secret"))
+	require.NoError(t, b.Set(ctx, "github_token", "gh-secret"))
+	require.Len(t, prompts, 1)
+	require.Contains(t, prompts[0], "New passphrase")
+
+	// the secrets are not stored in the clear
+	bs, err := os.ReadFile(filepath.Join(dir, FileName))
+	require.NoError(t, err)
+	require.NotContains(t, string(bs), "sk-secret")
+	info, err := os.Stat(filepath.Join(dir, FileName))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// another instance decrypts them with the same passphrase
+	prompts = nil
+	b = NewFile(dir, passphrase("correct horse"))
+	val, err = b.Get(ctx, "api_key")
+	require.NoError(t, err)
+	require.Equal(t, "sk-secret", val)
+	names, err := b.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"api_key", "github_token"}, names)
+	require.NoError(t, b.Delete(ctx, "github_token"))
+	require.Len(t, prompts, 1)
+
+	b = NewFile(dir, passphrase("correct horse"))
+	names, err = b.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"api_key"}, names)
+
+	// and refuses to with a different one
+	b = NewFile(dir, passphrase("wrong"))
+	_, err = b.Get(ctx, "api_key")
+	require.ErrorIs(t, err, ErrBadPassphrase)
+	require.ErrorIs(t, b.Set(ctx, "api_key", "sk-other"), ErrBadPassphrase)
+
+	// a new file needs a passphrase
+	b = NewFile(t.TempDir(), passphrase(""))
+	require.ErrorContains(t, b.Set(ctx, "api_key", "sk-secret"), "must not be empty")
+}
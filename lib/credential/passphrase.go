@@ -0,0 +1,43 @@
+package credential
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// PassphraseEnv can be set to unlock the encrypted credentials file without
+// being prompted.
+const PassphraseEnv = "GPTERM_PASSPHRASE"
+
+// PassphraseFunc returns the passphrase for the encrypted credentials file.
+// The prompt describes what the passphrase is for.
+type PassphraseFunc func(prompt string) (string, error)
+
+// PassphraseFromEnvOrTerminal reads the passphrase from GPTERM_PASSPHRASE if
+// it is set, and otherwise prompts for it on the controlling terminal.
+func PassphraseFromEnvOrTerminal(prompt string) (string, error) {
+	if val, ok := os.LookupEnv(PassphraseEnv); ok {
+		return val, nil
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("no terminal to prompt for a passphrase; set %s instead", PassphraseEnv)
+	}
+	defer tty.Close()
+	fmt.Fprint(tty, prompt)
+	bs, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(bs), "\r\n"), nil
+}
+
+// NoPassphrase is used where prompting is not possible.
+func NoPassphrase(string) (string, error) {
+	return "", errors.New("the encrypted credentials file is locked")
+}
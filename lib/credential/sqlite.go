@@ -0,0 +1,37 @@
+package credential
+
+import (
+	"context"
+
+	"github.com/collinvandyck/gpterm/lib/store"
+)
+
+// sqliteBackend keeps credentials in plaintext in the credential table of
+// the gpterm database.
+type sqliteBackend struct {
+	store store.Repository
+}
+
+func NewSQLite(str store.Repository) Backend {
+	return &sqliteBackend{store: str}
+}
+
+func (b *sqliteBackend) Name() string {
+	return "sqlite"
+}
+
+func (b *sqliteBackend) Get(ctx context.Context, name string) (string, error) {
+	return b.store.GetCredential(ctx, name)
+}
+
+func (b *sqliteBackend) Set(ctx context.Context, name string, value string) error {
+	return b.store.SetCredential(ctx, name, value)
+}
+
+func (b *sqliteBackend) Delete(ctx context.Context, name string) error {
+	return b.store.DeleteCredential(ctx, name)
+}
+
+func (b *sqliteBackend) List(ctx context.Context) ([]string, error) {
+	return b.store.ListCredentials(ctx)
+}
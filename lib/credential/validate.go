@@ -0,0 +1,44 @@
+package credential
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/collinvandyck/gpterm/lib/client"
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/google/go-github/v39/github"
+	"golang.org/x/oauth2"
+)
+
+// Validate checks a credential against the provider it belongs to. Only
+// credentials gpterm knows how to check are validated; any other credential
+// is accepted as is.
+func Validate(ctx context.Context, name string, value string) error {
+	switch name {
+	case store.CredentialAPIKey:
+		return client.ValidateKey(ctx, value)
+	case store.CredentialGithubToken:
+		return validateGithubToken(ctx, value)
+	default:
+		return nil
+	}
+}
+
+func validateGithubToken(ctx context.Context, token string) error {
+	tokenSrc := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	c := github.NewClient(&http.Client{
+		Transport: &oauth2.Transport{
+			Base:   http.DefaultTransport,
+			Source: tokenSrc,
+		},
+	})
+	_, resp, err := c.Users.Get(ctx, "")
+	if err != nil {
+		return err
+	}
+	if resp != nil && resp.StatusCode >= 400 {
+		return fmt.Errorf("github rejected the token with status code %d", resp.StatusCode)
+	}
+	return nil
+}
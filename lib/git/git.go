@@ -1,6 +1,8 @@
 package git
 
 import (
+	"bufio"
+	"bytes"
 	"os"
 	"os/exec"
 	"strings"
@@ -26,3 +28,45 @@ func ProjectDir() (string, error) {
 	projectDir := strings.TrimSpace(string(root))
 	return projectDir, nil
 }
+
+// ListFiles calls fn with each file in dir that git doesn't ignore, tracked
+// or not, relative to dir. Listing stops early if fn returns false. It fails
+// if dir isn't in a git repository.
+func ListFiles(dir string, fn func(file string) bool) error {
+	ec := exec.Command("git", "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	ec.Dir = dir
+	out, err := ec.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := ec.Start(); err != nil {
+		return err
+	}
+	s := bufio.NewScanner(out)
+	s.Split(splitNUL)
+	for s.Scan() {
+		if !fn(s.Text()) {
+			// git may have a lot more to list, such as in a home directory
+			ec.Process.Kill()
+			ec.Wait()
+			return nil
+		}
+	}
+	if err := s.Err(); err != nil {
+		ec.Process.Kill()
+		ec.Wait()
+		return err
+	}
+	return ec.Wait()
+}
+
+// splitNUL is a bufio.SplitFunc for NUL terminated fields.
+func splitNUL(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
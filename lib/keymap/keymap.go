@@ -0,0 +1,293 @@
+// Package keymap binds keys to the actions of the TUI. Every action has
+// default keys, which can be changed in a keymap file in the store
+// directory. A binding is either a single key, such as "ctrl+p" or "f1", or a
+// chord of keys pressed one after the other, such as "ctrl+k n".
+package keymap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const FileName = "keymap.json"
+
+// Action is something the TUI can be told to do with a key.
+type Action string
+
+const (
+	Editor           Action = "editor"
+	PrevConversation Action = "prev-conversation"
+	NextConversation Action = "next-conversation"
+	DropConversation Action = "drop-conversation"
+	LessContext      Action = "less-context"
+	MoreContext      Action = "more-context"
+	CycleModel       Action = "cycle-model"
+	Branches         Action = "branches"
+	Details          Action = "details"
+	Select           Action = "select"
+	History          Action = "history"
+	Multiline        Action = "multiline"
+)
+
+// ActionInfo describes an action and the keys it is bound to by default.
+type ActionInfo struct {
+	Action      Action
+	Description string
+	Keys        []string
+}
+
+// Actions is the registry of actions that can be bound, in the order they
+// are shown in help.
+var Actions = []ActionInfo{
+	{Editor, "Edit the prompt in $EDITOR", []string{"ctrl+y"}},
+	{PrevConversation, "Go to the previous conversation", []string{"ctrl+p"}},
+	{NextConversation, "Go to the next conversation", []string{"ctrl+n"}},
+	{DropConversation, "Drop the conversation, pressed twice", []string{"ctrl+x"}},
+	{LessContext, "Send less context", []string{"f1"}},
+	{MoreContext, "Send more context", []string{"f2"}},
+	{CycleModel, "Switch to the next model", []string{"f3"}},
+	{Branches, "Open the branch navigator", []string{"f4"}},
+	{Details, "Toggle response details", []string{"f5"}},
+	{Select, "Open the message selector", []string{"f6"}},
+	{History, "Open the history viewer", []string{"f7"}},
+	{Multiline, "Toggle multi-line input, where Enter adds a line", []string{"f8"}},
+}
+
+// reserved keys can't be bound because they always do the same thing.
+var reserved = map[string]string{
+	"ctrl+c":    "quit",
+	"ctrl+d":    "quit",
+	"enter":     "send the prompt",
+	"esc":       "cancel",
+	"up":        "prompt history",
+	"down":      "prompt history",
+	"alt+enter": "add a line or send the prompt",
+	"ctrl+j":    "add a line",
+	"tab":       "complete commands and paths",
+	"backspace": "edit the prompt",
+	"delete":    "edit the prompt",
+	"left":      "move the cursor",
+	"right":     "move the cursor",
+	"home":      "move the cursor",
+	"end":       "move the cursor",
+}
+
+// Keymap is the set of bindings in effect.
+type Keymap struct {
+	bindings map[Action][]string // the keys of each action
+	actions  map[string]Action   // the action of each binding
+}
+
+// Default returns the keymap with the default bindings.
+func Default() Keymap {
+	km, err := build(nil)
+	if err != nil {
+		panic(err)
+	}
+	return km
+}
+
+// Path returns the path of the keymap file for the store directory storeDir.
+func Path(storeDir string) string {
+	return filepath.Join(storeDir, FileName)
+}
+
+// Load returns the keymap for the store directory storeDir. Bindings in the
+// keymap file replace the defaults of the actions they name, and an action
+// bound to an empty list is unbound. It is an error for the file to name an
+// unknown action or key, or to bind a key to more than one action.
+func Load(storeDir string) (Keymap, error) {
+	if storeDir == "" {
+		return Default(), nil
+	}
+	path := Path(storeDir)
+	bs, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Default(), nil
+	}
+	if err != nil {
+		return Keymap{}, err
+	}
+	var overrides map[Action][]string
+	if err := json.Unmarshal(bs, &overrides); err != nil {
+		return Keymap{}, fmt.Errorf("%s: %w", path, err)
+	}
+	km, err := build(overrides)
+	if err != nil {
+		return Keymap{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return km, nil
+}
+
+func build(overrides map[Action][]string) (Keymap, error) {
+	var errs []error
+	known := map[Action]bool{}
+	for _, info := range Actions {
+		known[info.Action] = true
+	}
+	names := make([]string, 0, len(overrides))
+	for action := range overrides {
+		names = append(names, string(action))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !known[Action(name)] {
+			errs = append(errs, fmt.Errorf("unknown action %q", name))
+		}
+	}
+	km := Keymap{
+		bindings: map[Action][]string{},
+		actions:  map[string]Action{},
+	}
+	for _, info := range Actions {
+		keys, ok := overrides[info.Action]
+		if !ok {
+			keys = info.Keys
+		}
+		for _, key := range keys {
+			binding, err := normalize(key)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", info.Action, err))
+				continue
+			}
+			if other, ok := km.actions[binding]; ok {
+				errs = append(errs, fmt.Errorf("%q is bound to both %s and %s", binding, other, info.Action))
+				continue
+			}
+			km.actions[binding] = info.Action
+			km.bindings[info.Action] = append(km.bindings[info.Action], binding)
+		}
+	}
+	// a chord can't start with a key that does something on its own
+	for binding, action := range km.actions {
+		keys := strings.Fields(binding)
+		for i := 1; i < len(keys); i++ {
+			prefix := strings.Join(keys[:i], " ")
+			if other, ok := km.actions[prefix]; ok {
+				errs = append(errs, fmt.Errorf("%q of %s starts with %q, which is bound to %s", binding, action, prefix, other))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return Keymap{}, errors.Join(errs...)
+	}
+	return km, nil
+}
+
+// normalize checks that binding names keys that can be pressed and returns
+// it in the form keys are matched in.
+func normalize(binding string) (string, error) {
+	keys := strings.Fields(strings.ToLower(binding))
+	if len(keys) == 0 {
+		return "", errors.New("empty binding")
+	}
+	for _, key := range keys {
+		if !validKey(key) {
+			return "", fmt.Errorf("unknown key %q", key)
+		}
+	}
+	// keys are matched before the prompt sees them, so the first key can't
+	// be one that is typed or used to edit the prompt.
+	if use, ok := reserved[keys[0]]; ok {
+		return "", fmt.Errorf("%q is reserved to %s", keys[0], use)
+	}
+	if isTyped(keys[0]) {
+		return "", fmt.Errorf("%q is typed into the prompt: use ctrl+ or alt+ with it", keys[0])
+	}
+	return strings.Join(keys, " "), nil
+}
+
+// isTyped reports whether key is a printable key pressed without ctrl or
+// alt, which types a character into the prompt.
+func isTyped(key string) bool {
+	return key == "space" || len([]rune(key)) == 1
+}
+
+// validKey reports whether key is the name bubbletea gives a key press.
+// The space bar is called space, since bindings are separated by spaces.
+func validKey(key string) bool {
+	key = strings.TrimPrefix(key, "alt+")
+	if key == "space" {
+		return true
+	}
+	if len([]rune(key)) == 1 {
+		return true
+	}
+	return keyNames[key]
+}
+
+var keyNames = func() map[string]bool {
+	res := map[string]bool{}
+	for k := tea.KeyType(-100); k <= 127; k++ {
+		if name := k.String(); name != "" && k != tea.KeyRunes {
+			res[name] = true
+		}
+	}
+	return res
+}()
+
+// Match looks up the key pressed after the keys of a pending chord. If the
+// keys are bound to an action it is returned. If they are the start of a
+// chord, the keys are returned to be passed back in with the next key.
+func (km Keymap) Match(pending []string, key string) (Action, []string) {
+	if key == " " {
+		key = "space"
+	}
+	keys := append(append([]string{}, pending...), key)
+	binding := strings.Join(keys, " ")
+	if action, ok := km.actions[binding]; ok {
+		return action, nil
+	}
+	for other := range km.actions {
+		if strings.HasPrefix(other, binding+" ") {
+			return "", keys
+		}
+	}
+	return "", nil
+}
+
+// Is reports whether key on its own is bound to the action.
+func (km Keymap) Is(key string, action Action) bool {
+	if key == " " {
+		key = "space"
+	}
+	return km.actions[key] == action
+}
+
+// Keys returns the keys an action is bound to.
+func (km Keymap) Keys(action Action) []string {
+	return km.bindings[action]
+}
+
+// Help returns the first binding of the action as it is shown in help, or
+// the empty string if the action isn't bound.
+func (km Keymap) Help(action Action) string {
+	keys := km.bindings[action]
+	if len(keys) == 0 {
+		return ""
+	}
+	return Display(keys[0])
+}
+
+// Display returns a binding as it is shown in help, e.g. "Ctrl+p" or "F1".
+func Display(binding string) string {
+	keys := strings.Fields(binding)
+	for i, key := range keys {
+		parts := strings.Split(key, "+")
+		for j, part := range parts {
+			if j < len(parts)-1 || len(part) > 1 {
+				parts[j] = strings.ToUpper(part[:1]) + part[1:]
+			}
+		}
+		keys[i] = strings.Join(parts, "+")
+	}
+	return strings.Join(keys, " ")
+}
@@ -0,0 +1,82 @@
+package keymap
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	load := func(t *testing.T, contents string) (Keymap, error) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(Path(dir), []byte(contents), 0o600))
+		return Load(dir)
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		km, err := Load(t.TempDir())
+		require.NoError(t, err)
+		action, pending := km.Match(nil, "f7")
+		require.Equal(t, History, action)
+		require.Empty(t, pending)
+		require.Equal(t, "Ctrl+p", km.Help(PrevConversation))
+	})
+
+	t.Run("overrides and chords", func(t *testing.T) {
+		km, err := load(t, `{"history": ["ctrl+o"], "details": [], "next-conversation": ["ctrl+k n", "Alt+N"]}`)
+		require.NoError(t, err)
+		action, _ := km.Match(nil, "f7")
+		require.Empty(t, action)
+		action, _ = km.Match(nil, "ctrl+o")
+		require.Equal(t, History, action)
+		require.Empty(t, km.Help(Details))
+
+		action, pending := km.Match(nil, "ctrl+k")
+		require.Empty(t, action)
+		require.Equal(t, []string{"ctrl+k"}, pending)
+		action, pending = km.Match(pending, "n")
+		require.Equal(t, NextConversation, action)
+		require.Empty(t, pending)
+		action, pending = km.Match([]string{"ctrl+k"}, "x")
+		require.Empty(t, action)
+		require.Empty(t, pending)
+		action, _ = km.Match(nil, "alt+n")
+		require.Equal(t, NextConversation, action)
+		require.Equal(t, "Ctrl+k n", km.Help(NextConversation))
+
+		km, err = load(t, `{"history": ["ctrl+k space"]}`)
+		require.NoError(t, err)
+		action, _ = km.Match([]string{"ctrl+k"}, " ")
+		require.Equal(t, History, action)
+		require.Equal(t, "Ctrl+k Space", km.Help(History))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, contents := range []string{
+			`{"nope": ["f9"]}`,
+			`{"history": ["f99"]}`,
+			`{"history": ["ctrl+c"]}`,
+			`{"history": ["f1"]}`,
+			`{"history": ["f1 x"]}`,
+			`{"history": [" "]}`,
+			`{"history": ["q"]}`,
+			`{"history": ["/"]}`,
+			`{"history": ["@"]}`,
+			`{"history": ["space"]}`,
+			`{"history": ["g g"]}`,
+			`{"history": ["space x"]}`,
+			`{"history": ["tab"]}`,
+			`{"history": ["backspace"]}`,
+			`{"history": ["left"]}`,
+			`{"history": ["right"]}`,
+			`{"history": ["home"]}`,
+			`{"history": ["end x"]}`,
+			`{"history": ["esc x"]}`,
+			`not json`,
+		} {
+			_, err := load(t, contents)
+			require.Error(t, err, contents)
+		}
+	})
+}
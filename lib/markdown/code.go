@@ -0,0 +1,47 @@
+package markdown
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/text"
+)
+
+// CodeBlock is a fenced code block in a markdown document.
+type CodeBlock struct {
+	Language string
+	Source   string // the code between the fences, as written
+}
+
+// CodeBlocks returns the fenced code blocks in source, in the order they are
+// numbered when rendered with WithCodeNumbers.
+func CodeBlocks(source string) []CodeBlock {
+	bs := []byte(source)
+	node := goldmark.New().Parser().Parse(text.NewReader(bs))
+	r := &Renderer{source: bs}
+	var res []CodeBlock
+	collectCode(r.parse(node), &res)
+	return res
+}
+
+func collectCode(expr Expr, res *[]CodeBlock) {
+	var children []Expr
+	switch expr := expr.(type) {
+	case Document:
+		children = expr.children
+	case List:
+		children = expr.children
+	case ListItem:
+		children = expr.children
+	case TextBlock:
+		if expr.fenced() {
+			*res = append(*res, CodeBlock{
+				Language: expr.language,
+				Source:   strings.Join(expr.lines, ""),
+			})
+		}
+	}
+	for _, child := range children {
+		collectCode(child, res)
+	}
+}
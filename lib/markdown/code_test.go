@@ -0,0 +1,30 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const codeDoc = "Some code:\n\n```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```\n\n- a list\n\n  ```\n  indented\n  ```\n\n    not fenced\n"
+
+func TestCodeBlocks(t *testing.T) {
+	blocks := CodeBlocks(codeDoc)
+	require.Equal(t, []CodeBlock{
+		{Language: "go", Source: "func main() {\n\tfmt.Println(\"hi\")\n}\n"},
+		{Source: "indented\n"},
+	}, blocks)
+	require.Empty(t, CodeBlocks("no code here"))
+}
+
+func TestCodeNumbers(t *testing.T) {
+	bs, err := RenderString(codeDoc, 80, WithCodeNumbers())
+	require.NoError(t, err)
+	require.Contains(t, string(bs), "[1] go\n")
+	require.Contains(t, string(bs), "[2]\n")
+	require.NotContains(t, string(bs), "[3]")
+
+	bs, err = RenderString(codeDoc, 80)
+	require.NoError(t, err)
+	require.NotContains(t, string(bs), "[1]")
+}
@@ -53,6 +53,13 @@ func (t TextBlock) Visit(v Visitor) {
 	v.VisitTextBlock(t)
 }
 
+// fenced is true for code blocks between ``` fences, which are the ones
+// that are numbered.
+func (t TextBlock) fenced() bool {
+	_, ok := t.node.(*ast.FencedCodeBlock)
+	return ok
+}
+
 func (t TextBlock) String() string {
 	return fmt.Sprintf("TextBlock{blanksBefore: %v, code: %v, language: %q, lines: %d}", t.blanksBefore, t.code, t.language, len(t.lines))
 }
@@ -20,28 +20,37 @@ var (
 	backticks = "```"
 )
 
-func RenderBytes(bs []byte, width int) ([]byte, error) {
-	renderer := NewMarkdown(width)
+func RenderBytes(bs []byte, width int, opts ...Option) ([]byte, error) {
+	renderer := NewMarkdown(width, opts...)
 	return renderer.RenderBytes(bs)
 }
 
-func RenderString(s string, width int) ([]byte, error) {
-	bs, err := RenderBytes([]byte(s), width)
+func RenderString(s string, width int, opts ...Option) ([]byte, error) {
+	bs, err := RenderBytes([]byte(s), width, opts...)
 	return bs, err
 }
 
 type Markdown struct {
-	width int
+	width       int
+	theme       Theme
+	codeNumbers bool
 }
 
-func NewMarkdown(width int) *Markdown {
-	return &Markdown{
+func NewMarkdown(width int, opts ...Option) *Markdown {
+	res := &Markdown{
 		width: width,
+		theme: DefaultTheme,
 	}
+	for _, o := range opts {
+		o(res)
+	}
+	return res
 }
 
 func (r *Markdown) RenderBytes(bs []byte) ([]byte, error) {
 	renderer := NewRenderer(r.width)
+	renderer.theme = r.theme
+	renderer.codeNumbers = r.codeNumbers
 	md := goldmark.New(goldmark.WithRenderer(renderer))
 	buf := &bytes.Buffer{}
 	err := md.Convert(bs, buf)
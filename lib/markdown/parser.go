@@ -14,15 +14,18 @@ var _ renderer.Renderer = &Renderer{}
 
 // An experimental renderer that will build its own ast
 type Renderer struct {
-	width  int
-	w      io.Writer
-	source []byte
+	width       int
+	theme       Theme
+	codeNumbers bool
+	w           io.Writer
+	source      []byte
 }
 
 func NewRenderer(width int) *Renderer {
 	width = width - 2
 	return &Renderer{
 		width: width,
+		theme: DefaultTheme,
 	}
 }
 
@@ -37,6 +40,8 @@ func (r *Renderer) Render(w io.Writer, source []byte, node ast.Node) error {
 	r.source = source
 	expr := r.parse(node)
 	visitor := NewPrinter(ww, r.width)
+	visitor.theme = r.theme
+	visitor.codeNumbers = r.codeNumbers
 	expr.Visit(visitor)
 	ww.Close()
 	io.Copy(w, bytes.NewReader(ww.Bytes()))
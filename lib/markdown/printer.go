@@ -17,12 +17,15 @@ var _ Visitor = &Printer{}
 
 type Printer struct {
 	io.Writer
-	width     int
-	debug     bool
-	exprDepth int
-	lists     stack[ListContext]
-	lineStart bool
-	styles    stack[lipgloss.Style]
+	width       int
+	theme       Theme
+	codeNumbers bool // label fenced code blocks with their number
+	codeBlocks  int  // the number of fenced code blocks printed
+	debug       bool
+	exprDepth   int
+	lists       stack[ListContext]
+	lineStart   bool
+	styles      stack[lipgloss.Style]
 }
 
 type ListContext struct {
@@ -34,6 +37,7 @@ func NewPrinter(w io.Writer, width int) *Printer {
 	return &Printer{
 		Writer: w,
 		width:  width,
+		theme:  DefaultTheme,
 		debug:  false,
 	}
 }
@@ -55,7 +59,7 @@ func (p *Printer) VisitDocument(expr Document) {
 // VisitCodeSpan implements Visitor
 func (p *Printer) VisitCodeSpan(expr CodeSpan) {
 	p.debugExpr(expr, expr.node)
-	p.styles.push(lipgloss.NewStyle().Foreground(lipgloss.Color("#f5a2ff")))
+	p.styles.push(lipgloss.NewStyle().Foreground(p.theme.CodeSpan))
 	defer p.styles.pop()
 	p.visitChildren(expr.children)
 }
@@ -116,13 +120,22 @@ func (p *Printer) VisitText(expr Text) {
 func (p *Printer) VisitTextBlock(expr TextBlock) {
 	p.debugExpr(expr, expr.node)
 	p.startBlock(expr.blanksBefore)
-	if expr.code {
+	if expr.fenced() && p.codeNumbers {
+		p.codeBlocks++
+		label := fmt.Sprintf("[%d]", p.codeBlocks)
 		if expr.language != "" {
+			label += " " + expr.language
+		}
+		p.string(lipgloss.NewStyle().Faint(true).Render(label))
+		p.newline()
+	}
+	if expr.code {
+		if expr.language != "" && p.theme.Chroma != "" {
 			lexer := lexers.Get(expr.language)
 			if lexer == nil {
 				lexer = lexers.Fallback
 			}
-			style := styles.Get("monokai")
+			style := styles.Get(p.theme.Chroma)
 			if style == nil {
 				style = styles.Fallback
 			}
@@ -147,7 +160,7 @@ func (p *Printer) VisitTextBlock(expr TextBlock) {
 			p.string(str)
 			return
 		}
-		p.styles.push(lipgloss.NewStyle().Foreground(lipgloss.Color("#ddff00")))
+		p.styles.push(lipgloss.NewStyle().Foreground(p.theme.Code))
 		defer p.styles.pop()
 		for i, line := range expr.lines {
 			p.string(strings.TrimRight(line, "\n"))
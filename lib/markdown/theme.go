@@ -0,0 +1,34 @@
+package markdown
+
+import "github.com/charmbracelet/lipgloss"
+
+// Theme is the colors markdown is rendered with.
+type Theme struct {
+	CodeSpan lipgloss.TerminalColor // inline code
+	Code     lipgloss.TerminalColor // code blocks without a language
+	Chroma   string                 // chroma style for code blocks with a language, or none if empty
+}
+
+// DefaultTheme suits terminals with a dark background.
+var DefaultTheme = Theme{
+	CodeSpan: lipgloss.Color("#f5a2ff"),
+	Code:     lipgloss.Color("#ddff00"),
+	Chroma:   "monokai",
+}
+
+type Option func(*Markdown)
+
+// WithTheme renders with the specified theme instead of the default one.
+func WithTheme(theme Theme) Option {
+	return func(m *Markdown) {
+		m.theme = theme
+	}
+}
+
+// WithCodeNumbers labels each fenced code block with its number, counting
+// from 1, and its language, so that it can be referred to.
+func WithCodeNumbers() Option {
+	return func(m *Markdown) {
+		m.codeNumbers = true
+	}
+}
@@ -0,0 +1,93 @@
+// Package persona loads the personas that set the tone of a conversation.
+// A persona is the preamble sent ahead of every request. The built-in ones
+// are embedded, and more can be added as markdown files in the personas
+// directory of a store.
+package persona
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	Default = "default"
+	DirName = "personas"
+	suffix  = ".md"
+)
+
+var (
+	ErrNotFound = errors.New("persona not found")
+
+	nameRE = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+//go:embed builtin/*
+var builtin embed.FS
+
+// Dir returns the directory that personas are kept in for the store
+// directory storeDir.
+func Dir(storeDir string) string {
+	return filepath.Join(storeDir, DirName)
+}
+
+// Load returns the preamble of the named persona. A persona in the personas
+// directory of storeDir takes precedence over a built-in one of the same
+// name. storeDir may be empty, in which case only built-in personas are
+// found.
+func Load(storeDir string, name string) (string, error) {
+	if !nameRE.MatchString(name) {
+		return "", fmt.Errorf("invalid persona name: %q", name)
+	}
+	if storeDir != "" {
+		bs, err := os.ReadFile(filepath.Join(Dir(storeDir), name+suffix))
+		switch {
+		case err == nil:
+			return strings.TrimSpace(string(bs)), nil
+		case !errors.Is(err, fs.ErrNotExist):
+			return "", err
+		}
+	}
+	bs, err := builtin.ReadFile("builtin/" + name + suffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(bs)), nil
+}
+
+// List returns the names of the built-in personas and those in the personas
+// directory of storeDir, sorted by name.
+func List(storeDir string) ([]string, error) {
+	seen := map[string]bool{}
+	entries, err := builtin.ReadDir("builtin")
+	if err != nil {
+		return nil, err
+	}
+	if storeDir != "" {
+		custom, err := os.ReadDir(Dir(storeDir))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		entries = append(entries, custom...)
+	}
+	var res []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), suffix)
+		if e.IsDir() || !ok || !nameRE.MatchString(name) || seen[name] {
+			continue
+		}
+		seen[name] = true
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res, nil
+}
@@ -0,0 +1,118 @@
+// Package pricing estimates what requests cost from a table of per-model
+// token prices.
+package pricing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Price is what a model charges in USD per million tokens.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// Cost returns the cost in USD of the given token counts.
+func (p Price) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6
+}
+
+// String formats the price the way ParsePrice reads it.
+func (p Price) String() string {
+	return fmt.Sprintf("%g,%g", p.Input, p.Output)
+}
+
+// ParsePrice reads an "input,output" pair of USD per million token prices.
+func ParsePrice(s string) (Price, error) {
+	in, out, ok := strings.Cut(s, ",")
+	if !ok {
+		return Price{}, fmt.Errorf("invalid price %q: expected input,output per million tokens", s)
+	}
+	var (
+		res Price
+		err error
+	)
+	res.Input, err = strconv.ParseFloat(strings.TrimSpace(in), 64)
+	if err != nil {
+		return Price{}, fmt.Errorf("invalid input price %q", in)
+	}
+	res.Output, err = strconv.ParseFloat(strings.TrimSpace(out), 64)
+	if err != nil {
+		return Price{}, fmt.Errorf("invalid output price %q", out)
+	}
+	return res, nil
+}
+
+// Table maps model names, or prefixes of them, to prices.
+type Table map[string]Price
+
+// Default holds list prices at the time of writing. Dated model versions
+// such as gpt-4o-2024-08-06 are matched by prefix.
+var Default = Table{
+	"gpt-3.5-turbo":      {Input: 0.50, Output: 1.50},
+	"gpt-4":              {Input: 30, Output: 60},
+	"gpt-4-32k":          {Input: 60, Output: 120},
+	"gpt-4-turbo":        {Input: 10, Output: 30},
+	"gpt-4-1106-preview": {Input: 10, Output: 30},
+	"gpt-4-0125-preview": {Input: 10, Output: 30},
+	"gpt-4o":             {Input: 2.50, Output: 10},
+	"gpt-4o-mini":        {Input: 0.15, Output: 0.60},
+	"gpt-4.1":            {Input: 2, Output: 8},
+	"gpt-4.1-mini":       {Input: 0.40, Output: 1.60},
+	"gpt-4.1-nano":       {Input: 0.10, Output: 0.40},
+	"o1":                 {Input: 15, Output: 60},
+	"o1-mini":            {Input: 1.10, Output: 4.40},
+	"o3-mini":            {Input: 1.10, Output: 4.40},
+}
+
+// With returns a copy of the table with the overrides applied.
+func (t Table) With(overrides Table) Table {
+	res := make(Table, len(t)+len(overrides))
+	for k, v := range t {
+		res[k] = v
+	}
+	for k, v := range overrides {
+		res[k] = v
+	}
+	return res
+}
+
+// Lookup returns the price of a model along with the table entry that
+// matched it. The longest matching prefix wins, so gpt-4o-mini-2024-07-18
+// is priced as gpt-4o-mini rather than gpt-4o or gpt-4.
+func (t Table) Lookup(model string) (name string, price Price, ok bool) {
+	for k, v := range t {
+		if !matches(model, k) || len(k) < len(name) {
+			continue
+		}
+		name, price, ok = k, v, true
+	}
+	return
+}
+
+// Canonical returns the table entry a model is priced as, or the model
+// itself if it is not in the table.
+func (t Table) Canonical(model string) string {
+	if name, _, ok := t.Lookup(model); ok {
+		return name
+	}
+	return model
+}
+
+// matches reports whether model is the entry itself or a version of it.
+func matches(model string, entry string) bool {
+	if model == entry {
+		return true
+	}
+	return strings.HasPrefix(model, entry+"-")
+}
+
+// EstimateTokens roughly estimates the number of tokens in text, at about
+// four characters per token. It is only meant for guarding budgets before a
+// request is sent; the usage reported by the API is what gets recorded.
+func EstimateTokens(text string) int {
+	const perMessage = 4 // role and framing overhead
+	return perMessage + (len(text)+3)/4
+}
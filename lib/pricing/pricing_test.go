@@ -0,0 +1,87 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	for _, tc := range []struct {
+		model string
+		name  string
+		price Price
+	}{
+		{model: "gpt-4o", name: "gpt-4o", price: Price{Input: 2.50, Output: 10}},
+		{model: "gpt-4o-2024-08-06", name: "gpt-4o", price: Price{Input: 2.50, Output: 10}},
+		{model: "gpt-4o-mini", name: "gpt-4o-mini", price: Price{Input: 0.15, Output: 0.60}},
+		{model: "gpt-4o-mini-2024-07-18", name: "gpt-4o-mini", price: Price{Input: 0.15, Output: 0.60}},
+		{model: "gpt-4", name: "gpt-4", price: Price{Input: 30, Output: 60}},
+		{model: "gpt-4-0613", name: "gpt-4", price: Price{Input: 30, Output: 60}},
+		{model: "gpt-4-turbo-2024-04-09", name: "gpt-4-turbo", price: Price{Input: 10, Output: 30}},
+		{model: "gpt-4.1-nano", name: "gpt-4.1-nano", price: Price{Input: 0.10, Output: 0.40}},
+		{model: "o1-mini-2024-09-12", name: "o1-mini", price: Price{Input: 1.10, Output: 4.40}},
+		// prefixes only match whole parts of the name
+		{model: "gpt-4omni"},
+		{model: "o3"},
+		{model: "my-model"},
+		{model: ""},
+	} {
+		t.Run(tc.model, func(t *testing.T) {
+			name, price, ok := Default.Lookup(tc.model)
+			require.Equal(t, tc.name != "", ok)
+			require.Equal(t, tc.name, name)
+			require.Equal(t, tc.price, price)
+			if ok {
+				require.Equal(t, tc.name, Default.Canonical(tc.model))
+			} else {
+				require.Equal(t, tc.model, Default.Canonical(tc.model))
+			}
+		})
+	}
+
+	// overrides replace entries and add new ones without changing the table
+	// they were applied to
+	table := Default.With(Table{"gpt-4o": {Input: 1, Output: 2}, "my-model": {Input: 3, Output: 4}})
+	_, price, _ := table.Lookup("gpt-4o-2024-08-06")
+	require.Equal(t, Price{Input: 1, Output: 2}, price)
+	_, price, _ = table.Lookup("gpt-4o-mini")
+	require.Equal(t, Price{Input: 0.15, Output: 0.60}, price)
+	_, price, _ = table.Lookup("my-model")
+	require.Equal(t, Price{Input: 3, Output: 4}, price)
+	_, price, _ = Default.Lookup("gpt-4o")
+	require.Equal(t, Price{Input: 2.50, Output: 10}, price)
+	_, _, ok := Default.Lookup("my-model")
+	require.False(t, ok)
+}
+
+func TestCost(t *testing.T) {
+	for _, tc := range []struct {
+		price      Price
+		prompt     int
+		completion int
+		cost       float64
+	}{
+		{price: Price{Input: 2.50, Output: 10}, prompt: 1_000_000, completion: 0, cost: 2.50},
+		{price: Price{Input: 2.50, Output: 10}, prompt: 0, completion: 1_000_000, cost: 10},
+		{price: Price{Input: 2.50, Output: 10}, prompt: 1000, completion: 500, cost: 0.0075},
+		{price: Price{Input: 0.15, Output: 0.60}, prompt: 200, completion: 100, cost: 0.00009},
+		{price: Price{}, prompt: 1000, completion: 1000, cost: 0},
+	} {
+		require.InDelta(t, tc.cost, tc.price.Cost(tc.prompt, tc.completion), 1e-12, "%v %d %d", tc.price, tc.prompt, tc.completion)
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	price, err := ParsePrice(" 2.5, 10 ")
+	require.NoError(t, err)
+	require.Equal(t, Price{Input: 2.5, Output: 10}, price)
+	again, err := ParsePrice(price.String())
+	require.NoError(t, err)
+	require.Equal(t, price, again)
+
+	for _, s := range []string{"", "2.5", "a,10", "2.5,b"} {
+		_, err := ParsePrice(s)
+		require.Error(t, err, s)
+	}
+}
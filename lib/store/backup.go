@@ -0,0 +1,287 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	BackupDirName = "backups"
+
+	backupPrefix     = "gpterm-"
+	backupSuffix     = ".db"
+	backupTimeFormat = "20060102-150405"
+)
+
+// Backup describes a backup file in the backups directory.
+type Backup struct {
+	Path    string
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// BackupDir returns the directory timestamped backups are written to.
+func (s *Store) BackupDir() string {
+	return filepath.Join(s.dir, BackupDirName)
+}
+
+// Backup copies the database to path using SQLite's online backup API, so it
+// is safe to do while the database is in use. If path is empty a timestamped
+// file is created in the backups directory. The path of the backup is
+// returned.
+func (s *Store) Backup(ctx context.Context, path string) (string, error) {
+	if path == "" {
+		var err error
+		path, err = s.newBackupPath("")
+		if err != nil {
+			return "", err
+		}
+	}
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("%s already exists", path)
+	}
+	err := backupFile(ctx, s.DBPath(), path)
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// ListBackups returns the backups in the backups directory, newest first.
+func (s *Store) ListBackups() ([]Backup, error) {
+	entries, err := os.ReadDir(s.BackupDir())
+	switch {
+	case os.IsNotExist(err):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	var res []Backup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, Backup{
+			Path:    filepath.Join(s.BackupDir(), name),
+			Name:    name,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if !res[i].ModTime.Equal(res[j].ModTime) {
+			return res[i].ModTime.After(res[j].ModTime)
+		}
+		return res[i].Name > res[j].Name
+	})
+	return res, nil
+}
+
+// Restore replaces the contents of the database with the backup at path. The
+// backup is checked for integrity first, and the current database is itself
+// backed up before it is overwritten. Backups taken by older versions of
+// gpterm are migrated after they are restored. The path of the backup of the
+// replaced database is returned.
+func (s *Store) Restore(ctx context.Context, path string) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	src, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	problems, err := integrityCheck(ctx, src)
+	if err != nil {
+		return "", fmt.Errorf("check %s: %w", path, err)
+	}
+	if len(problems) > 0 {
+		return "", fmt.Errorf("%s failed its integrity check: %s", path, problems[0])
+	}
+	saved, err := s.newBackupPath("pre-restore")
+	if err != nil {
+		return "", err
+	}
+	saved, err = s.Backup(ctx, saved)
+	if err != nil {
+		return "", fmt.Errorf("backup current db: %w", err)
+	}
+	err = copyDB(ctx, s.db, src)
+	if err != nil {
+		return saved, fmt.Errorf("restore: %w", err)
+	}
+	err = s.migrate()
+	if err != nil {
+		return saved, fmt.Errorf("migrate: %w", err)
+	}
+	return saved, nil
+}
+
+// Vacuum rebuilds the database file to reclaim unused space. It returns the
+// size of the file before and after.
+func (s *Store) Vacuum(ctx context.Context) (before int64, after int64, err error) {
+	before, err = fileSize(s.DBPath())
+	if err != nil {
+		return 0, 0, err
+	}
+	_, err = s.db.ExecContext(ctx, "VACUUM")
+	if err != nil {
+		return 0, 0, err
+	}
+	after, err = fileSize(s.DBPath())
+	return before, after, err
+}
+
+// Check runs SQLite's integrity and foreign key checks, returning a
+// description of every problem found. No problems means the database is
+// healthy.
+func (s *Store) Check(ctx context.Context) ([]string, error) {
+	problems, err := integrityCheck(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			table  string
+			rowid  sql.NullInt64
+			parent string
+			fkid   int64
+		)
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return nil, err
+		}
+		problems = append(problems, fmt.Sprintf("%s row %d references a missing %s", table, rowid.Int64, parent))
+	}
+	return problems, rows.Err()
+}
+
+// newBackupPath returns a timestamped path in the backups directory, creating
+// the directory if needed. The reason, if any, is included in the name, and a
+// number is added if a backup with the same name was made this second.
+func (s *Store) newBackupPath(reason string) (string, error) {
+	if err := ensureDir(s.BackupDir()); err != nil {
+		return "", err
+	}
+	name := backupPrefix + time.Now().Format(backupTimeFormat)
+	if reason != "" {
+		name += "-" + reason
+	}
+	path := filepath.Join(s.BackupDir(), name+backupSuffix)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path, nil
+		}
+		path = filepath.Join(s.BackupDir(), fmt.Sprintf("%s-%d%s", name, i, backupSuffix))
+	}
+}
+
+func integrityCheck(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var problems []string
+	for rows.Next() {
+		var res string
+		if err := rows.Scan(&res); err != nil {
+			return nil, err
+		}
+		if res != "ok" {
+			problems = append(problems, res)
+		}
+	}
+	return problems, rows.Err()
+}
+
+// backupFile copies the database at srcPath to a new database at dstPath.
+func backupFile(ctx context.Context, srcPath string, dstPath string) error {
+	src, err := sql.Open("sqlite3", srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := sql.Open("sqlite3", dstPath)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	return copyDB(ctx, dst, src)
+}
+
+// copyDB overwrites the main database of dst with the main database of src.
+func copyDB(ctx context.Context, dst *sql.DB, src *sql.DB) error {
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+	return dstConn.Raw(func(dc any) error {
+		return srcConn.Raw(func(sc any) error {
+			d, ok := dc.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.New("destination is not a sqlite connection")
+			}
+			s, ok := sc.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.New("source is not a sqlite connection")
+			}
+			b, err := d.Backup("main", s, "main")
+			if err != nil {
+				return err
+			}
+			for {
+				done, err := b.Step(-1)
+				if err != nil {
+					b.Close()
+					return err
+				}
+				if done {
+					break
+				}
+				// the source is busy or locked, try again shortly.
+				select {
+				case <-ctx.Done():
+					b.Close()
+					return ctx.Err()
+				case <-time.After(50 * time.Millisecond):
+				}
+			}
+			return b.Close()
+		})
+	})
+}
+
+func fileSize(path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
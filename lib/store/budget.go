@@ -0,0 +1,316 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/collinvandyck/gpterm/lib/pricing"
+)
+
+const (
+	ConfigBudgetMonthly     = "budget.monthly"
+	ConfigBudgetModelPrefix = "budget.model."
+	ConfigBudgetThresholds  = "budget.thresholds"
+	ConfigBudgetHardCap     = "budget.hard-cap"
+	ConfigPricePrefix       = "price."
+
+	defaultBudgetThresholds = "80"
+)
+
+// HardCap decides what happens to a request that would go over a budget.
+type HardCap string
+
+const (
+	// HardCapConfirm asks for the request to be sent again before going
+	// over the budget.
+	HardCapConfirm HardCap = "confirm"
+	// HardCapRefuse never sends a request that would go over the budget.
+	HardCapRefuse HardCap = "refuse"
+)
+
+func ParseHardCap(s string) (HardCap, error) {
+	switch HardCap(s) {
+	case HardCapConfirm, HardCapRefuse:
+		return HardCap(s), nil
+	}
+	return "", fmt.Errorf("invalid hard cap %q: use %s or %s", s, HardCapConfirm, HardCapRefuse)
+}
+
+// Budget is the monthly spend allowed by the current profile. Each profile
+// has its own store, so the profile budget is simply the store's. Limits are
+// in USD and a zero limit means there is no budget.
+type Budget struct {
+	// Monthly limits the spend across all models.
+	Monthly float64
+	// Models limits the spend on a single model, keyed by the name it has
+	// in the price table.
+	Models map[string]float64
+	// Thresholds are the percentages of a budget at which a warning is
+	// shown, in increasing order.
+	Thresholds []int
+	HardCap    HardCap
+}
+
+// Enabled reports whether any limit is set.
+func (b Budget) Enabled() bool {
+	return b.Monthly > 0 || len(b.Models) > 0
+}
+
+// Spend is what was spent since the start of a month, in USD.
+type Spend struct {
+	Since  time.Time
+	Total  float64
+	Models map[string]float64
+	// Unpriced counts the tokens used by models that are not in the price
+	// table, which are not included in the totals.
+	Unpriced int64
+}
+
+// BudgetStatus describes the budget closest to, or furthest over, its limit.
+type BudgetStatus struct {
+	// Model is the model the budget applies to, or empty for the profile
+	// budget.
+	Model   string
+	Spent   float64
+	Limit   float64
+	Percent float64
+	// Threshold is the highest warning threshold that has been reached, or
+	// zero if none has.
+	Threshold int
+	// Exceeded is set when the spend is over the limit.
+	Exceeded bool
+	HardCap  HardCap
+}
+
+// Warning reports whether the status should be shown to the user.
+func (s BudgetStatus) Warning() bool {
+	return s.Threshold > 0 || s.Exceeded
+}
+
+func (s BudgetStatus) String() string {
+	scope := "Monthly budget"
+	if s.Model != "" {
+		scope = s.Model + " budget"
+	}
+	return fmt.Sprintf("%s: $%.2f of $%.2f (%.0f%%)", scope, s.Spent, s.Limit, s.Percent)
+}
+
+// Budget returns the configured budget. By default there are no limits.
+func (s *Store) Budget(ctx context.Context) (Budget, error) {
+	return getBudget(ctx, s)
+}
+
+func getBudget(ctx context.Context, r Repository) (Budget, error) {
+	cfg, err := r.GetConfig(ctx)
+	if err != nil {
+		return Budget{}, err
+	}
+	res := Budget{Models: map[string]float64{}, HardCap: HardCapConfirm}
+	thresholds := defaultBudgetThresholds
+	for _, c := range cfg {
+		switch {
+		case c.Name == ConfigBudgetMonthly:
+			res.Monthly, err = strconv.ParseFloat(c.Value, 64)
+		case strings.HasPrefix(c.Name, ConfigBudgetModelPrefix):
+			model := strings.TrimPrefix(c.Name, ConfigBudgetModelPrefix)
+			res.Models[model], err = strconv.ParseFloat(c.Value, 64)
+		case c.Name == ConfigBudgetThresholds:
+			thresholds = c.Value
+		case c.Name == ConfigBudgetHardCap:
+			res.HardCap, err = ParseHardCap(c.Value)
+		}
+		if err != nil {
+			return Budget{}, fmt.Errorf("config %s: %w", c.Name, err)
+		}
+	}
+	res.Thresholds, err = ParseBudgetThresholds(thresholds)
+	if err != nil {
+		return Budget{}, fmt.Errorf("config %s: %w", ConfigBudgetThresholds, err)
+	}
+	return res, nil
+}
+
+// SetBudget sets the monthly limit for a model, or for the profile if model
+// is empty. A zero limit removes the budget.
+func (s *Store) SetBudget(ctx context.Context, model string, limit float64) error {
+	if limit < 0 {
+		return errors.New("budget must not be negative")
+	}
+	key := ConfigBudgetMonthly
+	if model != "" {
+		prices, err := s.PriceTable(ctx)
+		if err != nil {
+			return err
+		}
+		key = ConfigBudgetModelPrefix + prices.Canonical(model)
+	}
+	if limit == 0 {
+		return s.DeleteConfig(ctx, key)
+	}
+	return s.SetConfigString(ctx, key, strconv.FormatFloat(limit, 'f', -1, 64))
+}
+
+// SetBudgetThresholds sets the percentages at which budget warnings are
+// shown.
+func (s *Store) SetBudgetThresholds(ctx context.Context, thresholds []int) error {
+	vals := make([]string, len(thresholds))
+	for i, t := range thresholds {
+		vals[i] = strconv.Itoa(t)
+	}
+	val := strings.Join(vals, ",")
+	if _, err := ParseBudgetThresholds(val); err != nil {
+		return err
+	}
+	return s.SetConfigString(ctx, ConfigBudgetThresholds, val)
+}
+
+// SetBudgetHardCap sets what happens to requests that would go over budget.
+func (s *Store) SetBudgetHardCap(ctx context.Context, hc HardCap) error {
+	if _, err := ParseHardCap(string(hc)); err != nil {
+		return err
+	}
+	return s.SetConfigString(ctx, ConfigBudgetHardCap, string(hc))
+}
+
+// ParseBudgetThresholds reads a comma separated list of percentages. An
+// empty list disables warnings.
+func ParseBudgetThresholds(s string) ([]int, error) {
+	var res []int
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(f), "%"))
+		if f == "" {
+			continue
+		}
+		t, err := strconv.Atoi(f)
+		if err != nil || t <= 0 || t > 100 {
+			return nil, fmt.Errorf("invalid threshold %q: use a percentage between 1 and 100", f)
+		}
+		res = append(res, t)
+	}
+	sort.Ints(res)
+	return res, nil
+}
+
+// PriceTable returns the default price table with any prices set in the
+// config, as "price.<model>" = "<input>,<output>" in USD per million tokens.
+func (s *Store) PriceTable(ctx context.Context) (pricing.Table, error) {
+	return priceTable(ctx, s)
+}
+
+func priceTable(ctx context.Context, r Repository) (pricing.Table, error) {
+	cfg, err := r.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	overrides := pricing.Table{}
+	for _, c := range cfg {
+		if !strings.HasPrefix(c.Name, ConfigPricePrefix) {
+			continue
+		}
+		price, err := pricing.ParsePrice(c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("config %s: %w", c.Name, err)
+		}
+		overrides[strings.TrimPrefix(c.Name, ConfigPricePrefix)] = price
+	}
+	return pricing.Default.With(overrides), nil
+}
+
+// MonthSpend prices the usage recorded since the start of the month that
+// now falls in.
+func (s *Store) MonthSpend(ctx context.Context, now time.Time) (Spend, error) {
+	return monthSpend(ctx, s, now)
+}
+
+func monthSpend(ctx context.Context, r Repository, now time.Time) (Spend, error) {
+	prices, err := priceTable(ctx, r)
+	if err != nil {
+		return Spend{}, err
+	}
+	since := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	usage, err := r.GetUsageSince(ctx, since)
+	if err != nil {
+		return Spend{}, err
+	}
+	res := Spend{Since: since, Models: map[string]float64{}}
+	for _, u := range usage {
+		name, price, ok := prices.Lookup(u.Model.String)
+		if !ok {
+			res.Unpriced += u.TotalTokens
+			continue
+		}
+		cost := price.Cost(int(u.PromptTokens), int(u.CompletionTokens))
+		res.Models[name] += cost
+		res.Total += cost
+	}
+	return res, nil
+}
+
+// CheckBudget reports on the budgets that apply to a request for model whose
+// prompt is estimated at promptTokens. The profile budget and the model's
+// budget are both checked, and the one in the worse state is returned.
+// Passing zero tokens reports on the spend so far.
+func (s *Store) CheckBudget(ctx context.Context, model string, promptTokens int) (BudgetStatus, error) {
+	return checkBudget(ctx, s, model, promptTokens)
+}
+
+func checkBudget(ctx context.Context, r Repository, model string, promptTokens int) (BudgetStatus, error) {
+	budget, err := getBudget(ctx, r)
+	if err != nil || !budget.Enabled() {
+		return BudgetStatus{}, err
+	}
+	prices, err := priceTable(ctx, r)
+	if err != nil {
+		return BudgetStatus{}, err
+	}
+	spend, err := monthSpend(ctx, r, time.Now())
+	if err != nil {
+		return BudgetStatus{}, err
+	}
+	name, price, _ := prices.Lookup(model)
+	estimate := price.Cost(promptTokens, 0)
+	var res BudgetStatus
+	check := func(model string, spent, limit float64) {
+		if limit <= 0 {
+			return
+		}
+		st := budget.status(spent+estimate, limit)
+		st.Model = model
+		if res.Limit == 0 || st.worse(res) {
+			res = st
+		}
+	}
+	check("", spend.Total, budget.Monthly)
+	if name != "" {
+		check(name, spend.Models[name], budget.Models[name])
+	}
+	return res, nil
+}
+
+func (b Budget) status(spent, limit float64) BudgetStatus {
+	res := BudgetStatus{
+		Spent:    spent,
+		Limit:    limit,
+		Percent:  spent / limit * 100,
+		Exceeded: spent > limit,
+		HardCap:  b.HardCap,
+	}
+	for _, t := range b.Thresholds {
+		if res.Percent >= float64(t) {
+			res.Threshold = t
+		}
+	}
+	return res
+}
+
+func (s BudgetStatus) worse(o BudgetStatus) bool {
+	if s.Exceeded != o.Exceeded {
+		return s.Exceeded
+	}
+	return s.Percent > o.Percent
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetStatus(t *testing.T) {
+	budget := Budget{Thresholds: []int{50, 80}, HardCap: HardCapRefuse}
+	for _, tc := range []struct {
+		name      string
+		budget    Budget
+		spent     float64
+		percent   float64
+		threshold int
+		exceeded  bool
+	}{
+		{name: "under", budget: budget, spent: 0.40, percent: 40},
+		{name: "first threshold", budget: budget, spent: 0.50, percent: 50, threshold: 50},
+		{name: "highest threshold", budget: budget, spent: 0.85, percent: 85, threshold: 80},
+		{name: "at the limit", budget: budget, spent: 1, percent: 100, threshold: 80},
+		{name: "over", budget: budget, spent: 1.20, percent: 120, threshold: 80, exceeded: true},
+		{name: "no thresholds", budget: Budget{}, spent: 0.90, percent: 90},
+		{name: "no thresholds over", budget: Budget{}, spent: 1.50, percent: 150, exceeded: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			st := tc.budget.status(tc.spent, 1)
+			require.InDelta(t, tc.percent, st.Percent, 1e-9)
+			require.Equal(t, tc.threshold, st.Threshold)
+			require.Equal(t, tc.exceeded, st.Exceeded)
+			require.Equal(t, tc.threshold > 0 || tc.exceeded, st.Warning())
+			require.Equal(t, tc.budget.HardCap, st.HardCap)
+		})
+	}
+}
+
+func TestCheckBudget(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemory()
+	// $0.25 on gpt-4o and $0.15 on gpt-4o-mini
+	require.NoError(t, r.SaveStreamResults(ctx, "response", MessageMeta{Model: "gpt-4o-2024-08-06", PromptTokens: 100_000}))
+	require.NoError(t, r.SaveStreamResults(ctx, "response", MessageMeta{Model: "gpt-4o-mini", PromptTokens: 1_000_000}))
+	require.NoError(t, r.SetConfigString(ctx, ConfigBudgetMonthly, "1"))
+	require.NoError(t, r.SetConfigString(ctx, ConfigBudgetModelPrefix+"gpt-4o", "0.30"))
+	require.NoError(t, r.SetConfigString(ctx, ConfigBudgetThresholds, "90%, 50"))
+
+	for _, tc := range []struct {
+		name      string
+		model     string
+		tokens    int
+		scope     string
+		spent     float64
+		threshold int
+		exceeded  bool
+	}{
+		// the model budget is closer to its limit than the profile's
+		{name: "model budget", model: "gpt-4o-2024-08-06", scope: "gpt-4o", spent: 0.25, threshold: 50},
+		// the estimated prompt counts towards the spend
+		{name: "prompt goes over", model: "gpt-4o", tokens: 40_000, scope: "gpt-4o", spent: 0.35, threshold: 90, exceeded: true},
+		// gpt-4o-mini has no budget of its own, and isn't held to gpt-4o's
+		{name: "profile budget", model: "gpt-4o-mini", scope: "", spent: 0.40},
+		{name: "profile threshold", model: "gpt-4o-mini", tokens: 4_000_000, scope: "", spent: 1.00, threshold: 90},
+		{name: "profile over", model: "gpt-4o-mini", tokens: 4_100_000, scope: "", spent: 1.015, threshold: 90, exceeded: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			st, err := r.CheckBudget(ctx, tc.model, tc.tokens)
+			require.NoError(t, err)
+			require.Equal(t, tc.scope, st.Model)
+			require.InDelta(t, tc.spent, st.Spent, 1e-9)
+			require.Equal(t, tc.threshold, st.Threshold)
+			require.Equal(t, tc.exceeded, st.Exceeded)
+			require.Equal(t, HardCapConfirm, st.HardCap)
+		})
+	}
+
+	require.NoError(t, r.SetConfigString(ctx, ConfigBudgetHardCap, string(HardCapRefuse)))
+	st, err := r.CheckBudget(ctx, "gpt-4o", 40_000)
+	require.NoError(t, err)
+	require.True(t, st.Exceeded)
+	require.Equal(t, HardCapRefuse, st.HardCap)
+
+	require.NoError(t, r.SetConfigString(ctx, ConfigBudgetHardCap, "sometimes"))
+	_, err = r.CheckBudget(ctx, "gpt-4o", 0)
+	require.ErrorContains(t, err, "invalid hard cap")
+	require.NoError(t, r.DeleteConfig(ctx, ConfigBudgetHardCap))
+	require.NoError(t, r.SetConfigString(ctx, ConfigBudgetThresholds, "120"))
+	_, err = r.CheckBudget(ctx, "gpt-4o", 0)
+	require.ErrorContains(t, err, "between 1 and 100")
+}
@@ -0,0 +1,239 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/errs"
+)
+
+const (
+	ConfigTrashRetentionDays  = "trash.retention-days"
+	DefaultTrashRetentionDays = 30
+)
+
+type ConversationSummary = query.GetConversationSummariesRow
+
+// ListConversations returns every conversation, including archived and
+// trashed ones, along with the number of messages in each.
+func (s *Store) ListConversations(ctx context.Context) ([]ConversationSummary, error) {
+	return s.queries.GetConversationSummaries(ctx)
+}
+
+// ActiveConversation returns the conversation selected by this process.
+func (s *Store) ActiveConversation(ctx context.Context) (query.Conversation, error) {
+	return s.current(ctx, s.queries)
+}
+
+// GetConversation returns the specified conversation.
+func (s *Store) GetConversation(ctx context.Context, id int64) (query.Conversation, error) {
+	return s.getConversation(ctx, id)
+}
+
+// SearchMessages returns the messages containing text, ignoring case, from
+// conversations that are not in the trash. If tag is not empty, only
+// conversations with that tag are searched.
+func (s *Store) SearchMessages(ctx context.Context, text string, tag string) ([]query.Message, error) {
+	msgs, err := s.queries.SearchMessages(ctx, "%"+text+"%")
+	if err != nil {
+		return nil, err
+	}
+	var ids map[int64]bool
+	if tag != "" {
+		tagged, err := s.GetConversationIDsForTag(ctx, tag)
+		if err != nil {
+			return nil, err
+		}
+		ids = map[int64]bool{}
+		for _, id := range tagged {
+			ids[id] = true
+		}
+	}
+	// like treats % and _ as wildcards, so matches are checked again here.
+	needle := strings.ToLower(text)
+	var res []query.Message
+	for _, m := range msgs {
+		if ids != nil && !ids[m.ConversationID] {
+			continue
+		}
+		if strings.Contains(strings.ToLower(m.Content), needle) {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+// SetConversationProtected marks a conversation as protected. Protected
+// conversations cannot be dropped.
+func (s *Store) SetConversationProtected(ctx context.Context, id int64, protected bool) error {
+	if _, err := s.getConversation(ctx, id); err != nil {
+		return err
+	}
+	return s.queries.SetConversationProtected(ctx, query.SetConversationProtectedParams{
+		Protected: boolInt(protected),
+		ID:        id,
+	})
+}
+
+// SetConversationArchived archives or unarchives a conversation. Archived
+// conversations are skipped when navigating between conversations.
+func (s *Store) SetConversationArchived(ctx context.Context, id int64, archived bool) error {
+	if _, err := s.getConversation(ctx, id); err != nil {
+		return err
+	}
+	return s.queries.SetConversationArchived(ctx, query.SetConversationArchivedParams{
+		Archived: boolInt(archived),
+		ID:       id,
+	})
+}
+
+// SetConversationName names a conversation. An empty name removes it.
+func (s *Store) SetConversationName(ctx context.Context, id int64, name string) error {
+	if _, err := s.getConversation(ctx, id); err != nil {
+		return err
+	}
+	return s.queries.SetConversationName(ctx, query.SetConversationNameParams{
+		Name: nullString(name),
+		ID:   id,
+	})
+}
+
+// NewConversation creates a conversation with the default settings and
+// selects it. If the current conversation has no messages yet it is kept
+// instead.
+func (s *Store) NewConversation(ctx context.Context) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := s.queries.WithTx(tx)
+	current, err := s.current(ctx, q)
+	if err != nil {
+		return err
+	}
+	count, err := q.CountMessagesForConversation(ctx, current.ID)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	c, err := createConversation(ctx, q)
+	if err != nil {
+		return err
+	}
+	err = selectConversation(ctx, q, c.ID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.setConversation(c.ID)
+	return nil
+}
+
+// GetTrash returns the conversations that have been dropped but not yet
+// purged, oldest first.
+func (s *Store) GetTrash(ctx context.Context) ([]query.Conversation, error) {
+	return s.queries.GetTrashedConversations(ctx)
+}
+
+// RestoreConversation takes a dropped conversation out of the trash.
+func (s *Store) RestoreConversation(ctx context.Context, id int64) error {
+	convo, err := s.getConversation(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !convo.DeletedAt.Valid {
+		return fmt.Errorf("conversation %d is not in the trash", id)
+	}
+	return s.queries.RestoreConversation(ctx, id)
+}
+
+// PurgeTrash permanently deletes conversations that were dropped more than
+// age ago, along with their messages. It returns the number of conversations
+// that were deleted.
+func (s *Store) PurgeTrash(ctx context.Context, age time.Duration) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	q := s.queries.WithTx(tx)
+	trash, err := q.GetTrashedConversations(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-age)
+	var purged int
+	for _, convo := range trash {
+		if convo.DeletedAt.Time.After(cutoff) {
+			continue
+		}
+		err = purgeConversation(ctx, q, convo.ID)
+		if err != nil {
+			return 0, err
+		}
+		purged++
+	}
+	return purged, tx.Commit()
+}
+
+// createConversation creates a conversation with the default settings.
+func createConversation(ctx context.Context, q *query.Queries) (query.Conversation, error) {
+	return q.CreateConversation(ctx, nullString(newUUID()))
+}
+
+// purgeConversation permanently deletes a conversation along with its
+// messages and tags.
+func purgeConversation(ctx context.Context, q *query.Queries, id int64) error {
+	// conversations that were synced leave a tombstone behind, so that
+	// they are deleted everywhere else too.
+	err := q.CreateSyncTombstone(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = q.DeleteMessagesForConversation(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = q.DeleteConversationTags(ctx, id)
+	if err != nil {
+		return err
+	}
+	_, err = q.DeleteConversation(ctx, id)
+	if err != nil {
+		return err
+	}
+	return q.DeleteUnusedTags(ctx)
+}
+
+// TrashRetention returns how long dropped conversations are kept before they
+// are eligible to be purged.
+func (s *Store) TrashRetention(ctx context.Context) (time.Duration, error) {
+	days, err := s.GetConfigInt(ctx, ConfigTrashRetentionDays, DefaultTrashRetentionDays)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+func (s *Store) getConversation(ctx context.Context, id int64) (query.Conversation, error) {
+	convo, err := s.queries.GetConversation(ctx, id)
+	if errs.IsDBNotFound(err) {
+		return convo, ErrConversationNotFound
+	}
+	return convo, err
+}
+
+func boolInt(v bool) int64 {
+	if v {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/errs"
+)
+
+// EditMessage replaces the content of a message. The message keeps its place
+// in the conversation, so the replies that follow it are unchanged.
+func (s *Store) EditMessage(ctx context.Context, id int64, content string) error {
+	if err := s.checkMessage(ctx, id); err != nil {
+		return err
+	}
+	return s.queries.SetMessageContent(ctx, query.SetMessageContentParams{
+		Content: content,
+		ID:      id,
+	})
+}
+
+// DeleteMessage deletes a message. Its content is removed, but the message
+// is kept as a placeholder so that the messages that follow it keep their
+// parent. Deleted messages are neither shown nor sent as context.
+func (s *Store) DeleteMessage(ctx context.Context, id int64) error {
+	if err := s.checkMessage(ctx, id); err != nil {
+		return err
+	}
+	return s.queries.DeleteMessage(ctx, id)
+}
+
+// SetMessageExcluded excludes a message from, or includes it in, the context
+// sent with future requests. The message is still shown.
+func (s *Store) SetMessageExcluded(ctx context.Context, id int64, excluded bool) error {
+	if err := s.checkMessage(ctx, id); err != nil {
+		return err
+	}
+	return s.queries.SetMessageExcluded(ctx, query.SetMessageExcludedParams{
+		Excluded: boolInt(excluded),
+		ID:       id,
+	})
+}
+
+func (s *Store) checkMessage(ctx context.Context, id int64) error {
+	msg, err := s.queries.GetMessage(ctx, id)
+	if errs.IsDBNotFound(err) {
+		return fmt.Errorf("no message with id %d", id)
+	}
+	if err != nil {
+		return err
+	}
+	if msg.DeletedAt.Valid {
+		return fmt.Errorf("message %d was deleted", id)
+	}
+	return nil
+}
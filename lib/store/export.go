@@ -0,0 +1,62 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/collinvandyck/gpterm/db/query"
+)
+
+// Export is a conversation as it is exported, with the messages on its
+// active branch.
+type Export struct {
+	ID       int64           `json:"id"`
+	Name     string          `json:"name,omitempty"`
+	Tags     []string        `json:"tags"`
+	Messages []ExportMessage `json:"messages"`
+}
+
+type ExportMessage struct {
+	ID        int64     `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+}
+
+// NewExport returns the export of the conversation convo, whose messages
+// are in thread.
+func NewExport(convo query.Conversation, tags []string, thread Thread) Export {
+	res := Export{
+		ID:       convo.ID,
+		Name:     convo.Name.String,
+		Tags:     tags,
+		Messages: []ExportMessage{},
+	}
+	for _, m := range thread.Path() {
+		res.Messages = append(res.Messages, ExportMessage{
+			ID:        m.ID,
+			Timestamp: m.Timestamp,
+			Role:      m.Role,
+			Content:   m.Content,
+		})
+	}
+	return res
+}
+
+// Markdown returns the export as a markdown document.
+func (e Export) Markdown() string {
+	var buf strings.Builder
+	title := fmt.Sprintf("Conversation %d", e.ID)
+	if e.Name != "" {
+		title += ": " + e.Name
+	}
+	fmt.Fprintf(&buf, "# %s\n\n", title)
+	if len(e.Tags) > 0 {
+		fmt.Fprintf(&buf, "Tags: %s\n\n", strings.Join(e.Tags, ", "))
+	}
+	for _, m := range e.Messages {
+		fmt.Fprintf(&buf, "### %s\n\n%s\n\n", m.Role, strings.TrimSpace(m.Content))
+	}
+	return buf.String()
+}
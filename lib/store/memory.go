@@ -0,0 +1,851 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/persona"
+	"github.com/collinvandyck/gpterm/lib/pricing"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Memory is a Repository that keeps everything in memory. It starts out
+// like a freshly migrated database: a single selected conversation and the
+// default client configs. It is meant for tests.
+type Memory struct {
+	mu            sync.Mutex
+	conversations []query.Conversation // ordered by id
+	messages      []query.Message      // ordered by id
+	tags          map[int64][]string   // conversation id to sorted tag names
+	config        map[string]string
+	clientConfigs []query.ClientConfig // ordered by name
+	credentials   map[string]string
+	usage         []query.Usage
+	lastMessageID int64
+	lastUsageID   int64
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		conversations: []query.Conversation{{
+			ID:        0,
+			Name:      sql.NullString{String: "default", Valid: true},
+			Protected: 1,
+			Selected:  1,
+			Uuid:      nullString(newUUID()),
+		}},
+		tags:   map[int64][]string{},
+		config: map[string]string{ConfigClientConfig: "gpt-4o"},
+		clientConfigs: []query.ClientConfig{
+			{Name: "gpt-3.5-turbo", Model: "gpt-3.5-turbo", MessageContext: 5},
+			{Name: "gpt-4", Model: "gpt-4", MessageContext: 5},
+			{Name: "gpt-4-turbo-preview", Model: "gpt-4-turbo-preview", MessageContext: 10},
+			{Name: "gpt-4o", Model: "gpt-4o", MessageContext: 20},
+		},
+		credentials: map[string]string{},
+	}
+}
+
+func (m *Memory) ActiveConversation(ctx context.Context) (query.Conversation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.active()
+	if err != nil {
+		return query.Conversation{}, err
+	}
+	return m.conversations[idx], nil
+}
+
+func (m *Memory) GetConversation(ctx context.Context, id int64) (query.Conversation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.conversation(id)
+	if err != nil {
+		return query.Conversation{}, err
+	}
+	return m.conversations[idx], nil
+}
+
+func (m *Memory) NextConversation(ctx context.Context, tag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.active()
+	if err != nil {
+		return err
+	}
+	if next, ok := m.navigate(idx, +1, tag); ok {
+		m.selectConversation(next)
+		return nil
+	}
+	if m.countMessages(m.conversations[idx].ID) == 0 {
+		return ErrNoMoreConversations
+	}
+	next := m.createConversation()
+	if tag != "" {
+		m.tag(m.conversations[next].ID, tag)
+	}
+	m.selectConversation(next)
+	return nil
+}
+
+func (m *Memory) PreviousConversation(ctx context.Context, tag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.active()
+	if err != nil {
+		return err
+	}
+	prev, ok := m.navigate(idx, -1, tag)
+	if !ok {
+		return ErrNoMoreConversations
+	}
+	m.selectConversation(prev)
+	return nil
+}
+
+func (m *Memory) DropConversation(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.active()
+	if err != nil {
+		return 0, err
+	}
+	current := m.conversations[idx]
+	if current.Protected != 0 {
+		return 0, ErrConversationProtected
+	}
+	count := m.countMessages(current.ID)
+	next, ok := m.navigate(idx, +1, "")
+	if !ok {
+		next, ok = m.navigate(idx, -1, "")
+	}
+	if !ok {
+		if count == 0 {
+			return 0, ErrNoMoreConversations
+		}
+		next = m.createConversation()
+	}
+	nextID := m.conversations[next].ID
+	if count == 0 {
+		m.purge(current.ID)
+	} else {
+		m.conversations[idx].DeletedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	}
+	// purging shifts the indexes, so the next conversation is found again
+	next, _ = m.conversation(nextID)
+	m.selectConversation(next)
+	return current.ID, nil
+}
+
+func (m *Memory) SetConversationProtected(ctx context.Context, id int64, protected bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.conversation(id)
+	if err != nil {
+		return err
+	}
+	m.conversations[idx].Protected = boolInt(protected)
+	return nil
+}
+
+func (m *Memory) SetConversationName(ctx context.Context, id int64, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.conversation(id)
+	if err != nil {
+		return err
+	}
+	m.conversations[idx].Name = nullString(name)
+	return nil
+}
+
+func (m *Memory) NewConversation(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.active()
+	if err != nil {
+		return err
+	}
+	if m.countMessages(m.conversations[idx].ID) == 0 {
+		return nil
+	}
+	m.selectConversation(m.createConversation())
+	return nil
+}
+
+func (m *Memory) SetConversationArchived(ctx context.Context, id int64, archived bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.conversation(id)
+	if err != nil {
+		return err
+	}
+	m.conversations[idx].Archived = boolInt(archived)
+	return nil
+}
+
+func (m *Memory) TagConversation(ctx context.Context, id int64, tag string) error {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.conversation(id); err != nil {
+		return err
+	}
+	m.tag(id, tag)
+	return nil
+}
+
+func (m *Memory) UntagConversation(ctx context.Context, id int64, tag string) error {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	exists := false
+	for _, tags := range m.tags {
+		exists = exists || contains(tags, tag)
+	}
+	if !exists {
+		return fmt.Errorf("conversation %d is not tagged %q", id, tag)
+	}
+	var res []string
+	for _, t := range m.tags[id] {
+		if t != tag {
+			res = append(res, t)
+		}
+	}
+	m.tags[id] = res
+	return nil
+}
+
+func (m *Memory) GetConversationTags(ctx context.Context, id int64) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.tags[id]...), nil
+}
+
+// ConversationChanged always returns false since a Memory cannot be shared
+// between processes.
+func (m *Memory) ConversationChanged(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+func (m *Memory) GetThread(ctx context.Context) (Thread, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.active()
+	if err != nil {
+		return Thread{}, err
+	}
+	return m.thread(idx), nil
+}
+
+func (m *Memory) GetLastMessages(ctx context.Context, count int) ([]query.Message, error) {
+	return lastMessages(ctx, m, count)
+}
+
+func (m *Memory) GetContextMessages(ctx context.Context, count int) ([]query.Message, error) {
+	return contextMessages(ctx, m, count)
+}
+
+func (m *Memory) GetContextMessagesBefore(ctx context.Context, messageID int64, count int) ([]query.Message, error) {
+	return contextMessagesBefore(ctx, m, messageID, count)
+}
+
+func (m *Memory) GetPreviousMessageForRole(ctx context.Context, role string, offset int) (query.Message, error) {
+	if offset <= 0 {
+		return query.Message{}, errors.New("bad offset")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.active()
+	if err != nil {
+		return query.Message{}, err
+	}
+	id := m.conversations[idx].ID
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		msg := m.messages[i]
+		if msg.ConversationID != id || msg.Role != role {
+			continue
+		}
+		offset--
+		if offset == 0 {
+			return msg, nil
+		}
+	}
+	return query.Message{}, sql.ErrNoRows
+}
+
+func (m *Memory) SwitchBranch(ctx context.Context, messageID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.active()
+	if err != nil {
+		return err
+	}
+	thread := m.thread(idx)
+	msg, ok := thread.Get(messageID)
+	if !ok {
+		return fmt.Errorf("message %d not found in conversation", messageID)
+	}
+	m.conversations[idx].LeafID = sql.NullInt64{Int64: thread.LatestLeaf(msg.ID), Valid: true}
+	return nil
+}
+
+func (m *Memory) SetMessagePinned(ctx context.Context, id int64, pinned bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.messages {
+		if m.messages[i].ID == id {
+			m.messages[i].Pinned = boolInt(pinned)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) PinRecentMessage(ctx context.Context, n int, pinned bool) (query.Message, error) {
+	return pinRecentMessage(ctx, m, n, pinned)
+}
+
+func (m *Memory) EditMessage(ctx context.Context, id int64, content string) error {
+	return m.updateMessage(id, func(msg *query.Message) {
+		msg.Content = content
+	})
+}
+
+func (m *Memory) DeleteMessage(ctx context.Context, id int64) error {
+	return m.updateMessage(id, func(msg *query.Message) {
+		msg.Content = ""
+		msg.Pinned = 0
+		msg.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	})
+}
+
+func (m *Memory) SetMessageExcluded(ctx context.Context, id int64, excluded bool) error {
+	return m.updateMessage(id, func(msg *query.Message) {
+		msg.Excluded = boolInt(excluded)
+	})
+}
+
+func (m *Memory) updateMessage(id int64, fn func(msg *query.Message)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.messages {
+		msg := &m.messages[i]
+		if msg.ID != id {
+			continue
+		}
+		if msg.DeletedAt.Valid {
+			return fmt.Errorf("message %d was deleted", id)
+		}
+		fn(msg)
+		msg.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		return nil
+	}
+	return fmt.Errorf("no message with id %d", id)
+}
+
+func (m *Memory) SaveRequest(ctx context.Context, req openai.ChatCompletionRequest) error {
+	return m.SaveBranchRequest(ctx, req, 0)
+}
+
+func (m *Memory) SaveBranchRequest(ctx context.Context, req openai.ChatCompletionRequest, messageID int64) error {
+	if len(req.Messages) > 1 {
+		msg := req.Messages[len(req.Messages)-1]
+		return m.insertMessage(msg.Role, strings.TrimSpace(msg.Content), MessageMeta{}, messageID)
+	}
+	return nil
+}
+
+func (m *Memory) SaveStreamResults(ctx context.Context, text string, meta MessageMeta) error {
+	err := m.insertMessage("assistant", strings.TrimSpace(text), meta, 0)
+	if err != nil {
+		return err
+	}
+	if meta.PromptTokens+meta.CompletionTokens > 0 {
+		m.insertUsage(meta.PromptTokens, meta.CompletionTokens, meta.PromptTokens+meta.CompletionTokens, meta.Model)
+	}
+	return nil
+}
+
+func (m *Memory) SaveRequestResponse(ctx context.Context, req openai.ChatCompletionRequest, resp openai.ChatCompletionResponse) error {
+	err := m.SaveRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	for _, choice := range resp.Choices {
+		meta := MessageMeta{
+			Model:            resp.Model,
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			FinishReason:     string(choice.FinishReason),
+		}
+		err := m.insertMessage(choice.Message.Role, strings.TrimSpace(choice.Message.Content), meta, 0)
+		if err != nil {
+			return err
+		}
+	}
+	m.insertUsage(resp.Usage.PromptTokens, resp.Usage.CompletionTokens, resp.Usage.TotalTokens, resp.Model)
+	return nil
+}
+
+func (m *Memory) GetConfig(ctx context.Context) (Config, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make(Config, 0, len(m.config))
+	for k, v := range m.config {
+		res = append(res, query.Config{Name: k, Value: v})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+func (m *Memory) GetConfigInt(ctx context.Context, name string, defaultValue int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	val, ok := m.config[name]
+	if !ok {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(val)
+}
+
+func (m *Memory) SetConfigInt(ctx context.Context, name string, value int) error {
+	if value < 0 {
+		return errors.New("value must be greater than 0")
+	}
+	return m.SetConfigString(ctx, name, strconv.Itoa(value))
+}
+
+func (m *Memory) GetConfigString(ctx context.Context, name string, defaultValue string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	val, ok := m.config[name]
+	if !ok {
+		return defaultValue, nil
+	}
+	return val, nil
+}
+
+func (m *Memory) SetConfigString(ctx context.Context, name string, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config[name] = value
+	return nil
+}
+
+func (m *Memory) DeleteConfig(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.config, name)
+	return nil
+}
+
+func (m *Memory) GetClientConfig(ctx context.Context) (query.ClientConfig, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.active()
+	if err != nil {
+		return query.ClientConfig{}, err
+	}
+	return m.conversationClientConfig(m.conversations[idx])
+}
+
+func (m *Memory) GetClientConfigs(ctx context.Context) ([]query.ClientConfig, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]query.ClientConfig{}, m.clientConfigs...), nil
+}
+
+func (m *Memory) SetConversationClientConfig(ctx context.Context, id int64, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.conversation(id)
+	if err != nil {
+		return err
+	}
+	for _, cc := range m.clientConfigs {
+		if cc.Name == name {
+			c := &m.conversations[idx]
+			c.ClientConfig = sql.NullString{String: cc.Name, Valid: true}
+			c.MessageContext = sql.NullInt64{Int64: cc.MessageContext, Valid: true}
+			m.config[ConfigClientConfig] = cc.Name
+			return nil
+		}
+	}
+	return fmt.Errorf("no client config named %q", name)
+}
+
+// UpdateClientConfig changes the context size of the current conversation
+// and of the client config it uses.
+func (m *Memory) UpdateClientConfig(ctx context.Context, messageContext int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.active()
+	if err != nil {
+		return err
+	}
+	cc, err := m.conversationClientConfig(m.conversations[idx])
+	if err != nil {
+		return err
+	}
+	c := &m.conversations[idx]
+	c.ClientConfig = sql.NullString{String: cc.Name, Valid: true}
+	c.MessageContext = sql.NullInt64{Int64: messageContext, Valid: true}
+	for i := range m.clientConfigs {
+		if m.clientConfigs[i].Name == cc.Name {
+			m.clientConfigs[i].MessageContext = messageContext
+		}
+	}
+	return nil
+}
+
+// CycleClientConfig switches the current conversation to the client config
+// that follows its current one in name order, wrapping around at the end.
+func (m *Memory) CycleClientConfig(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.active()
+	if err != nil {
+		return err
+	}
+	cc, err := m.conversationClientConfig(m.conversations[idx])
+	if err != nil {
+		return err
+	}
+	next := m.clientConfigs[0]
+	for _, config := range m.clientConfigs {
+		if config.Name > cc.Name {
+			next = config
+			break
+		}
+	}
+	c := &m.conversations[idx]
+	c.ClientConfig = sql.NullString{String: next.Name, Valid: true}
+	c.MessageContext = sql.NullInt64{Int64: next.MessageContext, Valid: true}
+	m.config[ConfigClientConfig] = next.Name
+	return nil
+}
+
+func (m *Memory) GetPersona(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.active()
+	if err != nil {
+		return "", err
+	}
+	if c := m.conversations[idx]; c.Persona.Valid {
+		return c.Persona.String, nil
+	}
+	if name, ok := m.config[ConfigPersona]; ok {
+		return name, nil
+	}
+	return persona.Default, nil
+}
+
+func (m *Memory) SetConversationPersona(ctx context.Context, id int64, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.conversation(id)
+	if err != nil {
+		return err
+	}
+	m.conversations[idx].Persona = sql.NullString{String: name, Valid: true}
+	m.config[ConfigPersona] = name
+	return nil
+}
+
+func (m *Memory) GetCredential(ctx context.Context, name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.credentials[name], nil
+}
+
+func (m *Memory) SetCredential(ctx context.Context, name string, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.credentials[name] = value
+	return nil
+}
+
+func (m *Memory) DeleteCredential(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.credentials, name)
+	return nil
+}
+
+func (m *Memory) ListCredentials(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make([]string, 0, len(m.credentials))
+	for name := range m.credentials {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+func (m *Memory) GetTotalUsage(ctx context.Context) (query.Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res query.Usage
+	for _, u := range m.usage {
+		res.PromptTokens += u.PromptTokens
+		res.CompletionTokens += u.CompletionTokens
+		res.TotalTokens += u.TotalTokens
+	}
+	return res, nil
+}
+
+func (m *Memory) GetUsageSince(ctx context.Context, t time.Time) ([]query.Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []query.Usage
+	for _, u := range m.usage {
+		if !u.Timestamp.Before(t) {
+			res = append(res, u)
+		}
+	}
+	return res, nil
+}
+
+func (m *Memory) CheckBudget(ctx context.Context, model string, promptTokens int) (BudgetStatus, error) {
+	return checkBudget(ctx, m, model, promptTokens)
+}
+
+func (m *Memory) PriceTable(ctx context.Context) (pricing.Table, error) {
+	return priceTable(ctx, m)
+}
+
+// Profile returns the default profile.
+func (m *Memory) Profile() string {
+	return DefaultProfile
+}
+
+// Dir returns the empty string, since nothing is kept on disk.
+func (m *Memory) Dir() string {
+	return ""
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+// active returns the index of the selected conversation.
+func (m *Memory) active() (int, error) {
+	for i, c := range m.conversations {
+		if c.Selected != 0 {
+			return i, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+// conversation returns the index of the conversation with the id.
+func (m *Memory) conversation(id int64) (int, error) {
+	for i, c := range m.conversations {
+		if c.ID == id {
+			return i, nil
+		}
+	}
+	return 0, ErrConversationNotFound
+}
+
+// navigate returns the index of the closest conversation in the direction
+// of delta that is neither archived nor trashed, and has the tag if one is
+// given.
+func (m *Memory) navigate(idx int, delta int, tag string) (int, bool) {
+	for i := idx + delta; i >= 0 && i < len(m.conversations); i += delta {
+		c := m.conversations[i]
+		if c.Archived != 0 || c.DeletedAt.Valid {
+			continue
+		}
+		if tag != "" && !contains(m.tags[c.ID], tag) {
+			continue
+		}
+		return i, true
+	}
+	return 0, false
+}
+
+func (m *Memory) selectConversation(idx int) {
+	for i := range m.conversations {
+		m.conversations[i].Selected = boolInt(i == idx)
+	}
+}
+
+// createConversation appends a new conversation and returns its index.
+// createConversation adds a conversation with the default settings.
+func (m *Memory) createConversation() int {
+	c := query.Conversation{
+		ID:        m.conversations[len(m.conversations)-1].ID + 1,
+		Uuid:      nullString(newUUID()),
+		UpdatedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	}
+	if idx, ok := m.clientConfig(); ok {
+		c.ClientConfig = sql.NullString{String: m.clientConfigs[idx].Name, Valid: true}
+		c.MessageContext = sql.NullInt64{Int64: m.clientConfigs[idx].MessageContext, Valid: true}
+	}
+	if name, ok := m.config[ConfigPersona]; ok {
+		c.Persona = sql.NullString{String: name, Valid: true}
+	}
+	m.conversations = append(m.conversations, c)
+	return len(m.conversations) - 1
+}
+
+// purge deletes a conversation along with its messages and tags.
+func (m *Memory) purge(id int64) {
+	var convos []query.Conversation
+	for _, c := range m.conversations {
+		if c.ID != id {
+			convos = append(convos, c)
+		}
+	}
+	m.conversations = convos
+	var msgs []query.Message
+	for _, msg := range m.messages {
+		if msg.ConversationID != id {
+			msgs = append(msgs, msg)
+		}
+	}
+	m.messages = msgs
+	delete(m.tags, id)
+}
+
+func (m *Memory) countMessages(id int64) int {
+	var res int
+	for _, msg := range m.messages {
+		if msg.ConversationID == id {
+			res++
+		}
+	}
+	return res
+}
+
+func (m *Memory) thread(idx int) Thread {
+	convo := m.conversations[idx]
+	var msgs []query.Message
+	for _, msg := range m.messages {
+		if msg.ConversationID == convo.ID {
+			msgs = append(msgs, msg)
+		}
+	}
+	return Thread{Messages: msgs, Leaf: convo.LeafID}
+}
+
+func (m *Memory) tag(id int64, tag string) {
+	if contains(m.tags[id], tag) {
+		return
+	}
+	tags := append(m.tags[id], tag)
+	sort.Strings(tags)
+	m.tags[id] = tags
+}
+
+// conversationClientConfig returns the client config of c, falling back to
+// the default if c has none.
+func (m *Memory) conversationClientConfig(c query.Conversation) (query.ClientConfig, error) {
+	idx, ok := -1, false
+	for i, cc := range m.clientConfigs {
+		if c.ClientConfig.Valid && cc.Name == c.ClientConfig.String {
+			idx, ok = i, true
+		}
+	}
+	if !ok {
+		idx, ok = m.clientConfig()
+	}
+	if !ok {
+		return query.ClientConfig{}, sql.ErrNoRows
+	}
+	cc := m.clientConfigs[idx]
+	if c.MessageContext.Valid {
+		cc.MessageContext = c.MessageContext.Int64
+	}
+	return cc, nil
+}
+
+func (m *Memory) clientConfig() (int, bool) {
+	name := m.config[ConfigClientConfig]
+	for i, cc := range m.clientConfigs {
+		if cc.Name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// insertMessage appends a message to the active branch of the current
+// conversation and makes it the new leaf.
+func (m *Memory) insertMessage(role string, content string, meta MessageMeta, branchFrom int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx, err := m.active()
+	if err != nil {
+		return err
+	}
+	parent := m.conversations[idx].LeafID
+	if branchFrom != 0 {
+		sibling, ok := m.thread(idx).Get(branchFrom)
+		if !ok {
+			return fmt.Errorf("message %d not found in conversation", branchFrom)
+		}
+		parent = sibling.ParentID
+	}
+	p := meta.insertParams(role, content)
+	m.lastMessageID++
+	msg := query.Message{
+		ID:               m.lastMessageID,
+		Timestamp:        time.Now().UTC(),
+		Role:             p.Role,
+		Content:          p.Content,
+		ConversationID:   m.conversations[idx].ID,
+		ParentID:         parent,
+		Model:            p.Model,
+		ClientConfig:     p.ClientConfig,
+		TtftMs:           p.TtftMs,
+		DurationMs:       p.DurationMs,
+		PromptTokens:     p.PromptTokens,
+		CompletionTokens: p.CompletionTokens,
+		FinishReason:     p.FinishReason,
+		Uuid:             nullString(newUUID()),
+	}
+	m.messages = append(m.messages, msg)
+	m.conversations[idx].LeafID = sql.NullInt64{Int64: msg.ID, Valid: true}
+	return nil
+}
+
+func (m *Memory) insertUsage(prompt, completion, total int, model string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastUsageID++
+	m.usage = append(m.usage, query.Usage{
+		ID:               m.lastUsageID,
+		Timestamp:        time.Now().UTC(),
+		PromptTokens:     int64(prompt),
+		CompletionTokens: int64(completion),
+		TotalTokens:      int64(total),
+		Model:            nullString(model),
+	})
+}
+
+func contains(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
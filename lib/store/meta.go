@@ -0,0 +1,60 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/collinvandyck/gpterm/db/query"
+)
+
+// MessageMeta describes how a response was produced. Zero values are stored
+// as nulls, since user messages and older responses have no metadata.
+type MessageMeta struct {
+	Model            string
+	ClientConfig     string        // name of the client config in use
+	TTFT             time.Duration // time until the first token arrived
+	Duration         time.Duration // time until the response was complete
+	PromptTokens     int
+	CompletionTokens int
+	FinishReason     string
+}
+
+// GetMessageMeta returns the metadata that was stored with a message.
+func GetMessageMeta(m query.Message) MessageMeta {
+	return MessageMeta{
+		Model:            m.Model.String,
+		ClientConfig:     m.ClientConfig.String,
+		TTFT:             time.Duration(m.TtftMs.Int64) * time.Millisecond,
+		Duration:         time.Duration(m.DurationMs.Int64) * time.Millisecond,
+		PromptTokens:     int(m.PromptTokens.Int64),
+		CompletionTokens: int(m.CompletionTokens.Int64),
+		FinishReason:     m.FinishReason.String,
+	}
+}
+
+// IsZero reports whether no metadata was recorded.
+func (m MessageMeta) IsZero() bool {
+	return m == MessageMeta{}
+}
+
+func (m MessageMeta) insertParams(role string, content string) query.InsertMessageParams {
+	return query.InsertMessageParams{
+		Role:             role,
+		Content:          content,
+		Model:            nullString(m.Model),
+		ClientConfig:     nullString(m.ClientConfig),
+		TtftMs:           nullInt(m.TTFT.Milliseconds()),
+		DurationMs:       nullInt(m.Duration.Milliseconds()),
+		PromptTokens:     nullInt(int64(m.PromptTokens)),
+		CompletionTokens: nullInt(int64(m.CompletionTokens)),
+		FinishReason:     nullString(m.FinishReason),
+	}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullInt(i int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: i, Valid: i != 0}
+}
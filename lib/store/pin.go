@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/collinvandyck/gpterm/db/query"
+)
+
+// GetContextMessages returns the messages to send as context for the next
+// request: the last count messages of the active branch, preceded by any
+// pinned messages that fall outside of that window. Excluded messages are
+// never sent, even if they are pinned.
+func (s *Store) GetContextMessages(ctx context.Context, count int) ([]query.Message, error) {
+	return contextMessages(ctx, s, count)
+}
+
+// GetContextMessagesBefore returns the context messages for a prompt that
+// replaces the message with the id, which are those before it on its branch.
+func (s *Store) GetContextMessagesBefore(ctx context.Context, messageID int64, count int) ([]query.Message, error) {
+	return contextMessagesBefore(ctx, s, messageID, count)
+}
+
+func contextMessages(ctx context.Context, r Repository, count int) ([]query.Message, error) {
+	thread, err := r.GetThread(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pathContext(thread.Path(), count), nil
+}
+
+func contextMessagesBefore(ctx context.Context, r Repository, messageID int64, count int) ([]query.Message, error) {
+	thread, err := r.GetThread(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msg, ok := thread.Get(messageID)
+	if !ok {
+		return nil, fmt.Errorf("message %d not found in conversation", messageID)
+	}
+	thread.Leaf = msg.ParentID
+	return pathContext(thread.Path(), count), nil
+}
+
+// pathContext returns the messages of path that are sent as context: the
+// last count of them and those that are pinned, unless they are excluded.
+func pathContext(path []query.Message, count int) []query.Message {
+	start := max(len(path)-count, 0)
+	var res []query.Message
+	for i, m := range path {
+		if m.Excluded == 0 && (i >= start || m.Pinned != 0) {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// PinRecentMessage pins or unpins the nth most recent message on the active
+// branch, where 1 is the latest message. The updated message is returned.
+func (s *Store) PinRecentMessage(ctx context.Context, n int, pinned bool) (query.Message, error) {
+	return pinRecentMessage(ctx, s, n, pinned)
+}
+
+func pinRecentMessage(ctx context.Context, r Repository, n int, pinned bool) (query.Message, error) {
+	thread, err := r.GetThread(ctx)
+	if err != nil {
+		return query.Message{}, err
+	}
+	path := thread.Path()
+	if n < 1 || n > len(path) {
+		return query.Message{}, fmt.Errorf("there is no message %d back in this conversation", n)
+	}
+	msg := path[len(path)-n]
+	err = r.SetMessagePinned(ctx, msg.ID, pinned)
+	if err != nil {
+		return query.Message{}, err
+	}
+	msg.Pinned = boolInt(pinned)
+	return msg, nil
+}
+
+// SetMessagePinned pins or unpins a message. Pinned messages are always sent
+// as context, and conversations with pinned messages can be kept by the
+// retention policy.
+func (s *Store) SetMessagePinned(ctx context.Context, id int64, pinned bool) error {
+	return s.queries.SetMessagePinned(ctx, query.SetMessagePinnedParams{
+		Pinned: boolInt(pinned),
+		ID:     id,
+	})
+}
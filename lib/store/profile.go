@@ -0,0 +1,164 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+const (
+	// ProfileEnv selects the profile to use when --profile is not given.
+	ProfileEnv = "GPTERM_PROFILE"
+	// DefaultProfile lives directly in the gpterm config dir so that stores
+	// created before profiles existed keep working.
+	DefaultProfile = "default"
+
+	profilesDirName = "profiles"
+)
+
+var (
+	ErrProfileNotFound = errors.New("profile does not exist")
+	ErrProfileExists   = errors.New("profile already exists")
+
+	profileNameRE = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+// Profile is a named store directory with its own database, credentials and
+// personas.
+type Profile struct {
+	Name    string
+	Dir     string
+	Current bool
+}
+
+// CurrentProfile returns the name of the profile selected by GPTERM_PROFILE,
+// which is used when no other profile is asked for.
+func CurrentProfile() string {
+	if name := os.Getenv(ProfileEnv); name != "" {
+		return name
+	}
+	return DefaultProfile
+}
+
+// ProfileDir returns the store directory for a profile. The directory is not
+// required to exist.
+func ProfileDir(name string) (string, error) {
+	if err := validateProfileName(name); err != nil {
+		return "", err
+	}
+	root, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	if name == DefaultProfile {
+		return root, nil
+	}
+	return filepath.Join(root, profilesDirName, name), nil
+}
+
+// ProfileExists reports whether a profile has been created. The default
+// profile always exists.
+func ProfileExists(name string) (bool, error) {
+	if name == DefaultProfile {
+		return true, nil
+	}
+	dir, err := ProfileDir(name)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(dir)
+	switch {
+	case os.IsNotExist(err):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+// ListProfiles returns the default profile followed by every other profile
+// in name order, marking current as the one in use.
+func ListProfiles(current string) ([]Profile, error) {
+	dir, err := ProfileDir(DefaultProfile)
+	if err != nil {
+		return nil, err
+	}
+	res := []Profile{{Name: DefaultProfile, Dir: dir, Current: current == DefaultProfile}}
+	entries, err := os.ReadDir(filepath.Join(dir, profilesDirName))
+	switch {
+	case os.IsNotExist(err):
+		return res, nil
+	case err != nil:
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && validateProfileName(e.Name()) == nil {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		res = append(res, Profile{
+			Name:    name,
+			Dir:     filepath.Join(dir, profilesDirName, name),
+			Current: current == name,
+		})
+	}
+	return res, nil
+}
+
+// CreateProfile creates the store directory for a new profile. The database
+// is created the first time the profile is used.
+func CreateProfile(name string) (string, error) {
+	exists, err := ProfileExists(name)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", fmt.Errorf("%w: %s", ErrProfileExists, name)
+	}
+	dir, err := ProfileDir(name)
+	if err != nil {
+		return "", err
+	}
+	return dir, os.MkdirAll(dir, 0755)
+}
+
+// RemoveProfile deletes a profile's store directory, including its history
+// and credentials. The default profile cannot be removed.
+func RemoveProfile(name string) error {
+	if name == DefaultProfile {
+		return errors.New("the default profile cannot be removed")
+	}
+	exists, err := ProfileExists(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	dir, err := ProfileDir(name)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func validateProfileName(name string) error {
+	if !profileNameRE.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, numbers, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+func configDir() (string, error) {
+	hd, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(hd, ".config", "gpterm"), nil
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/pricing"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Repository is the storage used by the TUI and the credential backends.
+// Store implements it on top of sqlite, and Memory implements it in memory
+// so that the TUI can be exercised without a database on disk.
+type Repository interface {
+	// Conversations
+	ActiveConversation(ctx context.Context) (query.Conversation, error)
+	GetConversation(ctx context.Context, id int64) (query.Conversation, error)
+	NewConversation(ctx context.Context) error
+	NextConversation(ctx context.Context, tag string) error
+	PreviousConversation(ctx context.Context, tag string) error
+	DropConversation(ctx context.Context) (int64, error)
+	SetConversationProtected(ctx context.Context, id int64, protected bool) error
+	SetConversationArchived(ctx context.Context, id int64, archived bool) error
+	SetConversationName(ctx context.Context, id int64, name string) error
+	TagConversation(ctx context.Context, id int64, tag string) error
+	UntagConversation(ctx context.Context, id int64, tag string) error
+	GetConversationTags(ctx context.Context, id int64) ([]string, error)
+	ConversationChanged(ctx context.Context) (bool, error)
+
+	// Messages
+	GetThread(ctx context.Context) (Thread, error)
+	GetLastMessages(ctx context.Context, count int) ([]query.Message, error)
+	GetContextMessages(ctx context.Context, count int) ([]query.Message, error)
+	GetContextMessagesBefore(ctx context.Context, messageID int64, count int) ([]query.Message, error)
+	GetPreviousMessageForRole(ctx context.Context, role string, offset int) (query.Message, error)
+	SwitchBranch(ctx context.Context, messageID int64) error
+	SetMessagePinned(ctx context.Context, id int64, pinned bool) error
+	PinRecentMessage(ctx context.Context, n int, pinned bool) (query.Message, error)
+	EditMessage(ctx context.Context, id int64, content string) error
+	DeleteMessage(ctx context.Context, id int64) error
+	SetMessageExcluded(ctx context.Context, id int64, excluded bool) error
+	SaveRequest(ctx context.Context, req openai.ChatCompletionRequest) error
+	SaveBranchRequest(ctx context.Context, req openai.ChatCompletionRequest, messageID int64) error
+	SaveStreamResults(ctx context.Context, text string, meta MessageMeta) error
+	SaveRequestResponse(ctx context.Context, req openai.ChatCompletionRequest, resp openai.ChatCompletionResponse) error
+
+	// Config
+	GetConfig(ctx context.Context) (Config, error)
+	GetConfigInt(ctx context.Context, name string, defaultValue int) (int, error)
+	SetConfigInt(ctx context.Context, name string, value int) error
+	GetConfigString(ctx context.Context, name string, defaultValue string) (string, error)
+	SetConfigString(ctx context.Context, name string, value string) error
+	DeleteConfig(ctx context.Context, name string) error
+	GetClientConfig(ctx context.Context) (query.ClientConfig, error)
+	GetClientConfigs(ctx context.Context) ([]query.ClientConfig, error)
+	SetConversationClientConfig(ctx context.Context, id int64, name string) error
+	UpdateClientConfig(ctx context.Context, messageContext int64) error
+	CycleClientConfig(ctx context.Context) error
+	GetPersona(ctx context.Context) (string, error)
+	SetConversationPersona(ctx context.Context, id int64, name string) error
+
+	// Credentials
+	GetCredential(ctx context.Context, name string) (string, error)
+	SetCredential(ctx context.Context, name string, value string) error
+	DeleteCredential(ctx context.Context, name string) error
+	ListCredentials(ctx context.Context) ([]string, error)
+
+	// Usage
+	GetTotalUsage(ctx context.Context) (query.Usage, error)
+	GetUsageSince(ctx context.Context, t time.Time) ([]query.Usage, error)
+	CheckBudget(ctx context.Context, model string, promptTokens int) (BudgetStatus, error)
+	PriceTable(ctx context.Context) (pricing.Table, error)
+
+	// Profile returns the name of the profile the repository belongs to.
+	Profile() string
+	// Dir returns the directory files belonging to the store, such as the
+	// encrypted credentials, are kept in. It is empty if there is none.
+	Dir() string
+	Close() error
+}
+
+var (
+	_ Repository = (*Store)(nil)
+	_ Repository = (*Memory)(nil)
+)
+
+// lastMessages returns up to count messages from the end of the active
+// branch of the current conversation.
+func lastMessages(ctx context.Context, r Repository, count int) ([]query.Message, error) {
+	thread, err := r.GetThread(ctx)
+	if err != nil {
+		return nil, err
+	}
+	path := thread.Path()
+	if len(path) > count {
+		path = path[len(path)-count:]
+	}
+	return path, nil
+}
@@ -0,0 +1,477 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/errs"
+	"github.com/collinvandyck/gpterm/lib/pricing"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreRepository(t *testing.T) {
+	testRepository(t, func(t *testing.T) Repository {
+		return newTestStore(t, t.TempDir())
+	})
+}
+
+func TestMemoryRepository(t *testing.T) {
+	testRepository(t, func(t *testing.T) Repository {
+		return NewMemory()
+	})
+}
+
+// testRepository is the conformance suite that every Repository must pass.
+func testRepository(t *testing.T, newRepo func(t *testing.T) Repository) {
+	ctx := context.Background()
+
+	// say adds a prompt and its response to the current conversation.
+	say := func(t *testing.T, r Repository, prompt, response string) {
+		t.Helper()
+		ask(t, r, prompt)
+		err := r.SaveStreamResults(ctx, response, MessageMeta{Model: "gpt-4o"})
+		require.NoError(t, err)
+	}
+	active := func(t *testing.T, r Repository) int64 {
+		t.Helper()
+		c, err := r.ActiveConversation(ctx)
+		require.NoError(t, err)
+		return c.ID
+	}
+	contents := func(msgs []query.Message) []string {
+		res := []string{}
+		for _, m := range msgs {
+			res = append(res, m.Content)
+		}
+		return res
+	}
+	// conversations creates n conversations with messages, leaving the
+	// last one selected, and returns their ids.
+	conversations := func(t *testing.T, r Repository, n int) []int64 {
+		t.Helper()
+		var res []int64
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				require.NoError(t, r.NextConversation(ctx, ""))
+			}
+			say(t, r, "hello", "hi")
+			res = append(res, active(t, r))
+		}
+		return res
+	}
+	// the default conversation is protected, which would keep it from
+	// being dropped
+	unprotect := func(t *testing.T, r Repository) {
+		t.Helper()
+		require.NoError(t, r.SetConversationProtected(ctx, 0, false))
+	}
+
+	t.Run("fresh", func(t *testing.T) {
+		r := newRepo(t)
+		convo, err := r.ActiveConversation(ctx)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, convo.Protected)
+		msgs, err := r.GetLastMessages(ctx, 10)
+		require.NoError(t, err)
+		require.Empty(t, msgs)
+		cc, err := r.GetClientConfig(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "gpt-4o", cc.Name)
+	})
+
+	t.Run("next from an empty conversation", func(t *testing.T) {
+		r := newRepo(t)
+		first := active(t, r)
+		require.ErrorIs(t, r.NextConversation(ctx, ""), ErrNoMoreConversations)
+		require.Equal(t, first, active(t, r))
+	})
+
+	t.Run("next creates a conversation at the end", func(t *testing.T) {
+		r := newRepo(t)
+		first := conversations(t, r, 1)[0]
+		require.NoError(t, r.NextConversation(ctx, ""))
+		second := active(t, r)
+		require.NotEqual(t, first, second)
+		msgs, err := r.GetLastMessages(ctx, 10)
+		require.NoError(t, err)
+		require.Empty(t, msgs)
+		require.ErrorIs(t, r.NextConversation(ctx, ""), ErrNoMoreConversations)
+		require.NoError(t, r.PreviousConversation(ctx, ""))
+		require.Equal(t, first, active(t, r))
+		require.ErrorIs(t, r.PreviousConversation(ctx, ""), ErrNoMoreConversations)
+		require.Equal(t, first, active(t, r))
+	})
+
+	t.Run("new conversation", func(t *testing.T) {
+		r := newRepo(t)
+		first := active(t, r)
+		// an empty conversation is already new
+		require.NoError(t, r.NewConversation(ctx))
+		require.Equal(t, first, active(t, r))
+		say(t, r, "hello", "hi")
+		require.NoError(t, r.SetConversationName(ctx, first, "greetings"))
+		require.NoError(t, r.NewConversation(ctx))
+		second := active(t, r)
+		require.NotEqual(t, first, second)
+		msgs, err := r.GetLastMessages(ctx, 10)
+		require.NoError(t, err)
+		require.Empty(t, msgs)
+		c, err := r.GetConversation(ctx, first)
+		require.NoError(t, err)
+		require.Equal(t, "greetings", c.Name.String)
+		require.ErrorIs(t, r.SetConversationName(ctx, 1000, "nope"), ErrConversationNotFound)
+	})
+
+	t.Run("navigation skips archived conversations", func(t *testing.T) {
+		r := newRepo(t)
+		ids := conversations(t, r, 3)
+		require.NoError(t, r.SetConversationArchived(ctx, ids[1], true))
+		require.NoError(t, r.PreviousConversation(ctx, ""))
+		require.Equal(t, ids[0], active(t, r))
+		require.NoError(t, r.NextConversation(ctx, ""))
+		require.Equal(t, ids[2], active(t, r))
+	})
+
+	t.Run("navigation with a tag", func(t *testing.T) {
+		r := newRepo(t)
+		ids := conversations(t, r, 2)
+		require.NoError(t, r.TagConversation(ctx, ids[0], "Work"))
+		require.NoError(t, r.PreviousConversation(ctx, "work"))
+		require.Equal(t, ids[0], active(t, r))
+
+		// there is no later conversation tagged work, so a new one is
+		// created with the tag.
+		require.NoError(t, r.NextConversation(ctx, "work"))
+		created := active(t, r)
+		require.NotContains(t, ids, created)
+		tags, err := r.GetConversationTags(ctx, created)
+		require.NoError(t, err)
+		require.Equal(t, []string{"work"}, tags)
+
+		require.NoError(t, r.PreviousConversation(ctx, "work"))
+		require.Equal(t, ids[0], active(t, r))
+		require.NoError(t, r.UntagConversation(ctx, ids[0], "work"))
+		tags, err = r.GetConversationTags(ctx, ids[0])
+		require.NoError(t, err)
+		require.Empty(t, tags)
+		require.Error(t, r.UntagConversation(ctx, ids[0], "missing"))
+	})
+
+	t.Run("drop selects the next conversation", func(t *testing.T) {
+		r := newRepo(t)
+		unprotect(t, r)
+		ids := conversations(t, r, 2)
+		require.NoError(t, r.PreviousConversation(ctx, ""))
+		dropped, err := r.DropConversation(ctx)
+		require.NoError(t, err)
+		require.Equal(t, ids[0], dropped)
+		require.Equal(t, ids[1], active(t, r))
+		convo, err := r.GetConversation(ctx, ids[0])
+		require.NoError(t, err)
+		require.True(t, convo.DeletedAt.Valid)
+		require.ErrorIs(t, r.PreviousConversation(ctx, ""), ErrNoMoreConversations)
+	})
+
+	t.Run("drop the last conversation selects the previous one", func(t *testing.T) {
+		r := newRepo(t)
+		ids := conversations(t, r, 2)
+		dropped, err := r.DropConversation(ctx)
+		require.NoError(t, err)
+		require.Equal(t, ids[1], dropped)
+		require.Equal(t, ids[0], active(t, r))
+	})
+
+	t.Run("drop the only conversation creates a new one", func(t *testing.T) {
+		r := newRepo(t)
+		unprotect(t, r)
+		ids := conversations(t, r, 1)
+		dropped, err := r.DropConversation(ctx)
+		require.NoError(t, err)
+		require.Equal(t, ids[0], dropped)
+		require.NotEqual(t, ids[0], active(t, r))
+		msgs, err := r.GetLastMessages(ctx, 10)
+		require.NoError(t, err)
+		require.Empty(t, msgs)
+	})
+
+	t.Run("drop the only conversation when it is empty", func(t *testing.T) {
+		r := newRepo(t)
+		unprotect(t, r)
+		first := active(t, r)
+		_, err := r.DropConversation(ctx)
+		require.ErrorIs(t, err, ErrNoMoreConversations)
+		require.Equal(t, first, active(t, r))
+	})
+
+	t.Run("drop an empty conversation deletes it", func(t *testing.T) {
+		r := newRepo(t)
+		ids := conversations(t, r, 1)
+		require.NoError(t, r.NextConversation(ctx, ""))
+		empty := active(t, r)
+		dropped, err := r.DropConversation(ctx)
+		require.NoError(t, err)
+		require.Equal(t, empty, dropped)
+		require.Equal(t, ids[0], active(t, r))
+		_, err = r.GetConversation(ctx, empty)
+		require.ErrorIs(t, err, ErrConversationNotFound)
+	})
+
+	t.Run("drop a protected conversation", func(t *testing.T) {
+		r := newRepo(t)
+		ids := conversations(t, r, 2)
+		require.NoError(t, r.SetConversationProtected(ctx, ids[1], true))
+		_, err := r.DropConversation(ctx)
+		require.ErrorIs(t, err, ErrConversationProtected)
+		require.Equal(t, ids[1], active(t, r))
+	})
+
+	t.Run("branches", func(t *testing.T) {
+		r := newRepo(t)
+		say(t, r, "one", "1")
+		say(t, r, "two", "2")
+		msgs, err := r.GetLastMessages(ctx, 2)
+		require.NoError(t, err)
+		require.Equal(t, []string{"two", "2"}, contents(msgs))
+		two := msgs[0]
+
+		// the context for the edit is what came before it, and the active
+		// branch only changes once the edit is saved
+		latest, err := r.GetContextMessagesBefore(ctx, two.ID, 10)
+		require.NoError(t, err)
+		require.Equal(t, []string{"one", "1"}, contents(latest))
+		thread, err := r.GetThread(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"one", "1", "two", "2"}, contents(thread.Path()))
+
+		err = r.SaveBranchRequest(ctx, openai.ChatCompletionRequest{
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: "context"},
+				{Role: openai.ChatMessageRoleUser, Content: "two again"},
+			},
+		}, two.ID)
+		require.NoError(t, err)
+		require.NoError(t, r.SaveStreamResults(ctx, "2b", MessageMeta{}))
+		thread, err = r.GetThread(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"one", "1", "two again", "2b"}, contents(thread.Path()))
+		require.Len(t, thread.Siblings(two.ID), 2)
+
+		require.NoError(t, r.SwitchBranch(ctx, two.ID))
+		thread, err = r.GetThread(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"one", "1", "two", "2"}, contents(thread.Path()))
+		require.Error(t, r.SwitchBranch(ctx, 12345))
+		_, err = r.GetContextMessagesBefore(ctx, 12345, 10)
+		require.Error(t, err)
+		require.Error(t, r.SaveBranchRequest(ctx, openai.ChatCompletionRequest{
+			Messages: []openai.ChatCompletionMessage{{Role: "system"}, {Role: "user", Content: "x"}},
+		}, 12345))
+	})
+
+	t.Run("pinned messages are kept in context", func(t *testing.T) {
+		r := newRepo(t)
+		say(t, r, "one", "1")
+		say(t, r, "two", "2")
+		say(t, r, "three", "3")
+		pinned, err := r.PinRecentMessage(ctx, 6, true)
+		require.NoError(t, err)
+		require.Equal(t, "one", pinned.Content)
+		msgs, err := r.GetContextMessages(ctx, 2)
+		require.NoError(t, err)
+		require.Equal(t, []string{"one", "three", "3"}, contents(msgs))
+		_, err = r.PinRecentMessage(ctx, 7, true)
+		require.Error(t, err)
+	})
+
+	t.Run("edit, delete and exclude messages", func(t *testing.T) {
+		r := newRepo(t)
+		say(t, r, "one", "1")
+		say(t, r, "two", "2")
+		say(t, r, "three", "3")
+		thread, err := r.GetThread(ctx)
+		require.NoError(t, err)
+		path := thread.Path()
+		require.NoError(t, r.EditMessage(ctx, path[1].ID, "uno"))
+		require.NoError(t, r.DeleteMessage(ctx, path[2].ID))
+		require.NoError(t, r.SetMessageExcluded(ctx, path[3].ID, true))
+		require.NoError(t, r.SetMessagePinned(ctx, path[3].ID, true))
+		msgs, err := r.GetLastMessages(ctx, 10)
+		require.NoError(t, err)
+		require.Equal(t, []string{"one", "uno", "2", "three", "3"}, contents(msgs))
+		msgs, err = r.GetContextMessages(ctx, 10)
+		require.NoError(t, err)
+		require.Equal(t, []string{"one", "uno", "three", "3"}, contents(msgs))
+		require.Error(t, r.EditMessage(ctx, path[2].ID, "again"))
+		require.NoError(t, r.SetMessageExcluded(ctx, path[3].ID, false))
+		msgs, err = r.GetContextMessages(ctx, 10)
+		require.NoError(t, err)
+		require.Equal(t, []string{"one", "uno", "2", "three", "3"}, contents(msgs))
+	})
+
+	t.Run("previous messages for a role", func(t *testing.T) {
+		r := newRepo(t)
+		say(t, r, "one", "1")
+		say(t, r, "two", "2")
+		msg, err := r.GetPreviousMessageForRole(ctx, openai.ChatMessageRoleUser, 1)
+		require.NoError(t, err)
+		require.Equal(t, "two", msg.Content)
+		msg, err = r.GetPreviousMessageForRole(ctx, openai.ChatMessageRoleUser, 2)
+		require.NoError(t, err)
+		require.Equal(t, "one", msg.Content)
+		_, err = r.GetPreviousMessageForRole(ctx, openai.ChatMessageRoleUser, 3)
+		require.True(t, errs.IsDBNotFound(err))
+	})
+
+	t.Run("config", func(t *testing.T) {
+		r := newRepo(t)
+		val, err := r.GetConfigInt(ctx, "test.int", 7)
+		require.NoError(t, err)
+		require.Equal(t, 7, val)
+		require.NoError(t, r.SetConfigInt(ctx, "test.int", 3))
+		val, err = r.GetConfigInt(ctx, "test.int", 7)
+		require.NoError(t, err)
+		require.Equal(t, 3, val)
+		require.Error(t, r.SetConfigInt(ctx, "test.int", -1))
+
+		require.NoError(t, r.SetConfigString(ctx, "test.string", "value"))
+		str, err := r.GetConfigString(ctx, "test.string", "")
+		require.NoError(t, err)
+		require.Equal(t, "value", str)
+		cfg, err := r.GetConfig(ctx)
+		require.NoError(t, err)
+		require.Contains(t, cfg, query.Config{Name: "test.string", Value: "value"})
+		require.NoError(t, r.DeleteConfig(ctx, "test.string"))
+		str, err = r.GetConfigString(ctx, "test.string", "default")
+		require.NoError(t, err)
+		require.Equal(t, "default", str)
+	})
+
+	t.Run("client config", func(t *testing.T) {
+		r := newRepo(t)
+		require.NoError(t, r.UpdateClientConfig(ctx, 3))
+		cc, err := r.GetClientConfig(ctx)
+		require.NoError(t, err)
+		require.EqualValues(t, 3, cc.MessageContext)
+		// gpt-4o is last in name order, so cycling wraps around
+		require.NoError(t, r.CycleClientConfig(ctx))
+		cc, err = r.GetClientConfig(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "gpt-3.5-turbo", cc.Name)
+		require.NoError(t, r.CycleClientConfig(ctx))
+		cc, err = r.GetClientConfig(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "gpt-4", cc.Name)
+		require.NoError(t, r.SetConversationClientConfig(ctx, active(t, r), "gpt-4o"))
+		cc, err = r.GetClientConfig(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "gpt-4o", cc.Name)
+		require.Error(t, r.SetConversationClientConfig(ctx, active(t, r), "gpt-99"))
+		ccs, err := r.GetClientConfigs(ctx)
+		require.NoError(t, err)
+		require.Len(t, ccs, 4)
+	})
+
+	t.Run("conversation settings", func(t *testing.T) {
+		r := newRepo(t)
+		settings := func() (string, int64, string) {
+			t.Helper()
+			cc, err := r.GetClientConfig(ctx)
+			require.NoError(t, err)
+			name, err := r.GetPersona(ctx)
+			require.NoError(t, err)
+			return cc.Name, cc.MessageContext, name
+		}
+		say(t, r, "hello", "hi")
+		require.NoError(t, r.CycleClientConfig(ctx))
+		require.NoError(t, r.UpdateClientConfig(ctx, 2))
+		require.NoError(t, r.SetConversationPersona(ctx, active(t, r), "terse"))
+		model, mc, name := settings()
+		require.Equal(t, "gpt-3.5-turbo", model)
+		require.EqualValues(t, 2, mc)
+		require.Equal(t, "terse", name)
+
+		// new conversations start with the settings chosen last
+		require.NoError(t, r.NextConversation(ctx, ""))
+		model, mc, name = settings()
+		require.Equal(t, "gpt-3.5-turbo", model)
+		require.EqualValues(t, 2, mc)
+		require.Equal(t, "terse", name)
+
+		// and keep their own when they are changed
+		require.NoError(t, r.CycleClientConfig(ctx))
+		require.NoError(t, r.SetConversationPersona(ctx, active(t, r), "default"))
+		model, mc, name = settings()
+		require.Equal(t, "gpt-4", model)
+		require.EqualValues(t, 5, mc)
+		require.Equal(t, "default", name)
+		require.NoError(t, r.PreviousConversation(ctx, ""))
+		model, mc, name = settings()
+		require.Equal(t, "gpt-3.5-turbo", model)
+		require.EqualValues(t, 2, mc)
+		require.Equal(t, "terse", name)
+	})
+
+	t.Run("credentials", func(t *testing.T) {
+		r := newRepo(t)
+		val, err := r.GetCredential(ctx, CredentialAPIKey)
+		require.NoError(t, err)
+		require.Empty(t, val)
+		require.NoError(t, r.SetCredential(ctx, CredentialAPIKey, "secret"))
+		require.NoError(t, r.SetCredential(ctx, CredentialAPIKey, "secret2"))
+		val, err = r.GetCredential(ctx, CredentialAPIKey)
+		require.NoError(t, err)
+		require.Equal(t, "secret2", val)
+		names, err := r.ListCredentials(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{CredentialAPIKey}, names)
+		require.NoError(t, r.DeleteCredential(ctx, CredentialAPIKey))
+		names, err = r.ListCredentials(ctx)
+		require.NoError(t, err)
+		require.Empty(t, names)
+	})
+
+	t.Run("usage and budgets", func(t *testing.T) {
+		r := newRepo(t)
+		say(t, r, "no usage", "was reported")
+		err := r.SaveStreamResults(ctx, "response", MessageMeta{
+			Model:            "gpt-4o-2024-08-06",
+			PromptTokens:     100,
+			CompletionTokens: 50,
+		})
+		require.NoError(t, err)
+		total, err := r.GetTotalUsage(ctx)
+		require.NoError(t, err)
+		require.EqualValues(t, 150, total.TotalTokens)
+		usage, err := r.GetUsageSince(ctx, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		require.Len(t, usage, 1)
+		require.Equal(t, "gpt-4o-2024-08-06", usage[0].Model.String)
+		usage, err = r.GetUsageSince(ctx, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		require.Empty(t, usage)
+
+		status, err := r.CheckBudget(ctx, "gpt-4o", 0)
+		require.NoError(t, err)
+		require.Zero(t, status.Limit)
+		require.NoError(t, r.SetConfigString(ctx, ConfigBudgetModelPrefix+"gpt-4o", "0.0001"))
+		status, err = r.CheckBudget(ctx, "gpt-4o", 0)
+		require.NoError(t, err)
+		require.Equal(t, "gpt-4o", status.Model)
+		require.True(t, status.Exceeded)
+		status, err = r.CheckBudget(ctx, "gpt-4", 0)
+		require.NoError(t, err)
+		require.Zero(t, status.Limit)
+
+		require.NoError(t, r.SetConfigString(ctx, ConfigPricePrefix+"my-model", "1,2"))
+		prices, err := r.PriceTable(ctx)
+		require.NoError(t, err)
+		_, price, ok := prices.Lookup("my-model")
+		require.True(t, ok)
+		require.Equal(t, pricing.Price{Input: 1, Output: 2}, price)
+		_, _, ok = prices.Lookup("gpt-4o")
+		require.True(t, ok)
+	})
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+const (
+	ConfigRetentionMaxAgeDays       = "retention.max-age-days"
+	ConfigRetentionMaxConversations = "retention.max-conversations"
+	ConfigRetentionKeepProtected    = "retention.keep-protected"
+	ConfigRetentionKeepPinned       = "retention.keep-pinned"
+)
+
+// RetentionPolicy decides which conversations are pruned. A zero MaxAge or
+// MaxConversations disables that rule. The selected conversation is never
+// pruned.
+type RetentionPolicy struct {
+	// MaxAge prunes conversations whose last message is older than this.
+	MaxAge time.Duration
+	// MaxConversations prunes the least recently used conversations once
+	// there are more than this many.
+	MaxConversations int
+	// KeepProtected exempts protected conversations from both rules. They
+	// still count towards MaxConversations.
+	KeepProtected bool
+	// KeepPinned does the same for conversations with pinned messages.
+	KeepPinned bool
+}
+
+// Enabled reports whether any rule is set.
+func (p RetentionPolicy) Enabled() bool {
+	return p.MaxAge > 0 || p.MaxConversations > 0
+}
+
+// PruneResult reports what was, or would be, removed by Prune.
+type PruneResult struct {
+	Conversations []int64
+	Messages      int64
+	Bytes         int64
+}
+
+// RetentionPolicy returns the configured retention policy. By default
+// nothing is pruned.
+func (s *Store) RetentionPolicy(ctx context.Context) (RetentionPolicy, error) {
+	days, err := s.GetConfigInt(ctx, ConfigRetentionMaxAgeDays, 0)
+	if err != nil {
+		return RetentionPolicy{}, err
+	}
+	max, err := s.GetConfigInt(ctx, ConfigRetentionMaxConversations, 0)
+	if err != nil {
+		return RetentionPolicy{}, err
+	}
+	keepProtected, err := s.GetConfigInt(ctx, ConfigRetentionKeepProtected, 1)
+	if err != nil {
+		return RetentionPolicy{}, err
+	}
+	keepPinned, err := s.GetConfigInt(ctx, ConfigRetentionKeepPinned, 1)
+	if err != nil {
+		return RetentionPolicy{}, err
+	}
+	return RetentionPolicy{
+		MaxAge:           time.Duration(days) * 24 * time.Hour,
+		MaxConversations: max,
+		KeepProtected:    keepProtected != 0,
+		KeepPinned:       keepPinned != 0,
+	}, nil
+}
+
+// SetRetentionPolicy saves the retention policy that is applied at startup.
+func (s *Store) SetRetentionPolicy(ctx context.Context, p RetentionPolicy) error {
+	err := s.SetConfigInt(ctx, ConfigRetentionMaxAgeDays, int(p.MaxAge/(24*time.Hour)))
+	if err != nil {
+		return err
+	}
+	err = s.SetConfigInt(ctx, ConfigRetentionMaxConversations, p.MaxConversations)
+	if err != nil {
+		return err
+	}
+	err = s.SetConfigInt(ctx, ConfigRetentionKeepProtected, int(boolInt(p.KeepProtected)))
+	if err != nil {
+		return err
+	}
+	return s.SetConfigInt(ctx, ConfigRetentionKeepPinned, int(boolInt(p.KeepPinned)))
+}
+
+// Prune permanently deletes the conversations that fall outside of the
+// policy, along with their messages. With dryRun nothing is deleted but the
+// result still reports what would have been.
+func (s *Store) Prune(ctx context.Context, p RetentionPolicy, dryRun bool) (PruneResult, error) {
+	var res PruneResult
+	if !p.Enabled() {
+		return res, nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+	q := s.queries.WithTx(tx)
+	// the conversation selected last is kept too, as well as the one this
+	// process is on, since another process may have it open.
+	s.mu.Lock()
+	current := s.conversationID
+	s.mu.Unlock()
+	convos, err := q.GetConversationSummaries(ctx)
+	if err != nil {
+		return res, err
+	}
+	lasts, err := q.GetLastMessagePerConversation(ctx)
+	if err != nil {
+		return res, err
+	}
+	pinned, err := q.GetConversationIDsWithPinnedMessages(ctx)
+	if err != nil {
+		return res, err
+	}
+	hasPinned := map[int64]bool{}
+	for _, id := range pinned {
+		hasPinned[id] = true
+	}
+	lastActive := map[int64]time.Time{}
+	for _, m := range lasts {
+		lastActive[m.ConversationID] = m.Timestamp
+	}
+	// most recently active first. conversations without messages have no
+	// activity to go on, so newer ids win.
+	sort.SliceStable(convos, func(i, j int) bool {
+		ti, tj := lastActive[convos[i].ID], lastActive[convos[j].ID]
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return convos[i].ID > convos[j].ID
+	})
+	cutoff := time.Now().Add(-p.MaxAge)
+	var kept int
+	for _, c := range convos {
+		exempt := c.Selected != 0 || c.ID == current ||
+			(p.KeepProtected && c.Protected != 0) ||
+			(p.KeepPinned && hasPinned[c.ID])
+		last, active := lastActive[c.ID]
+		prune := !exempt && ((p.MaxConversations > 0 && kept >= p.MaxConversations) ||
+			(p.MaxAge > 0 && active && last.Before(cutoff)))
+		if !prune {
+			kept++
+			continue
+		}
+		msgs, err := q.GetMessagesForConversation(ctx, c.ID)
+		if err != nil {
+			return res, err
+		}
+		res.Conversations = append(res.Conversations, c.ID)
+		res.Messages += int64(len(msgs))
+		for _, m := range msgs {
+			res.Bytes += int64(len(m.Content))
+		}
+		if dryRun {
+			continue
+		}
+		err = purgeConversation(ctx, q, c.ID)
+		if err != nil {
+			return res, err
+		}
+	}
+	if dryRun {
+		return res, nil
+	}
+	return res, tx.Commit()
+}
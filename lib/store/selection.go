@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/errs"
+)
+
+// The conversation a Store works on is held in the Store rather than in the
+// database, so that several gpterm processes can use the same database
+// without moving each other around. The selected column only records the
+// conversation that was selected last, which is where a new process starts.
+
+// revision identifies the state of a conversation as this process last saw
+// it. It changes when messages are added or the active branch moves.
+type revision struct {
+	conversation int64
+	leaf         int64
+	messages     int64
+}
+
+// ConversationChanged reports whether the current conversation was changed
+// by another process since this one last read or wrote it. It also reports
+// a change if the conversation was deleted elsewhere and another one had to
+// be selected.
+func (s *Store) ConversationChanged(ctx context.Context) (bool, error) {
+	c, err := s.ActiveConversation(ctx)
+	if err != nil {
+		return false, err
+	}
+	count, err := s.queries.CountMessagesForConversation(ctx, c.ID)
+	if err != nil {
+		return false, err
+	}
+	rev := revision{conversation: c.ID, leaf: c.LeafID.Int64, messages: count}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := s.seen != (revision{}) && s.seen != rev
+	s.seen = rev
+	return changed, nil
+}
+
+// current returns the conversation selected by this process. If another
+// process deleted or trashed it, the conversation selected last is used
+// instead, and a new one is created if there is none.
+func (s *Store) current(ctx context.Context, q *query.Queries) (query.Conversation, error) {
+	s.mu.Lock()
+	id := s.conversationID
+	s.mu.Unlock()
+	c, err := q.GetConversation(ctx, id)
+	if err == nil && !c.DeletedAt.Valid {
+		return c, nil
+	}
+	if !errs.IsDBNotFound(err) && err != nil {
+		return c, err
+	}
+	c, err = q.GetActiveConversation(ctx)
+	if errs.IsDBNotFound(err) {
+		c, err = createConversation(ctx, q)
+		if err == nil {
+			err = q.SetSelectedConversation(ctx, c.ID)
+		}
+	}
+	if err != nil {
+		return c, err
+	}
+	s.setConversation(c.ID)
+	return c, nil
+}
+
+// selectConversation records id as the conversation selected last, so that
+// the next process to start opens it. setConversation must be called once
+// the transaction has been committed.
+func selectConversation(ctx context.Context, q *query.Queries, id int64) error {
+	err := q.UnsetSelectedConversation(ctx)
+	if err != nil {
+		return err
+	}
+	return q.SetSelectedConversation(ctx, id)
+}
+
+// setConversation switches this process to the conversation with the id.
+func (s *Store) setConversation(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conversationID != id {
+		s.conversationID = id
+		s.seen = revision{}
+	}
+}
+
+// observe records the state of the current conversation after it was read
+// or written by this process.
+func (s *Store) observe(rev revision) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rev.conversation == s.conversationID {
+		s.seen = rev
+	}
+}
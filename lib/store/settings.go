@@ -0,0 +1,262 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/errs"
+	"github.com/collinvandyck/gpterm/lib/persona"
+)
+
+// Each conversation keeps its own client config, context size and persona.
+// New conversations start out with the defaults, which follow the settings
+// chosen last. Conversations that were created before settings were kept per
+// conversation have none of their own and use the defaults.
+
+const (
+	ConfigClientConfig = "client-config"
+	ConfigPersona      = "persona"
+	ConfigTheme        = "theme"
+	ConfigNotify       = "notify"               // how to notify that a response finished
+	ConfigNotifyAfter  = "notify.after-seconds" // how long a response takes before it is notified
+	DefaultNotifyAfter = 30
+)
+
+// Settings are the settings a conversation is continued with.
+type Settings struct {
+	ClientConfig query.ClientConfig // with the context size of the conversation
+	Persona      string
+}
+
+// ConversationSettings returns the settings of the conversation with the id.
+func (s *Store) ConversationSettings(ctx context.Context, id int64) (Settings, error) {
+	c, err := s.queries.GetConversation(ctx, id)
+	if errs.IsDBNotFound(err) {
+		return Settings{}, ErrConversationNotFound
+	}
+	if err != nil {
+		return Settings{}, err
+	}
+	cc, err := conversationClientConfig(ctx, s.queries, c)
+	if err != nil {
+		return Settings{}, err
+	}
+	name, err := s.conversationPersona(ctx, c)
+	if err != nil {
+		return Settings{}, err
+	}
+	return Settings{ClientConfig: cc, Persona: name}, nil
+}
+
+// GetClientConfig returns the client config of the current conversation,
+// with the conversation's context size.
+func (s *Store) GetClientConfig(ctx context.Context) (query.ClientConfig, error) {
+	c, err := s.ActiveConversation(ctx)
+	if err != nil {
+		return query.ClientConfig{}, err
+	}
+	return conversationClientConfig(ctx, s.queries, c)
+}
+
+// GetClientConfigs returns every client config, ordered by name.
+func (s *Store) GetClientConfigs(ctx context.Context) ([]query.ClientConfig, error) {
+	return s.queries.GetClientConfigs(ctx)
+}
+
+// UpdateClientConfig changes the context size of the current conversation.
+// It is also remembered as the context size for new conversations that use
+// the same client config.
+func (s *Store) UpdateClientConfig(ctx context.Context, messageContext int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := s.queries.WithTx(tx)
+	c, err := s.current(ctx, q)
+	if err != nil {
+		return err
+	}
+	cc, err := conversationClientConfig(ctx, q, c)
+	if err != nil {
+		return err
+	}
+	err = q.SetConversationClientConfig(ctx, query.SetConversationClientConfigParams{
+		ClientConfig:   sql.NullString{String: cc.Name, Valid: true},
+		MessageContext: sql.NullInt64{Int64: messageContext, Valid: true},
+		ID:             c.ID,
+	})
+	if err != nil {
+		return err
+	}
+	err = q.SetClientConfigMessageContext(ctx, query.SetClientConfigMessageContextParams{
+		MessageContext: messageContext,
+		Name:           cc.Name,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CycleClientConfig switches the current conversation to the client config
+// that follows its current one in name order, wrapping around at the end.
+// The new client config becomes the default for new conversations.
+func (s *Store) CycleClientConfig(ctx context.Context) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := s.queries.WithTx(tx)
+	c, err := s.current(ctx, q)
+	if err != nil {
+		return err
+	}
+	cc, err := conversationClientConfig(ctx, q, c)
+	if err != nil {
+		return err
+	}
+	configs, err := q.GetClientConfigs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(configs) == 0 {
+		return sql.ErrNoRows
+	}
+	next := configs[0]
+	for _, config := range configs {
+		if config.Name > cc.Name {
+			next = config
+			break
+		}
+	}
+	err = setConversationClientConfig(ctx, q, c.ID, next)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetConversationClientConfig switches the conversation with the id to the
+// named client config, which becomes the default for new conversations.
+func (s *Store) SetConversationClientConfig(ctx context.Context, id int64, name string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := s.queries.WithTx(tx)
+	if _, err := q.GetConversation(ctx, id); errs.IsDBNotFound(err) {
+		return ErrConversationNotFound
+	}
+	cc, err := q.GetClientConfigByName(ctx, name)
+	if errs.IsDBNotFound(err) {
+		return fmt.Errorf("no client config named %q", name)
+	}
+	if err != nil {
+		return err
+	}
+	err = setConversationClientConfig(ctx, q, id, cc)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetConversationMessageContext changes the context size of the
+// conversation with the id, without changing the defaults.
+func (s *Store) SetConversationMessageContext(ctx context.Context, id int64, messageContext int64) error {
+	if messageContext < 1 {
+		return fmt.Errorf("invalid context size: %d", messageContext)
+	}
+	if _, err := s.queries.GetConversation(ctx, id); errs.IsDBNotFound(err) {
+		return ErrConversationNotFound
+	}
+	return s.queries.SetConversationMessageContext(ctx, query.SetConversationMessageContextParams{
+		MessageContext: sql.NullInt64{Int64: messageContext, Valid: true},
+		ID:             id,
+	})
+}
+
+// GetPersona returns the name of the persona of the current conversation.
+func (s *Store) GetPersona(ctx context.Context) (string, error) {
+	c, err := s.ActiveConversation(ctx)
+	if err != nil {
+		return "", err
+	}
+	return s.conversationPersona(ctx, c)
+}
+
+// conversationPersona returns the persona of c, falling back to the default
+// if c has none.
+func (s *Store) conversationPersona(ctx context.Context, c query.Conversation) (string, error) {
+	if c.Persona.Valid {
+		return c.Persona.String, nil
+	}
+	return s.GetConfigString(ctx, ConfigPersona, persona.Default)
+}
+
+// SetConversationPersona sets the persona of the conversation with the id,
+// and makes it the default for new conversations.
+func (s *Store) SetConversationPersona(ctx context.Context, id int64, name string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := s.queries.WithTx(tx)
+	if _, err := q.GetConversation(ctx, id); errs.IsDBNotFound(err) {
+		return ErrConversationNotFound
+	}
+	err = q.SetConversationPersona(ctx, query.SetConversationPersonaParams{
+		Persona: sql.NullString{String: name, Valid: true},
+		ID:      id,
+	})
+	if err != nil {
+		return err
+	}
+	err = q.SetConfigValue(ctx, query.SetConfigValueParams{Name: ConfigPersona, Value: name})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// conversationClientConfig returns the client config of c, falling back to
+// the default if c has none or its client config no longer exists.
+func conversationClientConfig(ctx context.Context, q *query.Queries, c query.Conversation) (query.ClientConfig, error) {
+	var (
+		cc  query.ClientConfig
+		err = sql.ErrNoRows
+	)
+	if c.ClientConfig.Valid {
+		cc, err = q.GetClientConfigByName(ctx, c.ClientConfig.String)
+	}
+	if errs.IsDBNotFound(err) {
+		cc, err = q.GetClientConfig(ctx)
+	}
+	if err != nil {
+		return cc, err
+	}
+	if c.MessageContext.Valid {
+		cc.MessageContext = c.MessageContext.Int64
+	}
+	return cc, nil
+}
+
+// setConversationClientConfig switches the conversation with the id to cc,
+// using the context size last used with it, and makes cc the default.
+func setConversationClientConfig(ctx context.Context, q *query.Queries, id int64, cc query.ClientConfig) error {
+	err := q.SetConversationClientConfig(ctx, query.SetConversationClientConfigParams{
+		ClientConfig:   sql.NullString{String: cc.Name, Valid: true},
+		MessageContext: sql.NullInt64{Int64: cc.MessageContext, Valid: true},
+		ID:             id,
+	})
+	if err != nil {
+		return err
+	}
+	return q.SetConfigValue(ctx, query.SetConfigValueParams{Name: ConfigClientConfig, Value: cc.Name})
+}
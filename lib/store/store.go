@@ -5,10 +5,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/collinvandyck/gpterm/db"
 	"github.com/collinvandyck/gpterm/db/query"
@@ -29,17 +32,9 @@ const (
 	CredentialGithubToken    = "github_token"
 )
 
-func DefaultStorePath() (string, error) {
-	hd, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
-	}
-	res := filepath.Join(hd, ".config", "gpterm")
-	return res, nil
-}
-
-func DefaultDBPath() (string, error) {
-	sp, err := DefaultStorePath()
+// ProfileDBPath returns the path of a profile's database.
+func ProfileDBPath(profile string) (string, error) {
+	sp, err := ProfileDir(profile)
 	if err != nil {
 		return "", err
 	}
@@ -49,9 +44,14 @@ func DefaultDBPath() (string, error) {
 
 type Store struct {
 	log.Logger
+	profile string
 	dir     string
 	db      *sql.DB
 	queries *query.Queries
+
+	mu             sync.Mutex
+	conversationID int64    // the conversation this process is working on
+	seen           revision // the conversation as this process last saw it
 }
 
 type StoreOpt func(*Store)
@@ -62,6 +62,16 @@ func StoreLog(log log.Logger) StoreOpt {
 	}
 }
 
+// StoreProfile opens the store of the named profile. An empty name leaves the
+// profile selected by GPTERM_PROFILE in place.
+func StoreProfile(name string) StoreOpt {
+	return func(s *Store) {
+		if name != "" {
+			s.profile = name
+		}
+	}
+}
+
 func StoreDir(path string) StoreOpt {
 	return func(s *Store) {
 		s.dir = path
@@ -70,13 +80,14 @@ func StoreDir(path string) StoreOpt {
 
 func New(opts ...StoreOpt) (*Store, error) {
 	store := &Store{
-		Logger: log.Discard,
+		Logger:  log.Discard,
+		profile: CurrentProfile(),
 	}
 	for _, o := range opts {
 		o(store)
 	}
 	if store.dir == "" {
-		dir, err := DefaultStorePath()
+		dir, err := ProfileDir(store.profile)
 		if err != nil {
 			return nil, err
 		}
@@ -89,16 +100,11 @@ func New(opts ...StoreOpt) (*Store, error) {
 	return store, nil
 }
 
-var ErrNoMoreConversations = errors.New("no more conversations")
-
-func (s *Store) GetClientConfig(ctx context.Context) (query.ClientConfig, error) {
-	return s.queries.GetClientConfig(ctx)
-}
-
-func (s *Store) UpdateClientConfig(ctx context.Context, messageContext int64) error {
-	_, err := s.queries.UpdateClientConfig(ctx, messageContext)
-	return err
-}
+var (
+	ErrNoMoreConversations   = errors.New("no more conversations")
+	ErrConversationProtected = errors.New("conversation is protected")
+	ErrConversationNotFound  = errors.New("conversation not found")
+)
 
 func (s *Store) GetConfig(ctx context.Context) (Config, error) {
 	return s.queries.GetConfig(ctx)
@@ -126,72 +132,100 @@ func (s *Store) GetConfigInt(ctx context.Context, name string, defaultValue int)
 	}
 }
 
-func (s *Store) DropConversation(ctx context.Context) error {
+func (s *Store) SetConfigString(ctx context.Context, name string, value string) error {
+	return s.queries.SetConfigValue(ctx, query.SetConfigValueParams{
+		Name:  name,
+		Value: value,
+	})
+}
+
+func (s *Store) GetConfigString(ctx context.Context, name string, defaultValue string) (string, error) {
+	val, err := s.queries.GetConfigValue(ctx, name)
+	switch {
+	case errs.IsDBNotFound(err):
+		return defaultValue, nil
+	case err != nil:
+		return "", err
+	default:
+		return val, nil
+	}
+}
+
+func (s *Store) DeleteConfig(ctx context.Context, name string) error {
+	return s.queries.DeleteConfigValue(ctx, name)
+}
+
+// DropConversation moves the current conversation to the trash and selects
+// the next conversation, or the previous one if there is no next. A fresh
+// conversation is created if nothing else is left. Empty conversations are
+// deleted outright since there is nothing to restore. The id of the dropped
+// conversation is returned.
+func (s *Store) DropConversation(ctx context.Context) (int64, error) {
 	tx, err := s.db.Begin()
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer tx.Rollback()
 	q := s.queries.WithTx(tx)
-	convos, err := q.ConversationCount(ctx)
-	if err != nil {
-		return err
-	}
-	err = q.DeleteMessagesForCurrentConversation(ctx)
+	current, err := s.current(ctx, q)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	if convos == 1 {
-		// nothing left to do
-		return nil
+	if current.Protected != 0 {
+		return 0, ErrConversationProtected
 	}
-	current, err := q.GetActiveConversation(ctx)
+	count, err := q.CountMessagesForConversation(ctx, current.ID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	// we need to switch to the next conversation if it exists,
 	// otherwise switch to the previous conversation.
-	next, err := q.NextConversation(ctx)
+	next, err := q.NextConversation(ctx, current.ID)
 	switch {
 	case err == nil:
-		err = q.UnsetSelectedConversation(ctx)
-		if err != nil {
-			return err
-		}
-		err = q.SetSelectedConversation(ctx, next.ID)
-		if err != nil {
-			return err
-		}
-		_, err = q.DeleteConversation(ctx, current.ID)
-		if err != nil {
-			return err
-		}
-		return tx.Commit()
 	case errs.IsDBNotFound(err):
+		next, err = q.PreviousConversation(ctx, current.ID)
+		switch {
+		case err == nil:
+		case errs.IsDBNotFound(err):
+			if count == 0 {
+				// dropping the only conversation when it is already
+				// empty would just replace it with another empty one.
+				return 0, ErrNoMoreConversations
+			}
+			next, err = createConversation(ctx, q)
+			if err != nil {
+				return 0, err
+			}
+		default:
+			return 0, err
+		}
 	default:
-		return err
+		return 0, err
 	}
-	// check if there is a previous conversation
-	prev, err := q.PreviousConversation(ctx)
+	err = selectConversation(ctx, q, next.ID)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	err = q.UnsetSelectedConversation(ctx)
-	if err != nil {
-		return err
+	if count == 0 {
+		err = purgeConversation(ctx, q, current.ID)
+	} else {
+		err = q.TrashConversation(ctx, current.ID)
 	}
-	err = q.SetSelectedConversation(ctx, prev.ID)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	_, err = q.DeleteConversation(ctx, current.ID)
-	if err != nil {
-		return err
+	if err := tx.Commit(); err != nil {
+		return 0, err
 	}
-	return tx.Commit()
+	s.setConversation(next.ID)
+	return current.ID, nil
 }
 
-func (s *Store) NextConversation(ctx context.Context) error {
+// NextConversation selects the next conversation. If tag is not empty, only
+// conversations with that tag are considered. A new conversation is created,
+// and given the tag, when there is no next conversation.
+func (s *Store) NextConversation(ctx context.Context, tag string) error {
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
@@ -199,49 +233,56 @@ func (s *Store) NextConversation(ctx context.Context) error {
 	defer tx.Rollback()
 	queryTX := s.queries.WithTx(tx)
 
-	c, err := queryTX.NextConversation(ctx)
+	current, err := s.current(ctx, queryTX)
+	if err != nil {
+		return err
+	}
+	var c query.Conversation
+	if tag == "" {
+		c, err = queryTX.NextConversation(ctx, current.ID)
+	} else {
+		c, err = queryTX.NextConversationWithTag(ctx, query.NextConversationWithTagParams{
+			ID:   current.ID,
+			Name: tag,
+		})
+	}
 	switch {
 	case err == nil:
-		err = queryTX.UnsetSelectedConversation(ctx)
-		if err != nil {
-			return err
-		}
-		err = queryTX.SetSelectedConversation(ctx, c.ID)
-		if err != nil {
-			return err
-		}
-		return tx.Commit()
 	case errs.IsDBNotFound(err):
-		c, err = queryTX.GetActiveConversation(ctx)
-		if err != nil {
-			return err
-		}
-		count, err := queryTX.CountMessagesForConversation(ctx, c.ID)
+		count, err := queryTX.CountMessagesForConversation(ctx, current.ID)
 		if err != nil {
 			return err
 		}
 		if count == 0 {
 			return ErrNoMoreConversations
 		}
-		c, err = queryTX.CreateConversation(ctx)
+		c, err = createConversation(ctx, queryTX)
 		if err != nil {
 			return err
 		}
-		err = queryTX.UnsetSelectedConversation(ctx)
-		if err != nil {
-			return err
+		if tag != "" {
+			err = tagConversation(ctx, queryTX, c.ID, tag)
+			if err != nil {
+				return err
+			}
 		}
-		err = queryTX.SetSelectedConversation(ctx, c.ID)
-		if err != nil {
-			return err
-		}
-		return tx.Commit()
 	default:
 		return err
 	}
+	err = selectConversation(ctx, queryTX, c.ID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.setConversation(c.ID)
+	return nil
 }
 
-func (s *Store) PreviousConversation(ctx context.Context) error {
+// PreviousConversation selects the previous conversation. If tag is not
+// empty, only conversations with that tag are considered.
+func (s *Store) PreviousConversation(ctx context.Context, tag string) error {
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
@@ -250,23 +291,35 @@ func (s *Store) PreviousConversation(ctx context.Context) error {
 	defer tx.Rollback()
 	queryTX := s.queries.WithTx(tx)
 
-	c, err := queryTX.PreviousConversation(ctx)
+	current, err := s.current(ctx, queryTX)
+	if err != nil {
+		return err
+	}
+	var c query.Conversation
+	if tag == "" {
+		c, err = queryTX.PreviousConversation(ctx, current.ID)
+	} else {
+		c, err = queryTX.PreviousConversationWithTag(ctx, query.PreviousConversationWithTagParams{
+			ID:   current.ID,
+			Name: tag,
+		})
+	}
 	switch {
 	case err == nil:
-		err = queryTX.UnsetSelectedConversation(ctx)
-		if err != nil {
-			return err
-		}
-		err = queryTX.SetSelectedConversation(ctx, c.ID)
-		if err != nil {
-			return err
-		}
-		return tx.Commit()
 	case errs.IsDBNotFound(err):
 		return ErrNoMoreConversations
 	default:
 		return err
 	}
+	err = selectConversation(ctx, queryTX, c.ID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.setConversation(c.ID)
+	return nil
 }
 
 func (s *Store) GetTotalUsage(ctx context.Context) (res query.Usage, err error) {
@@ -290,32 +343,97 @@ func (s *Store) GetTotalUsage(ctx context.Context) (res query.Usage, err error)
 	return
 }
 
-func (s *Store) CycleClientConfig(ctx context.Context) error {
-	_, err := s.queries.CycleClientConfig(ctx)
-	return err
+// GetUsageSince returns the usage recorded since t, oldest first.
+func (s *Store) GetUsageSince(ctx context.Context, t time.Time) ([]query.Usage, error) {
+	return s.queries.GetUsageSince(ctx, t.UTC())
 }
 
 func (s *Store) GetPreviousMessageForRole(ctx context.Context, role string, offset int) (query.Message, error) {
 	if offset <= 0 {
 		return query.Message{}, errors.New("bad offset")
 	}
+	convo, err := s.ActiveConversation(ctx)
+	if err != nil {
+		return query.Message{}, err
+	}
 	return s.queries.GetPreviousMessageForRole(ctx, query.GetPreviousMessageForRoleParams{
-		Role:   role,
-		Offset: int64(offset - 1),
+		Role:           role,
+		ConversationID: convo.ID,
+		Offset:         int64(offset - 1),
 	})
 }
 
+// GetLastMessages returns up to count messages from the end of the active
+// branch of the current conversation.
 func (s *Store) GetLastMessages(ctx context.Context, count int) ([]query.Message, error) {
-	return s.queries.GetLatestMessages(ctx, int64(count))
+	return lastMessages(ctx, s, count)
+}
+
+// GetThread returns the full message tree for the current conversation.
+func (s *Store) GetThread(ctx context.Context) (Thread, error) {
+	convo, err := s.ActiveConversation(ctx)
+	if err != nil {
+		return Thread{}, err
+	}
+	thread, err := s.getThread(ctx, convo)
+	if err != nil {
+		return Thread{}, err
+	}
+	s.observe(revision{
+		conversation: convo.ID,
+		leaf:         convo.LeafID.Int64,
+		messages:     int64(len(thread.Messages)),
+	})
+	return thread, nil
+}
+
+// GetConversationThread returns the full message tree for the specified
+// conversation.
+func (s *Store) GetConversationThread(ctx context.Context, id int64) (Thread, error) {
+	convo, err := s.getConversation(ctx, id)
+	if err != nil {
+		return Thread{}, err
+	}
+	return s.getThread(ctx, convo)
+}
+
+func (s *Store) getThread(ctx context.Context, convo query.Conversation) (Thread, error) {
+	msgs, err := s.queries.GetMessagesForConversation(ctx, convo.ID)
+	if err != nil {
+		return Thread{}, err
+	}
+	return Thread{Messages: msgs, Leaf: convo.LeafID}, nil
+}
+
+// SwitchBranch makes the branch containing the specified message active. The
+// active leaf becomes the newest descendant of that message.
+func (s *Store) SwitchBranch(ctx context.Context, messageID int64) error {
+	thread, err := s.GetThread(ctx)
+	if err != nil {
+		return err
+	}
+	msg, ok := thread.Get(messageID)
+	if !ok {
+		return fmt.Errorf("message %d not found in conversation", messageID)
+	}
+	return s.queries.SetConversationLeaf(ctx, query.SetConversationLeafParams{
+		LeafID: sql.NullInt64{Int64: thread.LatestLeaf(msg.ID), Valid: true},
+		ID:     msg.ConversationID,
+	})
 }
 
 func (s *Store) SaveRequest(ctx context.Context, req openai.ChatCompletionRequest) error {
+	return s.SaveBranchRequest(ctx, req, 0)
+}
+
+// SaveBranchRequest saves the prompt of req as a sibling of the message with
+// the id, an earlier prompt that it replaces, and makes it the active leaf.
+// The original branch is left intact. An id of 0 saves the prompt after the
+// active leaf, as SaveRequest does.
+func (s *Store) SaveBranchRequest(ctx context.Context, req openai.ChatCompletionRequest, messageID int64) error {
 	if len(req.Messages) > 1 {
 		m := req.Messages[len(req.Messages)-1]
-		err := s.queries.InsertMessage(ctx, query.InsertMessageParams{
-			Role:    m.Role,
-			Content: strings.TrimSpace(m.Content),
-		})
+		err := s.insertMessage(ctx, m.Role, strings.TrimSpace(m.Content), MessageMeta{}, messageID)
 		if err != nil {
 			return err
 		}
@@ -323,20 +441,20 @@ func (s *Store) SaveRequest(ctx context.Context, req openai.ChatCompletionReques
 	return nil
 }
 
-func (s *Store) SaveStreamResults(ctx context.Context, text string, usage openai.Usage, failure error) error {
-	err := s.queries.InsertMessage(ctx, query.InsertMessageParams{
-		Role:    "assistant",
-		Content: strings.TrimSpace(text),
-	})
+// SaveStreamResults saves a streamed response along with the metadata that
+// was gathered while it was streamed.
+func (s *Store) SaveStreamResults(ctx context.Context, text string, meta MessageMeta) error {
+	err := s.insertMessage(ctx, "assistant", strings.TrimSpace(text), meta, 0)
 	if err != nil {
 		return err
 	}
 	// only record usage if it was reported.
-	if usage.PromptTokens+usage.CompletionTokens+usage.TotalTokens > 0 {
+	if meta.PromptTokens+meta.CompletionTokens > 0 {
 		err = s.queries.InsertUsage(ctx, query.InsertUsageParams{
-			PromptTokens:     int64(usage.PromptTokens),
-			CompletionTokens: int64(usage.CompletionTokens),
-			TotalTokens:      int64(usage.TotalTokens),
+			PromptTokens:     int64(meta.PromptTokens),
+			CompletionTokens: int64(meta.CompletionTokens),
+			TotalTokens:      int64(meta.PromptTokens + meta.CompletionTokens),
+			Model:            nullString(meta.Model),
 		})
 		if err != nil {
 			return err
@@ -354,10 +472,13 @@ func (s *Store) SaveRequestResponse(ctx context.Context, req openai.ChatCompleti
 	// save all responses
 	for _, choice := range resp.Choices {
 		m := choice.Message
-		err := s.queries.InsertMessage(ctx, query.InsertMessageParams{
-			Role:    m.Role,
-			Content: strings.TrimSpace(m.Content),
-		})
+		meta := MessageMeta{
+			Model:            resp.Model,
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			FinishReason:     string(choice.FinishReason),
+		}
+		err := s.insertMessage(ctx, m.Role, strings.TrimSpace(m.Content), meta, 0)
 		if err != nil {
 			return err
 		}
@@ -367,6 +488,7 @@ func (s *Store) SaveRequestResponse(ctx context.Context, req openai.ChatCompleti
 		PromptTokens:     int64(resp.Usage.PromptTokens),
 		CompletionTokens: int64(resp.Usage.CompletionTokens),
 		TotalTokens:      int64(resp.Usage.TotalTokens),
+		Model:            nullString(resp.Model),
 	})
 	if err != nil {
 		return err
@@ -374,6 +496,56 @@ func (s *Store) SaveRequestResponse(ctx context.Context, req openai.ChatCompleti
 	return nil
 }
 
+// insertMessage adds a message after the active leaf and makes it the active
+// leaf. If branchFrom isn't 0, the message is added as a sibling of that
+// message instead.
+func (s *Store) insertMessage(ctx context.Context, role string, content string, meta MessageMeta, branchFrom int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := s.queries.WithTx(tx)
+	convo, err := s.current(ctx, q)
+	if err != nil {
+		return err
+	}
+	params := meta.insertParams(role, content)
+	params.ConversationID = convo.ID
+	params.ParentID = convo.LeafID
+	if branchFrom != 0 {
+		sibling, err := q.GetMessage(ctx, branchFrom)
+		if err != nil && !errs.IsDBNotFound(err) {
+			return err
+		}
+		if err != nil || sibling.ConversationID != convo.ID {
+			return fmt.Errorf("message %d not found in conversation", branchFrom)
+		}
+		params.ParentID = sibling.ParentID
+	}
+	params.Uuid = nullString(newUUID())
+	msg, err := q.InsertMessage(ctx, params)
+	if err != nil {
+		return err
+	}
+	err = q.SetConversationLeaf(ctx, query.SetConversationLeafParams{
+		LeafID: sql.NullInt64{Int64: msg.ID, Valid: true},
+		ID:     msg.ConversationID,
+	})
+	if err != nil {
+		return err
+	}
+	count, err := q.CountMessagesForConversation(ctx, convo.ID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.observe(revision{conversation: convo.ID, leaf: msg.ID, messages: count})
+	return nil
+}
+
 func (s *Store) GetCredential(ctx context.Context, name string) (string, error) {
 	res, err := s.queries.GetCredential(ctx, name)
 	switch {
@@ -391,6 +563,14 @@ func (s *Store) SetCredential(ctx context.Context, name string, value string) er
 	})
 }
 
+func (s *Store) DeleteCredential(ctx context.Context, name string) error {
+	return s.queries.DeleteCredential(ctx, name)
+}
+
+func (s *Store) ListCredentials(ctx context.Context) ([]string, error) {
+	return s.queries.GetCredentialNames(ctx)
+}
+
 func (s *Store) Close() error {
 	if s.db == nil {
 		return nil
@@ -409,6 +589,9 @@ func (s *Store) init() error {
 		return fmt.Errorf("initDB: %w", err)
 	}
 	s.queries = query.New(s.db)
+	if err := s.initConversation(context.Background()); err != nil {
+		return fmt.Errorf("initConversation: %w", err)
+	}
 	return nil
 }
 
@@ -417,11 +600,36 @@ func (s *Store) migrate() error {
 	if err != nil {
 		return err
 	}
-	path := "sqlite3://" + s.DBPath()
+	path := "sqlite3://" + s.DBPath() + "?_busy_timeout=5000"
 	mg, err := migrate.NewWithSourceInstance("iofs", sourceDriver, path)
 	if err != nil {
 		return err
 	}
+	defer mg.Close()
+	version, _, err := mg.Version()
+	switch {
+	case errors.Is(err, migrate.ErrNilVersion):
+		// a brand new database, there is nothing worth backing up.
+	case err != nil:
+		return fmt.Errorf("version: %w", err)
+	default:
+		_, err = sourceDriver.Next(version)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			// already up to date.
+		case err != nil:
+			return fmt.Errorf("next: %w", err)
+		default:
+			backup, err := s.newBackupPath(fmt.Sprintf("pre-migrate-%d", version))
+			if err != nil {
+				return err
+			}
+			if _, err := s.Backup(context.Background(), backup); err != nil {
+				return fmt.Errorf("safety backup: %w", err)
+			}
+			s.Log("took safety backup before migrating", "path", backup)
+		}
+	}
 	err = mg.Up()
 	switch {
 	case errors.Is(err, migrate.ErrNoChange):
@@ -431,8 +639,15 @@ func (s *Store) migrate() error {
 	return nil
 }
 
+// dsnParams let several gpterm processes share the database. WAL lets
+// readers carry on while another process writes, the busy timeout waits for
+// a write lock instead of failing straight away, and immediate transactions
+// take the write lock up front so that a transaction that reads before it
+// writes cannot deadlock with another one.
+const dsnParams = "_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate"
+
 func (s *Store) initDB() error {
-	db, err := sql.Open("sqlite3", s.DBPath())
+	db, err := sql.Open("sqlite3", s.DBPath()+"?"+dsnParams)
 	if err != nil {
 		return err
 	}
@@ -440,6 +655,32 @@ func (s *Store) initDB() error {
 	return nil
 }
 
+// initConversation starts this process on the conversation that was
+// selected last.
+func (s *Store) initConversation(ctx context.Context) error {
+	c, err := s.queries.GetActiveConversation(ctx)
+	if errs.IsDBNotFound(err) {
+		c, err = createConversation(ctx, s.queries)
+		if err == nil {
+			err = s.queries.SetSelectedConversation(ctx, c.ID)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	s.conversationID = c.ID
+	return nil
+}
+
+// Profile returns the name of the profile the store belongs to.
+func (s *Store) Profile() string {
+	return s.profile
+}
+
+func (s *Store) Dir() string {
+	return s.dir
+}
+
 func (s *Store) DBPath() string {
 	return filepath.Join(s.dir, DBName)
 }
@@ -448,7 +689,7 @@ func ensureDir(dir string) error {
 	info, err := os.Stat(dir)
 	switch {
 	case os.IsNotExist(err):
-		err := os.Mkdir(dir, 0755)
+		err := os.MkdirAll(dir, 0755)
 		if err != nil {
 			return err
 		}
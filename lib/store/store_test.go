@@ -0,0 +1,257 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStore returns a store in dir that is closed when the test ends.
+func newTestStore(t *testing.T, dir string) *Store {
+	t.Helper()
+	str, err := New(StoreDir(dir))
+	require.NoError(t, err)
+	t.Cleanup(func() { str.Close() })
+	return str
+}
+
+// ask saves a request for prompt in the current conversation of r.
+func ask(t *testing.T, r Repository, prompt string) {
+	t.Helper()
+	err := r.SaveRequest(context.Background(), openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "context"},
+			{Role: openai.ChatMessageRoleUser, Content: prompt},
+		},
+	})
+	require.NoError(t, err)
+}
+
+// TestStoreInstances checks that two stores opened on the same directory, as
+// two gpterm processes would, do not get in each other's way.
+func TestStoreInstances(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	open := func() *Store {
+		return newTestStore(t, dir)
+	}
+	active := func(str *Store) int64 {
+		t.Helper()
+		c, err := str.ActiveConversation(ctx)
+		require.NoError(t, err)
+		return c.ID
+	}
+	changed := func(str *Store) bool {
+		t.Helper()
+		res, err := str.ConversationChanged(ctx)
+		require.NoError(t, err)
+		return res
+	}
+
+	a, b := open(), open()
+	ask(t, a, "first")
+	require.Equal(t, active(a), active(b))
+
+	// moving one instance leaves the other where it was
+	require.NoError(t, b.NextConversation(ctx, ""))
+	require.NotEqual(t, active(a), active(b))
+	ask(t, a, "second")
+	ask(t, b, "elsewhere")
+
+	msgs, err := a.GetLastMessages(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	require.Equal(t, "second", msgs[1].Content)
+	msgs, err = b.GetLastMessages(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, "elsewhere", msgs[0].Content)
+
+	// the last selection is where a new instance starts
+	require.Equal(t, active(b), active(open()))
+
+	// changes are only reported when made by the other instance
+	require.NoError(t, b.PreviousConversation(ctx, ""))
+	require.False(t, changed(a))
+	require.False(t, changed(b))
+	ask(t, a, "third")
+	require.False(t, changed(a))
+	require.True(t, changed(b))
+	require.False(t, changed(b))
+
+	// an instance whose conversation is trashed elsewhere follows the other
+	require.NoError(t, a.NextConversation(ctx, ""))
+	require.NoError(t, b.NextConversation(ctx, ""))
+	dropped, err := b.DropConversation(ctx)
+	require.NoError(t, err)
+	ask(t, a, "still here")
+	require.NotEqual(t, dropped, active(a))
+	require.Equal(t, active(b), active(a))
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	str := newTestStore(t, t.TempDir())
+	start := func(prompt string) int64 {
+		t.Helper()
+		ask(t, str, prompt)
+		c, err := str.ActiveConversation(ctx)
+		require.NoError(t, err)
+		return c.ID
+	}
+	next := func(prompt string) int64 {
+		t.Helper()
+		require.NoError(t, str.NewConversation(ctx))
+		return start(prompt)
+	}
+	backdate := func(id int64, days int) {
+		t.Helper()
+		_, err := str.db.ExecContext(ctx, "update message set timestamp = datetime('now', ?) where conversation_id = ?",
+			fmt.Sprintf("-%d days", days), id)
+		require.NoError(t, err)
+	}
+	exists := func(id int64) bool {
+		t.Helper()
+		_, err := str.GetConversation(ctx, id)
+		if errors.Is(err, ErrConversationNotFound) {
+			return false
+		}
+		require.NoError(t, err)
+		return true
+	}
+	prune := func(p RetentionPolicy, dryRun bool) PruneResult {
+		t.Helper()
+		res, err := str.Prune(ctx, p, dryRun)
+		require.NoError(t, err)
+		return res
+	}
+
+	// from least to most recently used
+	old := start("old")
+	require.NoError(t, str.SetConversationProtected(ctx, old, false))
+	pinned := next("pinned")
+	msgs, err := str.GetLastMessages(ctx, 1)
+	require.NoError(t, err)
+	require.NoError(t, str.SetMessagePinned(ctx, msgs[0].ID, true))
+	protected := next("protected")
+	require.NoError(t, str.SetConversationProtected(ctx, protected, true))
+	recent := next("recent")
+	current := next("current")
+	ask(t, str, "current again")
+
+	// nothing is pruned without a rule
+	require.Empty(t, prune(RetentionPolicy{KeepProtected: true, KeepPinned: true}, false).Conversations)
+
+	// only conversations whose last message is older than MaxAge are pruned,
+	// and never the selected one. a dry run reports what a real one deletes,
+	// and deletes nothing.
+	for _, id := range []int64{old, pinned, protected, current} {
+		backdate(id, 30)
+	}
+	backdate(recent, 3)
+	policy := RetentionPolicy{MaxAge: 7 * 24 * time.Hour, KeepProtected: true, KeepPinned: true}
+	res := prune(policy, true)
+	require.Equal(t, []int64{old}, res.Conversations)
+	require.EqualValues(t, 1, res.Messages)
+	require.EqualValues(t, len("old"), res.Bytes)
+	require.True(t, exists(old))
+	require.Equal(t, res, prune(policy, false))
+	require.False(t, exists(old))
+	require.True(t, exists(current))
+
+	// without the exemptions, protected and pinned conversations go too
+	policy.KeepPinned = false
+	require.Equal(t, []int64{pinned}, prune(policy, true).Conversations)
+	policy.KeepPinned, policy.KeepProtected = true, false
+	require.Equal(t, []int64{protected}, prune(policy, true).Conversations)
+	require.True(t, exists(pinned))
+	require.True(t, exists(protected))
+
+	// MaxConversations keeps the most recently used, which here is the only
+	// one that isn't exempt
+	policy = RetentionPolicy{MaxConversations: 1, KeepProtected: true, KeepPinned: true}
+	require.Empty(t, prune(policy, false).Conversations)
+	policy.KeepProtected, policy.KeepPinned = false, false
+	res = prune(policy, false)
+	require.ElementsMatch(t, []int64{protected, pinned}, res.Conversations)
+	require.EqualValues(t, 2, res.Messages)
+	require.False(t, exists(protected))
+	require.False(t, exists(pinned))
+	require.True(t, exists(recent))
+	require.True(t, exists(current))
+}
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	str := newTestStore(t, t.TempDir())
+	contents := func() []string {
+		t.Helper()
+		msgs, err := str.GetLastMessages(ctx, 10)
+		require.NoError(t, err)
+		var res []string
+		for _, m := range msgs {
+			res = append(res, m.Content)
+		}
+		return res
+	}
+	theme := func() string {
+		t.Helper()
+		res, err := str.GetConfigString(ctx, ConfigTheme, "")
+		require.NoError(t, err)
+		return res
+	}
+
+	ask(t, str, "original")
+	require.NoError(t, str.SetConfigString(ctx, ConfigTheme, "dark"))
+	backup, err := str.Backup(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, str.BackupDir(), filepath.Dir(backup))
+	_, err = str.Backup(ctx, backup)
+	require.ErrorContains(t, err, "already exists")
+
+	ask(t, str, "changed")
+	require.NoError(t, str.SetConfigString(ctx, ConfigTheme, "light"))
+	require.Equal(t, []string{"original", "changed"}, contents())
+
+	// restoring brings back the original rows, after backing up the ones
+	// it replaces
+	saved, err := str.Restore(ctx, backup)
+	require.NoError(t, err)
+	require.Contains(t, filepath.Base(saved), "pre-restore")
+	require.Equal(t, []string{"original"}, contents())
+	require.Equal(t, "dark", theme())
+	problems, err := str.Check(ctx)
+	require.NoError(t, err)
+	require.Empty(t, problems)
+	ask(t, str, "after restore")
+	require.Equal(t, []string{"original", "after restore"}, contents())
+
+	backups, err := str.ListBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	require.ElementsMatch(t, []string{backup, saved}, []string{backups[0].Path, backups[1].Path})
+
+	// which can be restored in turn
+	again, err := str.Restore(ctx, saved)
+	require.NoError(t, err)
+	require.NotEqual(t, saved, again)
+	require.Equal(t, []string{"original", "changed"}, contents())
+	require.Equal(t, "light", theme())
+
+	// a file that isn't a database is refused, and changes nothing
+	bad := filepath.Join(t.TempDir(), "bad.db")
+	require.NoError(t, os.WriteFile(bad, []byte("not a database"), 0o644))
+	_, err = str.Restore(ctx, bad)
+	require.Error(t, err)
+	require.Equal(t, []string{"original", "changed"}, contents())
+	backups, err = str.ListBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 3)
+}
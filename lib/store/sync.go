@@ -0,0 +1,696 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/collinvandyck/gpterm/db/query"
+)
+
+// Sync keeps the conversations in the store in step with a directory that is
+// shared with other machines, such as a synced folder or a git repository.
+// Each conversation is written to its own file named by its uuid, since
+// local ids differ from machine to machine.
+//
+// Conversations and messages are merged with the last writer winning. Each
+// row remembers when it was last synced, so that a change made on both sides
+// since then is reported as a conflict. When the content of a message is in
+// conflict, the losing version is kept as a sibling branch so that nothing is
+// lost. Conversations deleted on one machine are deleted on the others,
+// unless they were changed there in the meantime.
+
+const (
+	ConfigSyncDir = "sync.dir"
+
+	syncVersion = 1
+	syncSuffix  = ".json"
+	tmpSuffix   = ".tmp"
+)
+
+// SyncResult describes what a sync changed.
+type SyncResult struct {
+	Imported  int // conversations that were new to this store
+	Updated   int // conversations changed by the directory
+	Exported  int // files written to the directory
+	Deleted   int // conversations deleted because they were deleted elsewhere
+	Removed   int // files removed because the conversation was deleted here
+	Conflicts []SyncConflict
+}
+
+// SyncConflict is a conversation or message that was changed both here and
+// elsewhere since it was last synced.
+type SyncConflict struct {
+	ConversationID int64
+	MessageID      int64 // zero if the conversation itself was in conflict
+	KeptRemote     bool  // whether the other version won
+	CopyID         int64 // the message the losing version was kept as, if any
+}
+
+func (c SyncConflict) String() string {
+	kept := "this version"
+	if c.KeptRemote {
+		kept = "the synced version"
+	}
+	if c.MessageID == 0 {
+		return fmt.Sprintf("conversation %d: changed in both places, kept %s", c.ConversationID, kept)
+	}
+	res := fmt.Sprintf("conversation %d, message %d: changed in both places, kept %s", c.ConversationID, c.MessageID, kept)
+	if c.CopyID != 0 {
+		res += fmt.Sprintf(", the other is message %d", c.CopyID)
+	}
+	return res
+}
+
+// syncFile is the contents of the file a conversation is synced with.
+type syncFile struct {
+	Version   int       `json:"version"`
+	UUID      string    `json:"uuid"`
+	UpdatedAt time.Time `json:"updated_at"`
+	syncConversation
+	Leaf     string        `json:"leaf,omitempty"`
+	Messages []syncMessage `json:"messages"`
+}
+
+// syncConversation is the part of a conversation that is merged as a whole.
+type syncConversation struct {
+	Name           string     `json:"name,omitempty"`
+	Protected      bool       `json:"protected,omitempty"`
+	Archived       bool       `json:"archived,omitempty"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	Tags           []string   `json:"tags,omitempty"`
+	ClientConfig   string     `json:"client_config,omitempty"`
+	MessageContext int64      `json:"message_context,omitempty"`
+	Persona        string     `json:"persona,omitempty"`
+}
+
+func (c syncConversation) equal(o syncConversation) bool {
+	deleted := func(t *time.Time) time.Time {
+		if t == nil {
+			return time.Time{}
+		}
+		return *t
+	}
+	return c.Name == o.Name && c.Protected == o.Protected && c.Archived == o.Archived &&
+		deleted(c.DeletedAt).Equal(deleted(o.DeletedAt)) && slices.Equal(c.Tags, o.Tags) &&
+		c.ClientConfig == o.ClientConfig && c.MessageContext == o.MessageContext && c.Persona == o.Persona
+}
+
+type syncMessage struct {
+	UUID             string    `json:"uuid"`
+	Parent           string    `json:"parent,omitempty"`
+	Timestamp        time.Time `json:"timestamp"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	Role             string    `json:"role"`
+	Content          string    `json:"content"`
+	Pinned           bool      `json:"pinned,omitempty"`
+	Excluded         bool      `json:"excluded,omitempty"`
+	Deleted          bool      `json:"deleted,omitempty"`
+	Model            string    `json:"model,omitempty"`
+	ClientConfig     string    `json:"client_config,omitempty"`
+	TTFTMs           int64     `json:"ttft_ms,omitempty"`
+	DurationMs       int64     `json:"duration_ms,omitempty"`
+	PromptTokens     int64     `json:"prompt_tokens,omitempty"`
+	CompletionTokens int64     `json:"completion_tokens,omitempty"`
+	FinishReason     string    `json:"finish_reason,omitempty"`
+}
+
+// modified returns the last time anything in the file changed.
+func (f syncFile) modified() time.Time {
+	res := f.UpdatedAt
+	for _, m := range f.Messages {
+		if m.UpdatedAt.After(res) {
+			res = m.UpdatedAt
+		}
+	}
+	return res
+}
+
+// Sync merges the conversations in dir into the store, and then writes every
+// conversation back to dir.
+func (s *Store) Sync(ctx context.Context, dir string) (SyncResult, error) {
+	var res SyncResult
+	err := ensureDir(dir)
+	if err != nil {
+		return res, err
+	}
+	files, err := readSyncFiles(dir)
+	if err != nil {
+		return res, err
+	}
+	retention, err := s.TrashRetention(ctx)
+	if err != nil {
+		return res, err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+	q := s.queries.WithTx(tx)
+	// files are only changed once the merge is committed. new contents are
+	// written to temp files until then, so that a failure to write them
+	// still rolls the merge back.
+	var (
+		removals []string
+		written  []string
+	)
+	defer func() {
+		for _, path := range written {
+			os.Remove(path + tmpSuffix)
+		}
+	}()
+
+	tombstones, err := q.GetSyncTombstones(ctx)
+	if err != nil {
+		return res, err
+	}
+	deleted := map[string]time.Time{}
+	for _, t := range tombstones {
+		deleted[t.Uuid] = t.DeletedAt
+	}
+	convos, err := q.GetConversations(ctx)
+	if err != nil {
+		return res, err
+	}
+	local := map[string]query.Conversation{}
+	for _, c := range convos {
+		local[c.Uuid.String] = c
+	}
+
+	// merge the files into the store
+	uuids := make([]string, 0, len(files))
+	for uuid := range files {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+	for _, uuid := range uuids {
+		f := files[uuid]
+		c, ok := local[uuid]
+		switch {
+		case ok:
+			changed, conflicts, err := mergeSyncFile(ctx, q, c, f)
+			if err != nil {
+				return res, fmt.Errorf("merge %s: %w", syncPath(dir, uuid), err)
+			}
+			if changed {
+				res.Updated++
+			}
+			res.Conflicts = append(res.Conflicts, conflicts...)
+		case isTombstoned(deleted, f), isExpired(f, retention):
+			removals = append(removals, syncPath(dir, uuid))
+			res.Removed++
+		default:
+			c, err := q.CreateConversation(ctx, nullString(uuid))
+			if err != nil {
+				return res, err
+			}
+			// the file always wins over the defaults of a new conversation
+			c.UpdatedAt = sql.NullTime{}
+			_, _, err = mergeSyncFile(ctx, q, c, f)
+			if err != nil {
+				return res, fmt.Errorf("import %s: %w", syncPath(dir, uuid), err)
+			}
+			err = q.DeleteSyncTombstone(ctx, uuid)
+			if err != nil {
+				return res, err
+			}
+			res.Imported++
+		}
+	}
+
+	// conversations that were synced before but whose files are gone were
+	// deleted elsewhere.
+	for _, c := range convos {
+		if _, ok := files[c.Uuid.String]; ok || !c.SyncedAt.Valid {
+			continue
+		}
+		changed, err := changedSinceSync(ctx, q, c)
+		if err != nil {
+			return res, err
+		}
+		if changed {
+			continue
+		}
+		err = purgeConversation(ctx, q, c.ID)
+		if err != nil {
+			return res, err
+		}
+		res.Deleted++
+	}
+
+	// write everything back
+	convos, err = q.GetConversations(ctx)
+	if err != nil {
+		return res, err
+	}
+	for _, c := range convos {
+		f, err := newSyncFile(ctx, q, c)
+		if err != nil {
+			return res, err
+		}
+		if len(f.Messages) == 0 {
+			continue
+		}
+		path, err := writeSyncTemp(dir, f)
+		if err != nil {
+			return res, err
+		}
+		if path != "" {
+			written = append(written, path)
+			res.Exported++
+		}
+		err = q.MarkConversationSynced(ctx, c.ID)
+		if err != nil {
+			return res, err
+		}
+		err = q.MarkMessagesSynced(ctx, c.ID)
+		if err != nil {
+			return res, err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return res, err
+	}
+	for _, path := range removals {
+		err = os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return res, err
+		}
+	}
+	for _, path := range written {
+		err = os.Rename(path+tmpSuffix, path)
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// mergeSyncFile merges f into the conversation c. It reports whether c was
+// changed, and the conflicts that were found.
+func mergeSyncFile(ctx context.Context, q *query.Queries, c query.Conversation, f syncFile) (bool, []SyncConflict, error) {
+	var (
+		changed   bool
+		conflicts []SyncConflict
+	)
+
+	// the conversation itself
+	localTime, remoteTime := c.UpdatedAt.Time, f.UpdatedAt
+	if !localTime.Equal(remoteTime) {
+		mine, err := newSyncConversation(ctx, q, c)
+		if err != nil {
+			return false, nil, err
+		}
+		remoteNewer := remoteTime.After(localTime)
+		if inConflict(c.SyncedAt, localTime, remoteTime) && !mine.equal(f.syncConversation) {
+			conflicts = append(conflicts, SyncConflict{ConversationID: c.ID, KeptRemote: remoteNewer})
+		}
+		if remoteNewer {
+			err = applySyncConversation(ctx, q, c.ID, f)
+			if err != nil {
+				return false, nil, err
+			}
+			changed = !mine.equal(f.syncConversation)
+		}
+	}
+
+	// the messages
+	msgs, err := q.GetMessagesForConversation(ctx, c.ID)
+	if err != nil {
+		return false, nil, err
+	}
+	byUUID := map[string]query.Message{}
+	parents := map[int64]int64{}
+	for _, m := range msgs {
+		byUUID[m.Uuid.String] = m
+		parents[m.ID] = m.ParentID.Int64
+	}
+	var pending []syncMessage
+	for _, fm := range f.Messages {
+		lm, ok := byUUID[fm.UUID]
+		if !ok {
+			pending = append(pending, fm)
+			continue
+		}
+		localTime, remoteTime := messageUpdatedAt(lm), fm.UpdatedAt
+		if localTime.Equal(remoteTime) {
+			continue
+		}
+		remoteNewer := remoteTime.After(localTime)
+		differs := lm.Content != fm.Content || (lm.Pinned != 0) != fm.Pinned ||
+			(lm.Excluded != 0) != fm.Excluded || lm.DeletedAt.Valid != fm.Deleted
+		if remoteNewer {
+			var deletedAt sql.NullTime
+			if fm.Deleted {
+				deletedAt = sql.NullTime{Time: remoteTime, Valid: true}
+			}
+			err := q.SetSyncedMessage(ctx, query.SetSyncedMessageParams{
+				Content:   fm.Content,
+				Pinned:    boolInt(fm.Pinned),
+				Excluded:  boolInt(fm.Excluded),
+				DeletedAt: deletedAt,
+				UpdatedAt: sql.NullTime{Time: remoteTime, Valid: true},
+				ID:        lm.ID,
+			})
+			if err != nil {
+				return false, nil, err
+			}
+			changed = changed || differs
+		}
+		if !differs || !inConflict(lm.SyncedAt, localTime, remoteTime) {
+			continue
+		}
+		conflict := SyncConflict{ConversationID: c.ID, MessageID: lm.ID, KeptRemote: remoteNewer}
+		loser := messageToSync(lm, fm.Parent)
+		if !remoteNewer {
+			loser = fm
+		}
+		if lm.Content != fm.Content && !loser.Deleted {
+			// keep the version that lost next to the one that won
+			loser.UUID = newUUID()
+			loser.UpdatedAt = time.Now().UTC()
+			copied, err := importMessage(ctx, q, c.ID, lm.ParentID, loser)
+			if err != nil {
+				return false, nil, err
+			}
+			conflict.CopyID = copied.ID
+			parents[copied.ID] = copied.ParentID.Int64
+			changed = true
+		}
+		conflicts = append(conflicts, conflict)
+	}
+
+	// new messages are imported once their parents are
+	for len(pending) > 0 {
+		var next []syncMessage
+		for _, fm := range pending {
+			var parentID sql.NullInt64
+			if fm.Parent != "" {
+				parent, ok := byUUID[fm.Parent]
+				if !ok {
+					next = append(next, fm)
+					continue
+				}
+				parentID = sql.NullInt64{Int64: parent.ID, Valid: true}
+			}
+			m, err := importMessage(ctx, q, c.ID, parentID, fm)
+			if err != nil {
+				return false, nil, err
+			}
+			byUUID[fm.UUID] = m
+			parents[m.ID] = m.ParentID.Int64
+			changed = true
+		}
+		if len(next) == len(pending) {
+			return false, nil, fmt.Errorf("message %s has a missing parent %s", next[0].UUID, next[0].Parent)
+		}
+		pending = next
+	}
+
+	// follow the synced branch if it continues the one selected here
+	if leaf, ok := byUUID[f.Leaf]; ok && leaf.ID != c.LeafID.Int64 {
+		follow := !c.LeafID.Valid
+		for id := leaf.ID; id != 0 && !follow; id = parents[id] {
+			follow = id == c.LeafID.Int64
+		}
+		if follow {
+			err := q.SetConversationLeaf(ctx, query.SetConversationLeafParams{
+				LeafID: sql.NullInt64{Int64: leaf.ID, Valid: true},
+				ID:     c.ID,
+			})
+			if err != nil {
+				return false, nil, err
+			}
+			changed = true
+		}
+	}
+	return changed, conflicts, nil
+}
+
+// applySyncConversation replaces the conversation with the id with the one
+// in f.
+func applySyncConversation(ctx context.Context, q *query.Queries, id int64, f syncFile) error {
+	err := q.DeleteConversationTags(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, tag := range f.Tags {
+		err = tagConversation(ctx, q, id, tag)
+		if err != nil {
+			return err
+		}
+	}
+	err = q.DeleteUnusedTags(ctx)
+	if err != nil {
+		return err
+	}
+	var deletedAt sql.NullTime
+	if f.DeletedAt != nil {
+		deletedAt = sql.NullTime{Time: *f.DeletedAt, Valid: true}
+	}
+	return q.SetSyncedConversation(ctx, query.SetSyncedConversationParams{
+		Name:           nullString(f.Name),
+		Protected:      boolInt(f.Protected),
+		Archived:       boolInt(f.Archived),
+		DeletedAt:      deletedAt,
+		ClientConfig:   nullString(f.ClientConfig),
+		MessageContext: nullInt(f.MessageContext),
+		Persona:        nullString(f.Persona),
+		UpdatedAt:      sql.NullTime{Time: f.UpdatedAt, Valid: true},
+		ID:             id,
+	})
+}
+
+func importMessage(ctx context.Context, q *query.Queries, conversationID int64, parentID sql.NullInt64, m syncMessage) (query.Message, error) {
+	var deletedAt sql.NullTime
+	if m.Deleted {
+		deletedAt = sql.NullTime{Time: m.UpdatedAt, Valid: true}
+	}
+	return q.ImportMessage(ctx, query.ImportMessageParams{
+		Uuid:             nullString(m.UUID),
+		Timestamp:        m.Timestamp,
+		UpdatedAt:        sql.NullTime{Time: m.UpdatedAt, Valid: true},
+		Role:             m.Role,
+		Content:          m.Content,
+		Pinned:           boolInt(m.Pinned),
+		Excluded:         boolInt(m.Excluded),
+		DeletedAt:        deletedAt,
+		Model:            nullString(m.Model),
+		ClientConfig:     nullString(m.ClientConfig),
+		TtftMs:           nullInt(m.TTFTMs),
+		DurationMs:       nullInt(m.DurationMs),
+		PromptTokens:     nullInt(m.PromptTokens),
+		CompletionTokens: nullInt(m.CompletionTokens),
+		FinishReason:     nullString(m.FinishReason),
+		ConversationID:   conversationID,
+		ParentID:         parentID,
+	})
+}
+
+// newSyncFile returns the file the conversation c is synced with. Messages
+// are ordered so that parents come before their children, and siblings by
+// when they were written.
+func newSyncFile(ctx context.Context, q *query.Queries, c query.Conversation) (syncFile, error) {
+	conversation, err := newSyncConversation(ctx, q, c)
+	if err != nil {
+		return syncFile{}, err
+	}
+	f := syncFile{
+		Version:          syncVersion,
+		UUID:             c.Uuid.String,
+		UpdatedAt:        c.UpdatedAt.Time.UTC(),
+		syncConversation: conversation,
+		Messages:         []syncMessage{},
+	}
+	msgs, err := q.GetMessagesForConversation(ctx, c.ID)
+	if err != nil {
+		return syncFile{}, err
+	}
+	uuids := map[int64]string{}
+	children := map[int64][]syncMessage{}
+	for _, m := range msgs {
+		uuids[m.ID] = m.Uuid.String
+	}
+	for _, m := range msgs {
+		children[m.ParentID.Int64] = append(children[m.ParentID.Int64], messageToSync(m, uuids[m.ParentID.Int64]))
+		if m.ID == c.LeafID.Int64 {
+			f.Leaf = m.Uuid.String
+		}
+	}
+	ids := map[string]int64{}
+	for id, uuid := range uuids {
+		ids[uuid] = id
+	}
+	var walk func(parent int64)
+	walk = func(parent int64) {
+		siblings := children[parent]
+		sort.Slice(siblings, func(i, j int) bool {
+			if !siblings[i].Timestamp.Equal(siblings[j].Timestamp) {
+				return siblings[i].Timestamp.Before(siblings[j].Timestamp)
+			}
+			return siblings[i].UUID < siblings[j].UUID
+		})
+		for _, m := range siblings {
+			f.Messages = append(f.Messages, m)
+			walk(ids[m.UUID])
+		}
+	}
+	walk(0)
+	return f, nil
+}
+
+func newSyncConversation(ctx context.Context, q *query.Queries, c query.Conversation) (syncConversation, error) {
+	tags, err := q.GetConversationTags(ctx, c.ID)
+	if err != nil {
+		return syncConversation{}, err
+	}
+	res := syncConversation{
+		Name:           c.Name.String,
+		Protected:      c.Protected != 0,
+		Archived:       c.Archived != 0,
+		ClientConfig:   c.ClientConfig.String,
+		MessageContext: c.MessageContext.Int64,
+		Persona:        c.Persona.String,
+	}
+	for _, t := range tags {
+		res.Tags = append(res.Tags, t.Name)
+	}
+	if c.DeletedAt.Valid {
+		t := c.DeletedAt.Time.UTC()
+		res.DeletedAt = &t
+	}
+	return res, nil
+}
+
+func messageToSync(m query.Message, parent string) syncMessage {
+	return syncMessage{
+		UUID:             m.Uuid.String,
+		Parent:           parent,
+		Timestamp:        m.Timestamp.UTC(),
+		UpdatedAt:        messageUpdatedAt(m).UTC(),
+		Role:             m.Role,
+		Content:          m.Content,
+		Pinned:           m.Pinned != 0,
+		Excluded:         m.Excluded != 0,
+		Deleted:          m.DeletedAt.Valid,
+		Model:            m.Model.String,
+		ClientConfig:     m.ClientConfig.String,
+		TTFTMs:           m.TtftMs.Int64,
+		DurationMs:       m.DurationMs.Int64,
+		PromptTokens:     m.PromptTokens.Int64,
+		CompletionTokens: m.CompletionTokens.Int64,
+		FinishReason:     m.FinishReason.String,
+	}
+}
+
+// changedSinceSync reports whether c or any of its messages changed since
+// it was last synced.
+func changedSinceSync(ctx context.Context, q *query.Queries, c query.Conversation) (bool, error) {
+	if !c.UpdatedAt.Time.Equal(c.SyncedAt.Time) {
+		return true, nil
+	}
+	msgs, err := q.GetMessagesForConversation(ctx, c.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, m := range msgs {
+		if !m.SyncedAt.Valid || !messageUpdatedAt(m).Equal(m.SyncedAt.Time) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// messageUpdatedAt returns when m was last changed. Messages that were never
+// changed after they were written have no update time.
+func messageUpdatedAt(m query.Message) time.Time {
+	if m.UpdatedAt.Valid {
+		return m.UpdatedAt.Time
+	}
+	return m.Timestamp
+}
+
+// inConflict reports whether both versions of something changed since it
+// was synced.
+func inConflict(synced sql.NullTime, local, remote time.Time) bool {
+	return synced.Valid && !local.Equal(synced.Time) && !remote.Equal(synced.Time)
+}
+
+// isTombstoned reports whether f is of a conversation that was deleted here,
+// and has not changed since.
+func isTombstoned(deleted map[string]time.Time, f syncFile) bool {
+	at, ok := deleted[f.UUID]
+	return ok && !f.modified().After(at)
+}
+
+// isExpired reports whether f is of a conversation that has been in the
+// trash for longer than it is kept.
+func isExpired(f syncFile, retention time.Duration) bool {
+	return f.DeletedAt != nil && time.Since(*f.DeletedAt) > retention
+}
+
+func readSyncFiles(dir string) (map[string]syncFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]syncFile{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), syncSuffix) {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var f syncFile
+		err = json.Unmarshal(bs, &f)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		switch {
+		case f.Version > syncVersion:
+			return nil, fmt.Errorf("%s was written by a newer version of gpterm", path)
+		case f.UUID+syncSuffix != e.Name():
+			return nil, fmt.Errorf("%s does not hold conversation %s", path, f.UUID)
+		}
+		res[f.UUID] = f
+	}
+	return res, nil
+}
+
+// writeSyncTemp writes f to a temp file next to its file in dir, unless the
+// file already holds it. It returns the path the temp file is to be renamed
+// to, or the empty string if there is nothing to write.
+func writeSyncTemp(dir string, f syncFile) (string, error) {
+	bs, err := json.MarshalIndent(f, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	bs = append(bs, '\n')
+	path := syncPath(dir, f.UUID)
+	existing, err := os.ReadFile(path)
+	switch {
+	case err == nil && bytes.Equal(existing, bs):
+		return "", nil
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return "", err
+	}
+	return path, os.WriteFile(path+tmpSuffix, bs, 0o600)
+}
+
+func syncPath(dir string, uuid string) string {
+	return filepath.Join(dir, uuid+syncSuffix)
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	open := func() *Store {
+		return newTestStore(t, t.TempDir())
+	}
+	say := func(str *Store, prompt, response string) {
+		t.Helper()
+		ask(t, str, prompt)
+		require.NoError(t, str.SaveStreamResults(ctx, response, MessageMeta{Model: "gpt-4o"}))
+		// updates are ordered by time, so keep them apart
+		time.Sleep(5 * time.Millisecond)
+	}
+	sync := func(str *Store) SyncResult {
+		t.Helper()
+		res, err := str.Sync(ctx, dir)
+		require.NoError(t, err)
+		return res
+	}
+	thread := func(str *Store, uuid string) []string {
+		t.Helper()
+		c, err := str.queries.GetConversationByUUID(ctx, nullString(uuid))
+		require.NoError(t, err)
+		str.setConversation(c.ID)
+		msgs, err := str.GetLastMessages(ctx, 100)
+		require.NoError(t, err)
+		res := []string{}
+		for _, m := range msgs {
+			res = append(res, m.Content)
+		}
+		return res
+	}
+	active := func(str *Store) query.Conversation {
+		t.Helper()
+		c, err := str.ActiveConversation(ctx)
+		require.NoError(t, err)
+		return c
+	}
+
+	laptop, devbox := open(), open()
+	say(laptop, "hello", "hi")
+	convo := active(laptop)
+	uuid := convo.Uuid.String
+	require.NoError(t, laptop.TagConversation(ctx, convo.ID, "work"))
+
+	res := sync(laptop)
+	require.Equal(t, 1, res.Exported)
+	require.FileExists(t, filepath.Join(dir, uuid+".json"))
+
+	// the conversation is new to the devbox
+	res = sync(devbox)
+	require.Equal(t, 1, res.Imported)
+	require.Equal(t, []string{"hello", "hi"}, thread(devbox, uuid))
+	imported := active(devbox)
+	tags, err := devbox.GetConversationTags(ctx, imported.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"work"}, tags)
+
+	// nothing changes when nothing changed
+	res = sync(devbox)
+	require.Equal(t, SyncResult{}, res)
+
+	// messages added on one machine show up on the other
+	say(devbox, "more", "sure")
+	sync(devbox)
+	res = sync(laptop)
+	require.Equal(t, 1, res.Updated)
+	require.Empty(t, res.Conflicts)
+	require.Equal(t, []string{"hello", "hi", "more", "sure"}, thread(laptop, uuid))
+
+	// the same conversation changed on both sides is a conflict, and the
+	// later change wins
+	require.NoError(t, laptop.SetConversationArchived(ctx, convo.ID, true))
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, devbox.SetConversationProtected(ctx, imported.ID, true))
+	sync(laptop)
+	res = sync(devbox)
+	require.Len(t, res.Conflicts, 1)
+	require.False(t, res.Conflicts[0].KeptRemote)
+	sync(laptop)
+	c, err := laptop.GetConversation(ctx, convo.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, c.Protected)
+	require.EqualValues(t, 0, c.Archived)
+
+	// a message edited on both sides keeps both versions
+	edit := func(str *Store, content string) {
+		t.Helper()
+		msgs, err := str.GetLastMessages(ctx, 1)
+		require.NoError(t, err)
+		err = str.queries.SetSyncedMessage(ctx, query.SetSyncedMessageParams{
+			Content:   content,
+			UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+			ID:        msgs[0].ID,
+		})
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+	}
+	thread(laptop, uuid)
+	thread(devbox, uuid)
+	edit(laptop, "sure thing")
+	edit(devbox, "of course")
+	sync(laptop)
+	res = sync(devbox)
+	require.Len(t, res.Conflicts, 1)
+	require.NotZero(t, res.Conflicts[0].CopyID)
+	require.Equal(t, []string{"hello", "hi", "more", "of course"}, thread(devbox, uuid))
+	sync(laptop)
+	require.Equal(t, []string{"hello", "hi", "more", "of course"}, thread(laptop, uuid))
+	msgs, err := laptop.queries.GetMessagesForConversation(ctx, convo.ID)
+	require.NoError(t, err)
+	require.Equal(t, "sure thing", msgs[len(msgs)-1].Content)
+
+	// a conversation deleted on one machine is deleted on the other
+	require.NoError(t, devbox.SetConversationProtected(ctx, imported.ID, false))
+	sync(devbox)
+	sync(laptop)
+	thread(laptop, uuid)
+	_, err = laptop.DropConversation(ctx)
+	require.NoError(t, err)
+	_, err = laptop.PurgeTrash(ctx, 0)
+	require.NoError(t, err)
+
+	// a sync that fails leaves the directory as it was
+	require.NoError(t, laptop.NewConversation(ctx))
+	say(laptop, "another", "one")
+	blocked := filepath.Join(dir, active(laptop).Uuid.String+".json")
+	require.NoError(t, os.Mkdir(blocked+".tmp", 0o755))
+	_, err = laptop.Sync(ctx, dir)
+	require.Error(t, err)
+	require.FileExists(t, filepath.Join(dir, uuid+".json"))
+	require.NoFileExists(t, blocked)
+	require.NoError(t, os.Remove(blocked+".tmp"))
+
+	res = sync(laptop)
+	require.Equal(t, 1, res.Removed)
+	require.Equal(t, 1, res.Exported)
+	require.FileExists(t, blocked)
+	_, err = os.Stat(filepath.Join(dir, uuid+".json"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	res = sync(devbox)
+	require.Equal(t, 1, res.Deleted)
+	_, err = devbox.GetConversation(ctx, imported.ID)
+	require.Error(t, err)
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/errs"
+)
+
+type TagSummary = query.GetTagsRow
+
+// NormalizeTag lower cases a tag name and checks that it is usable as a
+// single word in commands.
+func NormalizeTag(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimPrefix(name, "#")
+	if name == "" {
+		return "", fmt.Errorf("tag must not be empty")
+	}
+	for _, r := range name {
+		if unicode.IsSpace(r) || r == ',' {
+			return "", fmt.Errorf("invalid tag %q: tags cannot contain spaces or commas", name)
+		}
+	}
+	return name, nil
+}
+
+// ListTags returns every tag along with the number of conversations that
+// have it.
+func (s *Store) ListTags(ctx context.Context) ([]TagSummary, error) {
+	return s.queries.GetTags(ctx)
+}
+
+// TagConversation adds a tag to a conversation, creating the tag if needed.
+func (s *Store) TagConversation(ctx context.Context, id int64, tag string) error {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	if _, err := s.getConversation(ctx, id); err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = tagConversation(ctx, s.queries.WithTx(tx), id, tag)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UntagConversation removes a tag from a conversation. Tags that no longer
+// belong to any conversation are deleted.
+func (s *Store) UntagConversation(ctx context.Context, id int64, tag string) error {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := s.queries.WithTx(tx)
+	t, err := q.GetTagByName(ctx, tag)
+	switch {
+	case errs.IsDBNotFound(err):
+		return fmt.Errorf("conversation %d is not tagged %q", id, tag)
+	case err != nil:
+		return err
+	}
+	err = q.UntagConversation(ctx, query.UntagConversationParams{
+		ConversationID: id,
+		TagID:          t.ID,
+	})
+	if err != nil {
+		return err
+	}
+	err = q.TouchConversation(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = q.DeleteUnusedTags(ctx)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetConversationTags returns the names of the tags on a conversation.
+func (s *Store) GetConversationTags(ctx context.Context, id int64) ([]string, error) {
+	tags, err := s.queries.GetConversationTags(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(tags))
+	for _, t := range tags {
+		res = append(res, t.Name)
+	}
+	return res, nil
+}
+
+// GetAllConversationTags returns the names of the tags on every
+// conversation, keyed by conversation id.
+func (s *Store) GetAllConversationTags(ctx context.Context) (map[int64][]string, error) {
+	rows, err := s.queries.GetAllConversationTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := map[int64][]string{}
+	for _, r := range rows {
+		res[r.ConversationID] = append(res[r.ConversationID], r.Name)
+	}
+	return res, nil
+}
+
+// GetConversationIDsForTag returns the ids of the conversations with a tag.
+func (s *Store) GetConversationIDsForTag(ctx context.Context, tag string) ([]int64, error) {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	return s.queries.GetConversationIDsForTag(ctx, tag)
+}
+
+func tagConversation(ctx context.Context, q *query.Queries, id int64, tag string) error {
+	err := q.CreateTag(ctx, tag)
+	if err != nil {
+		return err
+	}
+	t, err := q.GetTagByName(ctx, tag)
+	if err != nil {
+		return err
+	}
+	err = q.TagConversation(ctx, query.TagConversationParams{
+		ConversationID: id,
+		TagID:          t.ID,
+	})
+	if err != nil {
+		return err
+	}
+	return q.TouchConversation(ctx, id)
+}
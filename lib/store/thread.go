@@ -0,0 +1,88 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/collinvandyck/gpterm/db/query"
+)
+
+// Thread is the message tree for a single conversation. Each message points
+// at its parent, and the conversation's leaf determines which branch of the
+// tree is active.
+type Thread struct {
+	Messages []query.Message
+	Leaf     sql.NullInt64
+}
+
+// Path returns the messages from the root of the tree to the active leaf, in
+// order. Deleted messages are left out.
+func (t Thread) Path() []query.Message {
+	if !t.Leaf.Valid {
+		return nil
+	}
+	byID := t.byID()
+	var res []query.Message
+	id := t.Leaf
+	for id.Valid {
+		msg, ok := byID[id.Int64]
+		if !ok {
+			break
+		}
+		if !msg.DeletedAt.Valid {
+			res = append(res, msg)
+		}
+		id = msg.ParentID
+	}
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+	return res
+}
+
+// Siblings returns the messages that share a parent with the specified
+// message, including the message itself, in the order they were created.
+// Deleted messages are left out.
+func (t Thread) Siblings(id int64) []query.Message {
+	msg, ok := t.byID()[id]
+	if !ok {
+		return nil
+	}
+	var res []query.Message
+	for _, m := range t.Messages {
+		if m.ParentID == msg.ParentID && !m.DeletedAt.Valid {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// LatestLeaf follows the most recent child of each message starting with id
+// and returns the id of the leaf where it ends up.
+func (t Thread) LatestLeaf(id int64) int64 {
+	for {
+		next, found := int64(0), false
+		for _, m := range t.Messages {
+			if m.ParentID.Valid && m.ParentID.Int64 == id && m.ID > next {
+				next, found = m.ID, true
+			}
+		}
+		if !found {
+			return id
+		}
+		id = next
+	}
+}
+
+// Get returns the message with the specified id.
+func (t Thread) Get(id int64) (query.Message, bool) {
+	msg, ok := t.byID()[id]
+	return msg, ok
+}
+
+func (t Thread) byID() map[int64]query.Message {
+	res := make(map[int64]query.Message, len(t.Messages))
+	for _, m := range t.Messages {
+		res[m.ID] = m
+	}
+	return res
+}
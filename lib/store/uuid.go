@@ -0,0 +1,9 @@
+package store
+
+import "github.com/google/uuid"
+
+// newUUID returns a random id for a conversation or message that stays the
+// same wherever it is synced to.
+func newUUID() string {
+	return uuid.NewString()
+}
@@ -0,0 +1,25 @@
+package term
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+)
+
+// Copy puts text on the clipboard of the terminal w writes to, using the
+// OSC 52 escape sequence. Because the terminal does the copying, it works over
+// ssh too. Inside tmux and screen the sequence is passed through to the
+// terminal they run in, which tmux only allows with allow-passthrough set.
+func Copy(w io.Writer, text string) error {
+	seq := osc52.New(text)
+	switch {
+	case os.Getenv("TMUX") != "":
+		seq = seq.Tmux()
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		seq = seq.Screen()
+	}
+	_, err := seq.WriteTo(w)
+	return err
+}
@@ -0,0 +1,66 @@
+package term
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// The ways a notification can be delivered.
+const (
+	NotifyOSC9       = "osc9"        // iTerm2, WezTerm, kitty, Windows Terminal
+	NotifyOSC777     = "osc777"      // urxvt, foot, Ghostty
+	NotifyBell       = "bell"        // the terminal bell, which most terminals can flash or mark
+	NotifySend       = "notify-send" // the desktop notification service, through notify-send
+	NotifyOff        = "off"
+	DefaultNotify    = NotifyOSC9
+	notifyBodyLength = 200
+)
+
+// NotifyMethods are the ways a notification can be delivered.
+var NotifyMethods = []string{NotifyOSC9, NotifyOSC777, NotifyBell, NotifySend, NotifyOff}
+
+// Notify shows a notification with the title and body, delivered with the
+// method. Escape sequences are written to w, and passed through tmux to the
+// terminal it runs in, which needs allow-passthrough set.
+func Notify(w io.Writer, method string, title string, body string) error {
+	title, body = notifyText(title), notifyText(body)
+	var seq string
+	switch method {
+	case NotifyOSC9:
+		seq = "\x1b]9;" + title + ": " + body + "\a"
+	case NotifyOSC777:
+		seq = "\x1b]777;notify;" + strings.ReplaceAll(title, ";", ",") + ";" + body + "\a"
+	case NotifyBell:
+		_, err := io.WriteString(w, "\a")
+		return err
+	case NotifySend:
+		return exec.Command("notify-send", "--app-name=gpterm", title, body).Run()
+	case NotifyOff:
+		return nil
+	default:
+		return fmt.Errorf("unknown notification method %q, use one of %s", method, strings.Join(NotifyMethods, ", "))
+	}
+	if os.Getenv("TMUX") != "" {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	_, err := io.WriteString(w, seq)
+	return err
+}
+
+// notifyText keeps control characters, which would end the escape sequence,
+// out of the text and shortens it to what fits in a notification.
+func notifyText(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, s)
+	if runes := []rune(s); len(runes) > notifyBodyLength {
+		s = string(runes[:notifyBodyLength-1]) + "…"
+	}
+	return strings.TrimSpace(s)
+}
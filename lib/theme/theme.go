@@ -0,0 +1,144 @@
+// Package theme defines the colors of the TUI. There are dark and light
+// themes for the two kinds of terminal background, and a monochrome theme
+// that is used whenever NO_COLOR is set.
+package theme
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/collinvandyck/gpterm/lib/markdown"
+	"github.com/muesli/termenv"
+)
+
+const (
+	Auto  = "auto" // dark or light, depending on the terminal background
+	Dark  = "dark"
+	Light = "light"
+	Mono  = "mono"
+)
+
+// Names are the themes that can be chosen.
+var Names = []string{Auto, Dark, Light, Mono}
+
+// Theme is the colors the TUI is drawn with.
+type Theme struct {
+	Name string
+
+	// roles in the backlog
+	User      lipgloss.TerminalColor
+	Assistant lipgloss.TerminalColor
+	Error     lipgloss.TerminalColor
+	Notice    lipgloss.TerminalColor
+
+	Pin       lipgloss.TerminalColor // the pinned marker
+	Faint     lipgloss.TerminalColor // response details and the excluded marker
+	Secondary lipgloss.TerminalColor // previews and help in navigators
+	Spinner   lipgloss.TerminalColor
+
+	// the status bar
+	StatusBackground lipgloss.TerminalColor
+	StatusForeground lipgloss.TerminalColor
+	Warning          lipgloss.TerminalColor // budget warnings
+	Alert            lipgloss.TerminalColor // confirmations and exceeded budgets
+
+	// lines added and removed in diffs
+	Added   lipgloss.TerminalColor
+	Removed lipgloss.TerminalColor
+
+	Markdown markdown.Theme
+}
+
+var dark = Theme{
+	Name:             Dark,
+	User:             lipgloss.Color("2"),
+	Assistant:        lipgloss.Color("4"),
+	Error:            lipgloss.Color("#ff0000"),
+	Notice:           lipgloss.Color("3"),
+	Pin:              lipgloss.Color("5"),
+	Faint:            lipgloss.Color("8"),
+	Secondary:        lipgloss.Color("#aaaaaa"),
+	Spinner:          lipgloss.Color("#FFFF00"),
+	StatusBackground: lipgloss.Color("#222222"),
+	StatusForeground: lipgloss.Color("#dddddd"),
+	Warning:          lipgloss.Color("#dddd00"),
+	Alert:            lipgloss.Color("#dd0000"),
+	Added:            lipgloss.Color("2"),
+	Removed:          lipgloss.Color("1"),
+	Markdown:         markdown.DefaultTheme,
+}
+
+var light = Theme{
+	Name:             Light,
+	User:             lipgloss.Color("2"),
+	Assistant:        lipgloss.Color("4"),
+	Error:            lipgloss.Color("#cc0000"),
+	Notice:           lipgloss.Color("#b58900"),
+	Pin:              lipgloss.Color("5"),
+	Faint:            lipgloss.Color("8"),
+	Secondary:        lipgloss.Color("#666666"),
+	Spinner:          lipgloss.Color("#b58900"),
+	StatusBackground: lipgloss.Color("#e4e4e4"),
+	StatusForeground: lipgloss.Color("#222222"),
+	Warning:          lipgloss.Color("#a06000"),
+	Alert:            lipgloss.Color("#cc0000"),
+	Added:            lipgloss.Color("2"),
+	Removed:          lipgloss.Color("1"),
+	Markdown: markdown.Theme{
+		CodeSpan: lipgloss.Color("#a626a4"),
+		Code:     lipgloss.Color("#986801"),
+		Chroma:   "github",
+	},
+}
+
+var mono = Theme{
+	Name:             Mono,
+	User:             lipgloss.NoColor{},
+	Assistant:        lipgloss.NoColor{},
+	Error:            lipgloss.NoColor{},
+	Notice:           lipgloss.NoColor{},
+	Pin:              lipgloss.NoColor{},
+	Faint:            lipgloss.NoColor{},
+	Secondary:        lipgloss.NoColor{},
+	Spinner:          lipgloss.NoColor{},
+	StatusBackground: lipgloss.NoColor{},
+	StatusForeground: lipgloss.NoColor{},
+	Warning:          lipgloss.NoColor{},
+	Alert:            lipgloss.NoColor{},
+	Added:            lipgloss.NoColor{},
+	Removed:          lipgloss.NoColor{},
+	Markdown: markdown.Theme{
+		CodeSpan: lipgloss.NoColor{},
+		Code:     lipgloss.NoColor{},
+	},
+}
+
+// Default returns the dark theme, which gpterm has always used.
+func Default() Theme {
+	return dark
+}
+
+// Get returns the named theme. The monochrome theme is returned whenever
+// NO_COLOR is set, and auto picks the dark or light theme by asking the
+// terminal what its background is, so it must be called before the TUI
+// takes over the terminal.
+func Get(name string) (Theme, error) {
+	if os.Getenv("NO_COLOR") != "" {
+		return mono, nil
+	}
+	switch name {
+	case Auto, "":
+		if termenv.HasDarkBackground() {
+			return dark, nil
+		}
+		return light, nil
+	case Dark:
+		return dark, nil
+	case Light:
+		return light, nil
+	case Mono:
+		return mono, nil
+	}
+	return Theme{}, fmt.Errorf("no theme named %q", name)
+}
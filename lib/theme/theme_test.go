@@ -0,0 +1,26 @@
+package theme
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	for _, name := range []string{Dark, Light, Mono} {
+		th, err := Get(name)
+		require.NoError(t, err)
+		require.Equal(t, name, th.Name)
+	}
+	_, err := Get("solarized")
+	require.EqualError(t, err, `no theme named "solarized"`)
+}
+
+func TestNoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	th, err := Get(Dark)
+	require.NoError(t, err)
+	require.Equal(t, Mono, th.Name)
+	require.Empty(t, th.Markdown.Chroma)
+}
@@ -0,0 +1,202 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/keymap"
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/collinvandyck/gpterm/lib/ui/gptea"
+	"github.com/muesli/reflow/truncate"
+	"github.com/sashabaranov/go-openai"
+)
+
+const branchPreviewLines = 3
+
+// branchModel lets the user walk the user messages on the active branch of
+// the conversation, switch between sibling branches, and pick a message to
+// edit into a new branch.
+type branchModel struct {
+	uiOpts
+	active   bool
+	thread   store.Thread
+	selected int64 // the id of the selected user message
+	width    int
+}
+
+func (m branchModel) Init() tea.Cmd {
+	return nil
+}
+
+// open activates the navigator on the last user message of the thread. If
+// there are no user messages there is nothing to navigate.
+func (m branchModel) open(thread store.Thread) branchModel {
+	m.thread = thread
+	path := m.userPath()
+	if len(path) == 0 {
+		m.active = false
+		return m
+	}
+	m.selected = path[len(path)-1].ID
+	m.active = true
+	return m
+}
+
+func (m branchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds commands
+
+	switch msg := msg.(type) {
+
+	case gptea.WindowSizeMsg:
+		m.width = msg.Width
+
+	case gptea.BranchSwitchedMsg:
+		if msg.Err != nil {
+			break
+		}
+		m.thread = msg.Thread
+		m.selected = msg.Selected
+
+	case tea.KeyMsg:
+		if !m.active {
+			break
+		}
+		path := m.userPath()
+		idx := m.pathIndex(path)
+		switch msg.Type {
+
+		case tea.KeyUp:
+			if idx > 0 {
+				m.selected = path[idx-1].ID
+			}
+
+		case tea.KeyDown:
+			if idx >= 0 && idx < len(path)-1 {
+				m.selected = path[idx+1].ID
+			}
+
+		case tea.KeyLeft, tea.KeyRight:
+			siblings := m.thread.Siblings(m.selected)
+			pos := m.siblingIndex(siblings)
+			if msg.Type == tea.KeyLeft {
+				pos--
+			} else {
+				pos++
+			}
+			if pos >= 0 && pos < len(siblings) {
+				cmds.Add(m.switchBranch(siblings[pos].ID))
+			}
+
+		case tea.KeyEnter:
+			if sel, ok := m.thread.Get(m.selected); ok {
+				cmds.Add(gptea.MessageCmd(gptea.BranchEditMsg{Message: sel}))
+			}
+			m.active = false
+
+		case tea.KeyEsc:
+			m.active = false
+
+		default:
+			if m.keys.Is(msg.String(), keymap.Branches) {
+				m.active = false
+			}
+		}
+	}
+	return m, cmds.BatchWith()
+}
+
+func (m branchModel) View() string {
+	if !m.active {
+		return ""
+	}
+	path := m.userPath()
+	idx := m.pathIndex(path)
+	siblings := m.thread.Siblings(m.selected)
+	pos := m.siblingIndex(siblings)
+	sel, _ := m.thread.Get(m.selected)
+
+	header := fmt.Sprintf("Prompt %d of %d", idx+1, len(path))
+	if len(siblings) > 1 {
+		header += fmt.Sprintf(" ‹ branch %d of %d ›", pos+1, len(siblings))
+	}
+	headerStyle := lipgloss.NewStyle().Bold(true)
+	previewStyle := lipgloss.NewStyle().Foreground(m.theme.Secondary)
+	lines := strings.Split(strings.TrimSpace(sel.Content), "\n")
+	if len(lines) > branchPreviewLines {
+		lines = append(lines[:branchPreviewLines], "…")
+	}
+	width := m.width - m.rhsPadding
+	for i, line := range lines {
+		if width > 0 {
+			line = truncate.StringWithTail(line, uint(width), "…")
+		}
+		lines[i] = previewStyle.Render(line)
+	}
+	help := "↑/↓: Prompt | ←/→: Branch | Enter: Edit | Esc: Done"
+	return strings.Join([]string{
+		headerStyle.Render(header),
+		strings.Join(lines, "\n"),
+		previewStyle.Render(help),
+	}, "\n") + "\n"
+}
+
+// userPath returns the user messages on the active branch.
+func (m branchModel) userPath() []query.Message {
+	var res []query.Message
+	for _, msg := range m.thread.Path() {
+		if msg.Role == openai.ChatMessageRoleUser {
+			res = append(res, msg)
+		}
+	}
+	return res
+}
+
+func (m branchModel) pathIndex(path []query.Message) int {
+	for i, msg := range path {
+		if msg.ID == m.selected {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m branchModel) siblingIndex(siblings []query.Message) int {
+	for i, msg := range siblings {
+		if msg.ID == m.selected {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m branchModel) switchBranch(id int64) tea.Cmd {
+	return func() tea.Msg {
+		ctx := m.storeContext()
+		err := m.store.SwitchBranch(ctx, id)
+		if err != nil {
+			return gptea.BranchSwitchedMsg{Err: err}
+		}
+		thread, err := m.store.GetThread(ctx)
+		return gptea.BranchSwitchedMsg{Thread: thread, Selected: id, Err: err}
+	}
+}
+
+func (m branchModel) storeContext() context.Context {
+	return context.Background()
+}
+
+// pathBefore returns the messages on the active branch that precede the
+// specified message.
+func pathBefore(thread store.Thread, id int64) []query.Message {
+	path := thread.Path()
+	for i, msg := range path {
+		if msg.ID == id {
+			return path[:i]
+		}
+	}
+	return path
+}
@@ -0,0 +1,18 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/collinvandyck/gpterm/lib/term"
+	"github.com/collinvandyck/gpterm/lib/ui/gptea"
+)
+
+// copyText puts text on the clipboard and reports what was copied.
+func (o uiOpts) copyText(text string, what string) tea.Cmd {
+	return func() tea.Msg {
+		err := term.Copy(os.Stdout, text)
+		return gptea.NoticeMsg{Text: fmt.Sprintf("Copied %s to the clipboard.", what), Err: err}
+	}
+}
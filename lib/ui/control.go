@@ -17,8 +17,12 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/attach"
 	"github.com/collinvandyck/gpterm/lib/client"
+	"github.com/collinvandyck/gpterm/lib/keymap"
 	"github.com/collinvandyck/gpterm/lib/markdown"
+	"github.com/collinvandyck/gpterm/lib/persona"
+	"github.com/collinvandyck/gpterm/lib/pricing"
 	"github.com/collinvandyck/gpterm/lib/store"
 	"github.com/collinvandyck/gpterm/lib/ui/gptea"
 	"github.com/google/go-github/v39/github"
@@ -29,6 +33,11 @@ import (
 
 const (
 	defaultChatlogMaxSize = 100
+	watchInterval         = 2 * time.Second // how often to look for changes by other instances
+
+	// the range of messages that can be sent as context
+	minMessageContext = 1
+	maxMessageContext = 20
 )
 
 type controlModel struct {
@@ -36,13 +45,24 @@ type controlModel struct {
 	prompt     promptModel
 	status     statusModel
 	typewriter typewriterModel
-	backlog    backlog // message backlog loaded from store
-	config     config  // persisted config
-	ready      bool    // has the terminal initialized
-	inflight   bool    // is there a completion in flight
+	branch     branchModel    // conversation branch navigator
+	selector   selectModel    // message selector
+	viewer     viewerModel    // full screen history viewer
+	saver      saveModel      // saves code blocks to files
+	backlog    backlog        // message backlog loaded from store
+	config     config         // persisted config
+	editing    *query.Message // earlier prompt being edited into a new branch
+	branching  bool           // the prompt in flight is an edit of an earlier one
+	ready      bool           // has the terminal initialized
+	inflight   bool           // is there a completion in flight
+	stale      bool           // the conversation was changed elsewhere while a completion was in flight
 	width      int
 	height     int
 	dropCount  int
+	tagFilter  string   // only navigate between conversations with this tag
+	details    bool     // show response metadata under assistant messages
+	overBudget string   // prompt that may be sent over budget if entered again
+	chord      []string // keys pressed so far of a chord
 }
 
 type textInput struct {
@@ -54,7 +74,8 @@ type textInput struct {
 type config struct {
 	store.Config
 	query.ClientConfig
-	set bool
+	preamble string // what the persona tells the model
+	set      bool
 }
 
 type backlog struct {
@@ -73,7 +94,15 @@ func newControlModel(uiOpts uiOpts) controlModel {
 		typewriter: typewriterModel{
 			uiOpts: uiOpts.NamedLogger("typewriter"),
 		},
+		branch: branchModel{
+			uiOpts: uiOpts.NamedLogger("branch"),
+		},
+		selector: selectModel{
+			uiOpts: uiOpts.NamedLogger("select"),
+		},
 		status: newStatusModel(uiOpts.NamedLogger("status")),
+		viewer: newViewerModel(uiOpts.NamedLogger("viewer")),
+		saver:  newSaveModel(uiOpts.NamedLogger("save")),
 	}
 	return res
 }
@@ -82,9 +111,14 @@ func (m controlModel) Init() tea.Cmd {
 	return tea.Batch(
 		m.loadBacklog,
 		m.loadConfig,
+		m.watch(),
 		m.prompt.Init(),
 		m.status.Init(),
 		m.typewriter.Init(),
+		m.branch.Init(),
+		m.selector.Init(),
+		m.viewer.Init(),
+		m.saver.Init(),
 	)
 }
 
@@ -92,8 +126,20 @@ func (m controlModel) View() string {
 	if !m.ready {
 		return ""
 	}
+	if m.viewer.active {
+		return m.viewer.View()
+	}
 	var res string
-	res += m.typewriter.View()
+	switch {
+	case m.branch.active:
+		res += m.branch.View()
+	case m.selector.active:
+		res += m.selector.View()
+	case m.saver.active:
+		res += m.saver.View()
+	default:
+		res += m.typewriter.View()
+	}
 	res += "\n"
 	res += m.prompt.View()
 	res += "\n"
@@ -129,14 +175,21 @@ func (m controlModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Sequence(seq...)
 
 	case gptea.ConfigLoadedMsg:
+		m.Log("Config loaded", "len", len(msg.Config), "client", msg.ClientConfig, "persona", msg.Persona, "err", msg.Err)
+		if msg.Err != nil {
+			cmds.Add(m.error(msg.Err))
+			break
+		}
 		m.config.Config = msg.Config
 		m.config.ClientConfig = msg.ClientConfig
+		m.config.preamble = msg.Preamble
 		m.config.set = true
 		m.client.Update(
 			client.WithModel(m.config.ClientConfig.Model),
 			client.WithClientContext(int(m.config.ClientConfig.MessageContext)),
+			client.WithPreamble(msg.Preamble),
 		)
-		m.Log("Config loaded", "len", len(msg.Config), "client", msg.ClientConfig, "err", msg.Err)
+		cmds.Add(m.loadBudget)
 
 	case gptea.BacklogMsg:
 		m.Log("Backlog loaded", "len", len(msg.Messages), "err", msg.Err)
@@ -159,35 +212,156 @@ func (m controlModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case msg.Err != nil:
 			cmds.Add(m.error(msg.Err))
 		default:
+			// the prompt being edited belongs to the conversation that
+			// was switched away from
+			var cmd tea.Cmd
+			m, cmd = m.stopEditing()
+			cmds.Add(cmd)
+			m.stale = false
 			m.backlog.messages = msg.Messages
 			m.backlog.set = true
 			m.backlog.printed = false
+			cmds.Add(m.loadConfig)
 			seq := []tea.Cmd{}
 			seq = append(seq, gptea.ClearScrollback)
 			seq = append(seq, m.printBacklog())
+			if msg.Dropped != 0 {
+				seq = append(seq, m.notice(fmt.Sprintf(
+					"Conversation %d was moved to the trash. Restore it with `gpterm convo restore %d`.",
+					msg.Dropped, msg.Dropped)))
+			}
 			cmds.Add(tea.Sequence(seq...))
 		}
 
+	case gptea.WatchMsg:
+		cmds.Add(m.watch())
+		if m.ready && !m.inflight && !m.branch.active && !m.selector.active && !m.viewer.active && !m.saver.active && m.backlog.printed {
+			if m.stale {
+				// the change was already seen, so it won't be reported again
+				m.stale = false
+				cmds.Add(m.reloadChanged)
+			} else {
+				cmds.Add(m.checkChanged)
+			}
+		}
+
+	case gptea.ConversationChangedMsg:
+		switch {
+		case msg.Err != nil:
+			m.Log("Checking for changes failed", "err", msg.Err)
+		case msg.Changed && m.inflight:
+			// reloaded once the response is in
+			m.stale = true
+		case msg.Changed:
+			m.Log("Conversation changed by another instance", "len", len(msg.Messages))
+			// the prompt being edited may be gone, and the thread it was
+			// edited from is out of date
+			var cmd tea.Cmd
+			m, cmd = m.stopEditing()
+			cmds.Add(cmd)
+			m.backlog.messages = msg.Messages
+			m.backlog.set = true
+			m.backlog.printed = false
+			cmds.Add(m.loadConfig)
+			cmds.Add(tea.Sequence(
+				gptea.ClearScrollback,
+				m.printBacklog(),
+				m.notice("This conversation was updated by another gpterm."),
+			))
+		}
+
 	case gptea.StreamCompletionReq:
 		m.inflight = true
-		um := query.Message{
-			Role:    openai.ChatMessageRoleUser,
-			Content: msg.Text,
-		}
-		m.backlog.messages = append(m.backlog.messages, um)
-		am := query.Message{
-			Role: openai.ChatMessageRoleAssistant,
-		}
-		m.backlog.messages = append(m.backlog.messages, am)
-		cmds.Add(tea.Sequence(
-			tea.Println(""),
-			tea.Println(m.renderMessage(um)),
-			tea.Println(m.renderMessage(am)),
-			m.completeStream(msg.Text),
-		))
+		cmds.Add(m.expand(msg.Text))
+
+	case gptea.PromptExpandedMsg:
+		if msg.Err != nil {
+			cmds.Add(m.handBack(msg.Text, fmt.Errorf("attach: %w", msg.Err)))
+			break
+		}
+		if msg.Text != "" && msg.Text == m.overBudget {
+			// the user confirmed going over budget by sending it again
+			m.overBudget = ""
+			var cmd tea.Cmd
+			m, cmd = m.send(msg.Expansion)
+			cmds.Add(cmd)
+			break
+		}
+		m.overBudget = ""
+		cmds.Add(m.checkBudget(msg.Text, msg.Expansion))
+
+	case gptea.BudgetCheckedMsg:
+		var err error
+		switch {
+		case msg.Err != nil:
+			err = fmt.Errorf("budget: %w", msg.Err)
+		case msg.Status.Exceeded && msg.Status.HardCap == store.HardCapRefuse:
+			err = fmt.Errorf("%s. This request would go over budget and was not sent.", msg.Status)
+		case msg.Status.Exceeded:
+			m.overBudget = msg.Text
+			err = fmt.Errorf("%s. This request would go over budget. Press Enter to send it anyway.", msg.Status)
+		}
+		if err != nil {
+			cmds.Add(m.handBack(msg.Text, err))
+			break
+		}
+		var cmd tea.Cmd
+		m, cmd = m.send(msg.Expansion)
+		cmds.Add(cmd)
+
+	case gptea.ThreadMsg:
+		if msg.Err != nil {
+			cmds.Add(m.error(msg.Err))
+			break
+		}
+		if msg.Select {
+			m.selector = m.selector.open(msg.Thread)
+			break
+		}
+		m.branch = m.branch.open(msg.Thread)
+
+	case gptea.BranchSwitchedMsg:
+		if msg.Err != nil {
+			cmds.Add(m.error(msg.Err))
+			break
+		}
+		var cmd tea.Cmd
+		m, cmd = m.resetBacklog(msg.Thread)
+		cmds.Add(cmd)
+
+	case gptea.ViewerClosedMsg:
+		// anything printed while the viewer was open was lost
+		m.backlog.printed = false
+		cmds.Add(tea.Sequence(gptea.ClearScrollback, m.printBacklog()))
+
+	case gptea.MessageEditRequestMsg:
+		if m.ready && !m.inflight {
+			cmds.Add(m.editMessage(msg.Message))
+		}
+
+	case gptea.MessageUpdatedMsg:
+		if msg.Err != nil {
+			cmds.Add(m.error(msg.Err))
+			break
+		}
+		var cmd tea.Cmd
+		m, cmd = m.resetBacklog(msg.Thread)
+		cmds.Add(cmd)
+
+	case gptea.BranchEditMsg:
+		m.editing = &msg.Message
+		m.status.setEditing(true)
+		// the files are attached again when it is sent
+		cmds.Add(gptea.MessageCmd(gptea.SetPromptMsg{Text: attach.Strip(msg.Message.Content)}))
 
 	case gptea.StreamCompletionResult:
 		m.inflight = false
+		branching := m.branching
+		m.branching = false
+		if msg.Err != nil && branching {
+			cmds.Add(m.editFailed(msg.Err))
+			break
+		}
 		if msg.Err != nil {
 			cmds.Add(m.error(msg.Err))
 			break
@@ -198,6 +372,32 @@ func (m controlModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if extra > 0 {
 			m.backlog.messages = m.backlog.messages[extra:]
 		}
+		cmds.Add(m.loadSavedResponse)
+
+	case gptea.EditFailedMsg:
+		var cmd tea.Cmd
+		m, cmd = m.resetBacklog(msg.Thread, m.error(msg.Err))
+		cmds.Add(cmd)
+
+	case gptea.ResponseSavedMsg:
+		if msg.Err != nil {
+			cmds.Add(m.error(msg.Err))
+			break
+		}
+		// the backlog entry was built while streaming, so it has none of
+		// what the store recorded, such as its id and metadata.
+		l := len(m.backlog.messages)
+		if l == 0 || m.backlog.messages[l-1].Role != msg.Message.Role {
+			break
+		}
+		m.backlog.messages[l-1] = msg.Message
+		cmds.Add(m.loadBudget)
+		cmds.Add(m.notify(msg.Message))
+		if m.details {
+			if details := m.renderDetails(msg.Message); details != "" {
+				cmds.Add(tea.Println(details))
+			}
+		}
 
 	case gptea.EditorRequestMsg:
 		if m.ready && !m.inflight {
@@ -213,6 +413,39 @@ func (m controlModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case gptea.ErrorMsg:
 		cmds.Add(m.error(msg.Err))
 
+	case gptea.CommandMsg:
+		var cmd tea.Cmd
+		m, cmd = m.command(msg)
+		cmds.Add(cmd)
+
+	case gptea.NoticeMsg:
+		if msg.Err != nil {
+			cmds.Add(m.error(msg.Err))
+			break
+		}
+		cmds.Add(m.notice(msg.Text))
+
+	case gptea.TagsMsg:
+		if msg.Err != nil {
+			cmds.Add(m.error(msg.Err))
+			break
+		}
+		text := fmt.Sprintf("Conversation %d has no tags.", msg.ConversationID)
+		if len(msg.Tags) > 0 {
+			text = fmt.Sprintf("Conversation %d is tagged %s.", msg.ConversationID, strings.Join(msg.Tags, ", "))
+		}
+		cmds.Add(m.notice(text))
+
+	case gptea.PinnedMsg:
+		if msg.Err != nil {
+			cmds.Add(m.error(msg.Err))
+			break
+		}
+		m.backlog.messages = msg.Messages
+		m.backlog.set = true
+		m.backlog.printed = false
+		cmds.Add(tea.Sequence(gptea.ClearScrollback, m.printBacklog()))
+
 	case gptea.GistResultMsg:
 		seq := []tea.Cmd{}
 		if msg.Err != nil {
@@ -234,8 +467,37 @@ func (m controlModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds.Add(tea.Sequence(seq...))
 
 	case tea.KeyMsg:
+		if m.branch.active && msg.Type != tea.KeyCtrlC && msg.Type != tea.KeyCtrlD {
+			// the navigator owns the keyboard while it is open
+			branch, branchCmd := m.branch.Update(msg)
+			m.branch = branch.(branchModel)
+			return m, branchCmd
+		}
+		if m.viewer.active && msg.Type != tea.KeyCtrlC && msg.Type != tea.KeyCtrlD {
+			viewer, viewerCmd := m.viewer.Update(msg)
+			m.viewer = viewer.(viewerModel)
+			return m, viewerCmd
+		}
+		if m.selector.active && msg.Type != tea.KeyCtrlC && msg.Type != tea.KeyCtrlD {
+			// as does the selector
+			selector, selectorCmd := m.selector.Update(msg)
+			m.selector = selector.(selectModel)
+			return m, selectorCmd
+		}
+		if m.saver.active && msg.Type != tea.KeyCtrlC && msg.Type != tea.KeyCtrlD {
+			saver, saverCmd := m.saver.Update(msg)
+			m.saver = saver.(saveModel)
+			return m, saverCmd
+		}
+		action, chord := m.keys.Match(m.chord, msg.String())
+		m.chord = chord
+		m.status.setChord(chord)
+		if len(chord) > 0 {
+			// wait for the rest of the chord
+			return m, nil
+		}
 		dropCancelled := false
-		if msg.Type != tea.KeyCtrlX {
+		if action != keymap.DropConversation {
 			if m.dropCount > 0 {
 				m.dropCount = 0
 				m.status.setDrop(m.dropCount)
@@ -254,49 +516,24 @@ func (m controlModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyCtrlD:
 			return m, tea.Quit
 
-		case tea.KeyCtrlX:
-			if m.ready && !m.inflight {
-				if m.dropCount == 1 {
-					m.dropCount = 0
-					m.status.setDrop(0)
-					return m, m.dropConvo()
-				} else {
-					m.dropCount++
-					m.status.setDrop(m.dropCount)
-				}
-			}
-
-		case tea.KeyF1:
-			if m.ready && !m.inflight {
-				cmds.Add(m.changeConvoHistory(-1))
-			}
-
-		case tea.KeyF2:
-			if m.ready && !m.inflight {
-				cmds.Add(m.changeConvoHistory(+1))
-			}
+		case tea.KeyEsc:
+			var cmd tea.Cmd
+			m, cmd = m.stopEditing()
+			cmds.Add(cmd)
 
-		case tea.KeyF3:
-			if m.ready && !m.inflight {
-				cmds.Add(m.cycleClientConfig())
-			}
 		case tea.KeyF12:
 			// gist support isn't ready yet
 			// cmds.Add(m.gist)
 
-		case tea.KeyCtrlP:
-			if m.ready && !m.inflight {
-				cmds.Add(m.previous)
-			}
-
-		case tea.KeyCtrlN:
-			if m.ready && !m.inflight {
-				cmds.Add(m.next)
-			}
-
 		default:
 
 		}
+		if action != "" {
+			// the key did something, so it isn't passed on to the prompt
+			var cmd tea.Cmd
+			m, cmd = m.do(action)
+			return m, cmds.BatchWith(cmd)
+		}
 	}
 
 	prompt, promptCmd := m.prompt.Update(msg)
@@ -310,13 +547,93 @@ func (m controlModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	typewriter, typewriterCmd := m.typewriter.Update(msg)
 	m.typewriter = typewriter.(typewriterModel)
 	cmds.Add(typewriterCmd)
+	m.status.setStreamed(len(m.typewriter.data))
+	if m.config.set {
+		m.status.setPromptTokens(m.promptTokens())
+	}
+
+	branch, branchCmd := m.branch.Update(msg)
+	m.branch = branch.(branchModel)
+	cmds.Add(branchCmd)
+
+	selector, selectorCmd := m.selector.Update(msg)
+	m.selector = selector.(selectModel)
+	cmds.Add(selectorCmd)
+
+	viewer, viewerCmd := m.viewer.Update(msg)
+	m.viewer = viewer.(viewerModel)
+	cmds.Add(viewerCmd)
+
+	saver, saverCmd := m.saver.Update(msg)
+	m.saver = saver.(saveModel)
+	cmds.Add(saverCmd)
 
 	return m, tea.Batch(cmds...)
 }
 
+// do performs the action a key is bound to.
+func (m controlModel) do(action keymap.Action) (controlModel, tea.Cmd) {
+	if !m.ready || m.inflight {
+		return m, nil
+	}
+	switch action {
+
+	case keymap.Editor:
+		if m.prompt.ta.Focused() {
+			return m, gptea.StartEditorCmd(m.prompt.ta.Value())
+		}
+
+	case keymap.PrevConversation:
+		return m, m.previous
+
+	case keymap.NextConversation:
+		return m, m.next
+
+	case keymap.DropConversation:
+		if m.dropCount == 1 {
+			m.dropCount = 0
+			m.status.setDrop(0)
+			return m, m.dropConvo()
+		}
+		m.dropCount++
+		m.status.setDrop(m.dropCount)
+
+	case keymap.LessContext:
+		return m, m.changeConvoHistory(-1)
+
+	case keymap.MoreContext:
+		return m, m.changeConvoHistory(+1)
+
+	case keymap.CycleModel:
+		return m, m.cycleClientConfig()
+
+	case keymap.Branches:
+		return m, m.loadThread
+
+	case keymap.Details:
+		m.details = !m.details
+		m.status.setDetails(m.details)
+		m.backlog.printed = false
+		return m, tea.Sequence(gptea.ClearScrollback, m.printBacklog())
+
+	case keymap.Select:
+		return m, m.loadSelectThread
+
+	case keymap.History:
+		var cmd tea.Cmd
+		m.viewer, cmd = m.viewer.open()
+		return m, cmd
+
+	case keymap.Multiline:
+		m.prompt = m.prompt.setMultiline(!m.prompt.multiline)
+		m.status.setMultiline(m.prompt.multiline)
+	}
+	return m, nil
+}
+
 func (m controlModel) gist() tea.Msg {
 	ctx := context.Background()
-	accessToken, err := m.store.GetCredential(ctx, store.CredentialGithubToken)
+	accessToken, err := m.credentials.Get(ctx, store.CredentialGithubToken)
 	if err != nil {
 		return gptea.GistResultMsg{Err: err}
 	}
@@ -400,6 +717,45 @@ func editorIsVim(editor string) bool {
 }
 
 func (m controlModel) spawnEditor(prompt string) tea.Cmd {
+	template := strings.TrimLeft(editorTemplate, " \n")
+	return m.openEditor(template+prompt, func(text string) tea.Msg {
+		buf := new(bytes.Buffer)
+		inHeader := true
+		s := bufio.NewScanner(strings.NewReader(text))
+		for s.Scan() {
+			line := s.Text()
+			if inHeader && strings.HasPrefix(line, "#") {
+				continue
+			}
+			inHeader = false
+			buf.WriteString(line + "\n")
+		}
+		text = buf.String()
+		text = strings.TrimSpace(text)
+		return gptea.EditorResultMsg{Text: text}
+	})
+}
+
+// editMessage opens msg in the editor and saves the result in its place.
+// Nothing changes if the content is left as it was or removed entirely.
+func (m controlModel) editMessage(msg query.Message) tea.Cmd {
+	return m.openEditor(msg.Content, func(text string) tea.Msg {
+		if text == "" || text == strings.TrimSpace(msg.Content) {
+			return nil
+		}
+		ctx := m.storeContext()
+		err := m.store.EditMessage(ctx, msg.ID, text)
+		if err != nil {
+			return gptea.MessageUpdatedMsg{Err: err}
+		}
+		thread, err := m.store.GetThread(ctx)
+		return gptea.MessageUpdatedMsg{Thread: thread, Selected: msg.ID, Err: err}
+	})
+}
+
+// openEditor opens content in the user's editor. When the editor exits, done
+// is called with what was saved, trimmed of surrounding whitespace.
+func (m controlModel) openEditor(content string, done func(text string) tea.Msg) tea.Cmd {
 	const editorEnv = "EDITOR"
 	editor := os.Getenv(editorEnv)
 	if editor == "" {
@@ -409,9 +765,7 @@ func (m controlModel) spawnEditor(prompt string) tea.Cmd {
 	if err != nil {
 		return gptea.ErrorCmd(err)
 	}
-	template := strings.TrimLeft(editorTemplate, " \n")
-	io.Copy(f, strings.NewReader(template))
-	io.Copy(f, strings.NewReader(prompt))
+	io.Copy(f, strings.NewReader(content))
 
 	args := []string{}
 	if editorIsVim(editor) {
@@ -419,7 +773,7 @@ func (m controlModel) spawnEditor(prompt string) tea.Cmd {
 	}
 	args = append(args, f.Name())
 	cmd := exec.Command(editor, args...)
-	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+	return m.terminal.exec(cmd, func(err error) tea.Msg {
 		if err != nil {
 			return gptea.ErrorMsg{Err: err}
 		}
@@ -435,47 +789,41 @@ func (m controlModel) spawnEditor(prompt string) tea.Cmd {
 		if err != nil {
 			return gptea.ErrorMsg{Err: err}
 		}
-		text := string(bs)
-		text = strings.TrimSpace(text)
-		buf := new(bytes.Buffer)
-		inHeader := true
-		s := bufio.NewScanner(strings.NewReader(text))
-		for s.Scan() {
-			line := s.Text()
-			if inHeader && strings.HasPrefix(line, "#") {
-				continue
-			}
-			inHeader = false
-			buf.WriteString(line + "\n")
-		}
-		text = buf.String()
-		text = strings.TrimSpace(text)
-		return gptea.EditorResultMsg{Text: text}
+		return done(strings.TrimSpace(string(bs)))
 	})
 }
 
+// stopEditing gives up editing an earlier prompt, if one is being edited,
+// and clears the prompt.
+func (m controlModel) stopEditing() (controlModel, tea.Cmd) {
+	if m.editing == nil {
+		return m, nil
+	}
+	m.editing = nil
+	m.status.setEditing(false)
+	return m, gptea.MessageCmd(gptea.SetPromptMsg{})
+}
+
 func (m controlModel) cycleClientConfig() tea.Cmd {
 	return func() tea.Msg {
 		ctx := m.storeContext()
-		m.store.CycleClientConfig(ctx)
-		config, err := m.store.GetConfig(ctx)
+		err := m.store.CycleClientConfig(ctx)
 		if err != nil {
-			return gptea.ConfigLoadedMsg{Config: config, Err: err}
+			return gptea.ConfigLoadedMsg{Err: err}
 		}
-		cc, err := m.store.GetClientConfig(ctx)
-		return gptea.ConfigLoadedMsg{Config: config, ClientConfig: cc, Err: err}
+		return m.loadConfig()
 	}
 }
 
 func (m controlModel) dropConvo() tea.Cmd {
 	return func() tea.Msg {
 		ctx := m.storeContext()
-		err := m.store.DropConversation(ctx)
+		dropped, err := m.store.DropConversation(ctx)
 		if err != nil {
 			return gptea.ConversationSwitchedMsg{Err: err}
 		}
 		msgs, err := m.store.GetLastMessages(ctx, defaultChatlogMaxSize)
-		return gptea.ConversationSwitchedMsg{Messages: msgs, Err: err}
+		return gptea.ConversationSwitchedMsg{Messages: msgs, Dropped: dropped, Err: err}
 	}
 }
 
@@ -484,19 +832,14 @@ func (m controlModel) changeConvoHistory(delta int) tea.Cmd {
 		ctx := m.storeContext()
 		val := int(m.config.ClientConfig.MessageContext)
 		val += delta
-		if val < 1 || val > 20 {
+		if val < minMessageContext || val > maxMessageContext {
 			return gptea.ConversationHistoryMsg{Err: fmt.Errorf("invalid value: %d", val)}
 		}
 		err := m.store.UpdateClientConfig(ctx, int64(val))
 		if err != nil {
 			return gptea.ConversationHistoryMsg{Val: val, Err: err}
 		}
-		config, err := m.store.GetConfig(ctx)
-		if err != nil {
-			return gptea.ConfigLoadedMsg{Config: config, Err: err}
-		}
-		cc, err := m.store.GetClientConfig(ctx)
-		return gptea.ConfigLoadedMsg{Config: config, ClientConfig: cc, Err: err}
+		return m.loadConfig()
 	}
 }
 
@@ -509,9 +852,56 @@ func (m controlModel) error(err error) tea.Cmd {
 	return tea.Println("\n" + errStr)
 }
 
+func (m controlModel) notice(text string) tea.Cmd {
+	noticeStr := m.renderMessage(query.Message{
+		Role:    "notice",
+		Content: text,
+	})
+	noticeStr = strings.TrimSpace(noticeStr)
+	return tea.Println("\n" + noticeStr)
+}
+
+// tag adds or removes tags on the current conversation and reports the
+// resulting tags.
+func (m controlModel) tag(remove bool, tags []string) tea.Cmd {
+	return func() tea.Msg {
+		ctx := m.storeContext()
+		convo, err := m.store.ActiveConversation(ctx)
+		if err != nil {
+			return gptea.TagsMsg{Err: err}
+		}
+		for _, tag := range tags {
+			if remove {
+				err = m.store.UntagConversation(ctx, convo.ID, tag)
+			} else {
+				err = m.store.TagConversation(ctx, convo.ID, tag)
+			}
+			if err != nil {
+				return gptea.TagsMsg{Err: err}
+			}
+		}
+		res, err := m.store.GetConversationTags(ctx, convo.ID)
+		return gptea.TagsMsg{ConversationID: convo.ID, Tags: res, Err: err}
+	}
+}
+
+// pin pins or unpins the nth most recent message and reloads the backlog so
+// that the marker is shown.
+func (m controlModel) pin(n int, pinned bool) tea.Cmd {
+	return func() tea.Msg {
+		ctx := m.storeContext()
+		msg, err := m.store.PinRecentMessage(ctx, n, pinned)
+		if err != nil {
+			return gptea.PinnedMsg{Err: err}
+		}
+		msgs, err := m.store.GetLastMessages(ctx, defaultChatlogMaxSize)
+		return gptea.PinnedMsg{Message: msg, Messages: msgs, Err: err}
+	}
+}
+
 func (m controlModel) next() tea.Msg {
 	ctx := m.storeContext()
-	err := m.store.NextConversation(ctx)
+	err := m.store.NextConversation(ctx, m.tagFilter)
 	if err != nil {
 		return gptea.ConversationSwitchedMsg{Err: err}
 	}
@@ -521,7 +911,7 @@ func (m controlModel) next() tea.Msg {
 
 func (m controlModel) previous() tea.Msg {
 	ctx := m.storeContext()
-	err := m.store.PreviousConversation(ctx)
+	err := m.store.PreviousConversation(ctx, m.tagFilter)
 	if err != nil {
 		return gptea.ConversationSwitchedMsg{Err: err}
 	}
@@ -531,12 +921,189 @@ func (m controlModel) previous() tea.Msg {
 
 func (m controlModel) loadConfig() tea.Msg {
 	ctx := m.storeContext()
+	convo, err := m.store.ActiveConversation(ctx)
+	if err != nil {
+		return gptea.ConfigLoadedMsg{Err: err}
+	}
 	cfg, err := m.store.GetConfig(ctx)
 	if err != nil {
-		return gptea.ConfigLoadedMsg{Config: cfg, Err: err}
+		return gptea.ConfigLoadedMsg{Conversation: convo, Config: cfg, Err: err}
 	}
 	clientCfg, err := m.store.GetClientConfig(ctx)
-	return gptea.ConfigLoadedMsg{Config: cfg, ClientConfig: clientCfg, Err: err}
+	if err != nil {
+		return gptea.ConfigLoadedMsg{Conversation: convo, Config: cfg, ClientConfig: clientCfg, Err: err}
+	}
+	name, err := m.store.GetPersona(ctx)
+	if err != nil {
+		return gptea.ConfigLoadedMsg{Conversation: convo, Config: cfg, ClientConfig: clientCfg, Err: err}
+	}
+	preamble, err := persona.Load(m.store.Dir(), name)
+	return gptea.ConfigLoadedMsg{Conversation: convo, Config: cfg, ClientConfig: clientCfg, Persona: name, Preamble: preamble, Err: err}
+}
+
+func (m controlModel) loadThread() tea.Msg {
+	ctx := m.storeContext()
+	thread, err := m.store.GetThread(ctx)
+	return gptea.ThreadMsg{Thread: thread, Err: err}
+}
+
+func (m controlModel) loadSelectThread() tea.Msg {
+	ctx := m.storeContext()
+	thread, err := m.store.GetThread(ctx)
+	return gptea.ThreadMsg{Thread: thread, Select: true, Err: err}
+}
+
+// editFailed shows the branch that is active after an edited prompt failed,
+// which is the one it would have replaced unless the prompt was saved before
+// the request failed.
+func (m controlModel) editFailed(err error) tea.Cmd {
+	return func() tea.Msg {
+		thread, loadErr := m.store.GetThread(m.storeContext())
+		if loadErr != nil {
+			return gptea.ErrorMsg{Err: err}
+		}
+		return gptea.EditFailedMsg{Thread: thread, Err: err}
+	}
+}
+
+// resetBacklog replaces the backlog with the active branch of thread and
+// prints it again, followed by what after prints.
+func (m controlModel) resetBacklog(thread store.Thread, after ...tea.Cmd) (controlModel, tea.Cmd) {
+	msgs := thread.Path()
+	if extra := len(msgs) - defaultChatlogMaxSize; extra > 0 {
+		msgs = msgs[extra:]
+	}
+	m.backlog.messages = msgs
+	m.backlog.set = true
+	m.backlog.printed = false
+	return m, tea.Sequence(append([]tea.Cmd{gptea.ClearScrollback, m.printBacklog()}, after...)...)
+}
+
+// loadSavedResponse loads the response that was just saved, and prices it.
+func (m controlModel) loadSavedResponse() tea.Msg {
+	ctx := m.storeContext()
+	msgs, err := m.store.GetLastMessages(ctx, 1)
+	if err != nil || len(msgs) == 0 {
+		return gptea.ResponseSavedMsg{Err: err}
+	}
+	prices, err := m.store.PriceTable(ctx)
+	if err != nil {
+		return gptea.ResponseSavedMsg{Message: msgs[0], Err: err}
+	}
+	meta := store.GetMessageMeta(msgs[0])
+	_, price, _ := prices.Lookup(meta.Model)
+	cost := price.Cost(meta.PromptTokens, meta.CompletionTokens)
+	return gptea.ResponseSavedMsg{Message: msgs[0], Cost: cost}
+}
+
+// promptTokens estimates the size of the prompt that sending what has been
+// typed would make: the persona, the messages sent as context and the text.
+// It is the same estimate the budget is checked with.
+func (m controlModel) promptTokens() int {
+	msgs := m.backlog.messages
+	start := max(len(msgs)-int(m.config.ClientConfig.MessageContext), 0)
+	tokens := 0
+	if m.config.preamble != "" {
+		tokens += pricing.EstimateTokens(m.config.preamble)
+	}
+	for i, msg := range msgs {
+		if msg.Excluded == 0 && (i >= start || msg.Pinned != 0) {
+			tokens += pricing.EstimateTokens(msg.Content)
+		}
+	}
+	text := strings.TrimSpace(m.prompt.ta.Value())
+	if _, _, ok := parseCommand(text); !ok && text != "" {
+		tokens += pricing.EstimateTokens(promptText(text))
+	}
+	return tokens
+}
+
+// loadBudget reports the spend so far against the budgets for the current
+// model.
+func (m controlModel) loadBudget() tea.Msg {
+	ctx := m.storeContext()
+	status, err := m.store.CheckBudget(ctx, m.config.ClientConfig.Model, 0)
+	return gptea.BudgetMsg{Status: status, Err: err}
+}
+
+// contextMessages returns the messages sent as context with the next prompt.
+// If branchFrom isn't 0, the prompt replaces that earlier message and the
+// context is what came before it.
+func (m controlModel) contextMessages(ctx context.Context, branchFrom int64, count int) ([]query.Message, error) {
+	if branchFrom != 0 {
+		return m.store.GetContextMessagesBefore(ctx, branchFrom, count)
+	}
+	return m.store.GetContextMessages(ctx, count)
+}
+
+// expand attaches the files mentioned in text to it.
+func (m controlModel) expand(text string) tea.Cmd {
+	return func() tea.Msg {
+		dir, err := os.Getwd()
+		if err != nil {
+			return gptea.PromptExpandedMsg{Text: text, Err: err}
+		}
+		expansion, err := attach.Expand(dir, text)
+		return gptea.PromptExpandedMsg{Text: text, Expansion: expansion, Err: err}
+	}
+}
+
+// handBack reports why the prompt wasn't sent and hands it back, so that it
+// can be sent again or changed.
+func (m controlModel) handBack(text string, err error) tea.Cmd {
+	return tea.Sequence(
+		gptea.MessageCmd(gptea.StreamCompletionResult{Err: err}),
+		gptea.MessageCmd(gptea.SetPromptMsg{Text: text}),
+	)
+}
+
+// checkBudget checks whether sending the prompt would go over budget. The
+// prompt is estimated from its text, with the files attached to it, and the
+// context that is sent along with it.
+func (m controlModel) checkBudget(text string, expansion attach.Expansion) tea.Cmd {
+	var branchFrom int64
+	if m.editing != nil {
+		branchFrom = m.editing.ID
+	}
+	return func() tea.Msg {
+		ctx := m.storeContext()
+		latest, err := m.contextMessages(ctx, branchFrom, int(m.config.ClientConfig.MessageContext))
+		if err != nil {
+			return gptea.BudgetCheckedMsg{Text: text, Expansion: expansion, Err: err}
+		}
+		tokens := pricing.EstimateTokens(expansion.Text)
+		for _, msg := range latest {
+			tokens += pricing.EstimateTokens(msg.Content)
+		}
+		status, err := m.store.CheckBudget(ctx, m.config.ClientConfig.Model, tokens)
+		return gptea.BudgetCheckedMsg{Text: text, Expansion: expansion, Status: status, Err: err}
+	}
+}
+
+// watch schedules the next check for changes made by other instances.
+func (m controlModel) watch() tea.Cmd {
+	return tea.Tick(watchInterval, func(time.Time) tea.Msg {
+		return gptea.WatchMsg{}
+	})
+}
+
+// checkChanged reloads the backlog if another instance changed the current
+// conversation.
+func (m controlModel) checkChanged() tea.Msg {
+	ctx := m.storeContext()
+	changed, err := m.store.ConversationChanged(ctx)
+	if err != nil || !changed {
+		return gptea.ConversationChangedMsg{Err: err}
+	}
+	msgs, err := m.store.GetLastMessages(ctx, defaultChatlogMaxSize)
+	return gptea.ConversationChangedMsg{Changed: true, Messages: msgs, Err: err}
+}
+
+// reloadChanged reloads the backlog after a change that was seen while a
+// completion was in flight.
+func (m controlModel) reloadChanged() tea.Msg {
+	msgs, err := m.store.GetLastMessages(m.storeContext(), defaultChatlogMaxSize)
+	return gptea.ConversationChangedMsg{Changed: true, Messages: msgs, Err: err}
 }
 
 func (m controlModel) loadBacklog() tea.Msg {
@@ -583,6 +1150,11 @@ func (m controlModel) renderBacklog() string {
 		re := m.renderMessage(msg)
 		re = strings.TrimSpace(re)
 		buf.WriteString(re)
+		if m.details {
+			if details := m.renderDetails(msg); details != "" {
+				buf.WriteString("\n" + details)
+			}
+		}
 		buf.WriteString("\n\n")
 	}
 	re := buf.String()
@@ -592,17 +1164,36 @@ func (m controlModel) renderBacklog() string {
 }
 
 func (m controlModel) renderMessage(msg query.Message) string {
-	width := m.width
-	if width > m.rhsPadding {
-		width -= m.rhsPadding
+	return m.render(msg, m.width)
+}
+
+// render renders a message as markdown to fit the specified terminal width.
+func (o uiOpts) render(msg query.Message, width int) string {
+	if width > o.rhsPadding {
+		width -= o.rhsPadding
 	}
 	role := msg.Role
-	role = m.styles.Role(role)
+	role = o.styles.Role(role)
+	if msg.Pinned != 0 {
+		role += " " + o.styles.Pin()
+	}
+	if msg.Excluded != 0 {
+		role += " " + o.styles.Excluded()
+	}
 
 	if msg.Content == "" {
 		return role
 	}
-	bs, err := markdown.RenderString(msg.Content, width)
+	content := msg.Content
+	if files := attach.Files(content); msg.Role == openai.ChatMessageRoleUser && len(files) > 0 {
+		// the files are sent and kept with the prompt, but only named here
+		content = attach.Strip(content) + "\n\nAttached `" + strings.Join(files, "`, `") + "`"
+	}
+	opts := []markdown.Option{markdown.WithTheme(o.theme.Markdown)}
+	if msg.Role == openai.ChatMessageRoleAssistant {
+		opts = append(opts, markdown.WithCodeNumbers())
+	}
+	bs, err := markdown.RenderString(content, width, opts...)
 	if err != nil {
 		panic(err)
 	}
@@ -617,18 +1208,101 @@ func (m controlModel) renderMessage(msg query.Message) string {
 	return strings.Join([]string{role, rendered.String()}, "\n")
 }
 
-func (m controlModel) completeStream(msg string) tea.Cmd {
+// renderDetails renders the metadata line shown under a response, or the
+// empty string if there is nothing to show.
+func (m controlModel) renderDetails(msg query.Message) string {
+	meta := store.GetMessageMeta(msg)
+	if msg.Role != openai.ChatMessageRoleAssistant || meta.IsZero() {
+		return ""
+	}
+	var parts []string
+	if meta.Model != "" {
+		model := meta.Model
+		if meta.ClientConfig != "" && meta.ClientConfig != meta.Model {
+			model += fmt.Sprintf(" (%s)", meta.ClientConfig)
+		}
+		parts = append(parts, model)
+	}
+	if meta.TTFT > 0 {
+		parts = append(parts, "first token "+formatDuration(meta.TTFT))
+	}
+	if meta.Duration > 0 {
+		parts = append(parts, "total "+formatDuration(meta.Duration))
+	}
+	if meta.PromptTokens+meta.CompletionTokens > 0 {
+		parts = append(parts, fmt.Sprintf("%d prompt + %d completion tokens", meta.PromptTokens, meta.CompletionTokens))
+	}
+	if meta.FinishReason != "" {
+		parts = append(parts, meta.FinishReason)
+	}
+	return m.styles.Details(strings.Join(parts, " · "))
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// send prints the prompt, with the files attached to it, and starts
+// streaming the response to it. If an earlier prompt is being edited, the
+// backlog is first rewound to it.
+func (m controlModel) send(expansion attach.Expansion) (controlModel, tea.Cmd) {
+	text := expansion.Text
+	seq := []tea.Cmd{}
+	var branchFrom int64
+	if m.editing != nil {
+		// the edited prompt replaces the original one, so the backlog
+		// is rewound to the point where the new branch starts.
+		branchFrom = m.editing.ID
+		m.editing = nil
+		m.branching = true
+		m.status.setEditing(false)
+		m.backlog.messages = pathBefore(m.branch.thread, branchFrom)
+		m.backlog.printed = false
+		seq = append(seq, gptea.ClearScrollback)
+		seq = append(seq, m.printBacklog())
+	}
+	um := query.Message{
+		Role:    openai.ChatMessageRoleUser,
+		Content: text,
+	}
+	m.backlog.messages = append(m.backlog.messages, um)
+	am := query.Message{
+		Role: openai.ChatMessageRoleAssistant,
+	}
+	m.backlog.messages = append(m.backlog.messages, am)
+	seq = append(seq,
+		tea.Println(""),
+		tea.Println(m.renderMessage(um)),
+	)
+	if len(expansion.Skipped) > 0 {
+		seq = append(seq, m.notice("Not attached: "+strings.Join(expansion.Skipped, ", ")+"."))
+	}
+	seq = append(seq,
+		tea.Println(m.renderMessage(am)),
+		m.completeStream(text, branchFrom),
+	)
+	return m, tea.Sequence(seq...)
+}
+
+// completeStream sends the prompt and streams the response. If branchFrom is
+// non-zero, the prompt is an edit of that earlier message and is added as a
+// sibling of it rather than at the end of the active branch.
+func (m controlModel) completeStream(msg string, branchFrom int64) tea.Cmd {
 	return func() tea.Msg {
 		csm := gptea.NewStreamCompletion()
 		go func() {
 			ctx, cancel := context.WithTimeout(context.Background(), m.clientTimeout)
 			defer cancel()
 			buf := new(bytes.Buffer) // we'll use this for saving the response
-			var usage openai.Usage
+			meta := store.MessageMeta{ClientConfig: m.config.ClientConfig.Name}
+			start := time.Now()
 			err := func() error {
 				clientHistory := m.config.ClientConfig.MessageContext
 				m.Log("Using client history", "val", clientHistory)
-				latest, err := m.store.GetLastMessages(ctx, int(clientHistory))
+				latest, err := m.contextMessages(ctx, branchFrom, int(clientHistory))
 				if err != nil {
 					return fmt.Errorf("load context: %w", err)
 				}
@@ -638,7 +1312,10 @@ func (m controlModel) completeStream(msg string) tea.Cmd {
 					return fmt.Errorf("failed to complete: %w", err)
 				}
 				req := streamResult.Req
-				err = m.store.SaveRequest(ctx, req)
+				meta.Model = req.Model
+				// the edited prompt only becomes the active branch once it
+				// is saved, so a request that fails leaves the branch as it was
+				err = m.store.SaveBranchRequest(ctx, req, branchFrom)
 				if err != nil {
 					return err
 				}
@@ -654,7 +1331,25 @@ func (m controlModel) completeStream(msg string) tea.Cmd {
 						return fmt.Errorf("recv: %w", err)
 					}
 
-					content := sr.Choices[0].Delta.Content
+					if sr.Model != "" {
+						meta.Model = sr.Model
+					}
+					if sr.Usage != nil {
+						meta.PromptTokens = sr.Usage.PromptTokens
+						meta.CompletionTokens = sr.Usage.CompletionTokens
+					}
+					if len(sr.Choices) == 0 {
+						// the usage chunk at the end has no choices
+						continue
+					}
+					choice := sr.Choices[0]
+					if choice.FinishReason != "" {
+						meta.FinishReason = string(choice.FinishReason)
+					}
+					content := choice.Delta.Content
+					if content != "" && meta.TTFT == 0 {
+						meta.TTFT = time.Since(start)
+					}
 					buf.WriteString(content)
 					err = csm.Write(ctx, content)
 					if err != nil {
@@ -663,8 +1358,9 @@ func (m controlModel) completeStream(msg string) tea.Cmd {
 				}
 			}()
 			buffered := buf.String()
+			meta.Duration = time.Since(start)
 			if err == nil {
-				err = m.store.SaveStreamResults(ctx, buffered, usage, err)
+				err = m.store.SaveStreamResults(ctx, buffered, meta)
 			}
 			csm.Close(err)
 		}()
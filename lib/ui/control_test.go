@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/keymap"
+	"github.com/collinvandyck/gpterm/lib/log"
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/collinvandyck/gpterm/lib/theme"
+	"github.com/collinvandyck/gpterm/lib/ui/gptea"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestModel returns a control model backed by a memory store.
+func newTestModel(keys keymap.Keymap) controlModel {
+	o := uiOpts{
+		Logger: log.Discard,
+		store:  store.NewMemory(),
+		theme:  theme.Default(),
+		keys:   keys,
+	}
+	o.styles = newStaticStyles(o.theme)
+	return newControlModel(o)
+}
+
+func TestConversationChangesStopEditing(t *testing.T) {
+	m := newTestModel(keymap.Default())
+	update := func(msg any) {
+		t.Helper()
+		model, _ := m.Update(msg)
+		m = model.(controlModel)
+	}
+	update(gptea.BranchEditMsg{Message: query.Message{ID: 3, Role: "user", Content: "hello"}})
+	require.NotNil(t, m.editing)
+	require.True(t, m.status.editing)
+
+	// nothing was switched to, so the edit goes on
+	update(gptea.ConversationSwitchedMsg{Err: store.ErrNoMoreConversations})
+	require.NotNil(t, m.editing)
+
+	update(gptea.ConversationSwitchedMsg{})
+	require.Nil(t, m.editing)
+	require.False(t, m.status.editing)
+
+	// nor is it kept when another instance changes the conversation
+	update(gptea.BranchEditMsg{Message: query.Message{ID: 3, Role: "user", Content: "hello"}})
+	require.NotNil(t, m.editing)
+	update(gptea.ConversationChangedMsg{})
+	require.NotNil(t, m.editing)
+	update(gptea.ConversationChangedMsg{Changed: true})
+	require.Nil(t, m.editing)
+	require.False(t, m.status.editing)
+}
+
+func TestChangesWhileSending(t *testing.T) {
+	m := newTestModel(keymap.Default())
+	update := func(msg any) {
+		t.Helper()
+		model, _ := m.Update(msg)
+		m = model.(controlModel)
+	}
+	m.ready, m.backlog.printed = true, true
+	m.inflight = true
+	m.backlog.messages = []query.Message{{Role: "user", Content: "sending"}}
+
+	// a change seen mid-send is held until the response is in
+	update(gptea.ConversationChangedMsg{Changed: true, Messages: []query.Message{{Role: "user", Content: "elsewhere"}}})
+	require.True(t, m.stale)
+	require.Equal(t, "sending", m.backlog.messages[0].Content)
+	update(gptea.WatchMsg{})
+	require.True(t, m.stale)
+
+	// and then reloaded, since the store won't report it again
+	m.inflight = false
+	update(gptea.WatchMsg{})
+	require.False(t, m.stale)
+	msg := m.reloadChanged()
+	require.Equal(t, gptea.ConversationChangedMsg{Changed: true}, msg)
+	update(msg)
+	require.Empty(t, m.backlog.messages)
+}
@@ -0,0 +1,11 @@
+package gptea
+
+import "github.com/collinvandyck/gpterm/lib/attach"
+
+// PromptExpandedMsg is sent once the files mentioned in a prompt have been
+// attached to it, before it is checked against the budget.
+type PromptExpandedMsg struct {
+	Text      string // the prompt as it was typed
+	Expansion attach.Expansion
+	Err       error
+}
@@ -0,0 +1,39 @@
+package gptea
+
+import (
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/store"
+)
+
+// ThreadMsg carries the message tree for the current conversation.
+type ThreadMsg struct {
+	Thread store.Thread
+	Select bool // open the message selector rather than the branch navigator
+	Err    error
+}
+
+// BranchSwitchedMsg is sent after the active branch of the current
+// conversation has changed.
+type BranchSwitchedMsg struct {
+	Thread   store.Thread
+	Selected int64
+	Err      error
+}
+
+// BranchEditMsg requests that an earlier user message be edited. Submitting
+// the edited prompt starts a new branch alongside the original message.
+type BranchEditMsg struct {
+	Message query.Message
+}
+
+// SetPromptMsg replaces the contents of the prompt.
+type SetPromptMsg struct {
+	Text string
+}
+
+// EditFailedMsg is sent when an edited prompt couldn't be sent, with the
+// branch that is active after it.
+type EditFailedMsg struct {
+	Thread store.Thread
+	Err    error // why the prompt couldn't be sent
+}
@@ -0,0 +1,21 @@
+package gptea
+
+import (
+	"github.com/collinvandyck/gpterm/lib/attach"
+	"github.com/collinvandyck/gpterm/lib/store"
+)
+
+// BudgetCheckedMsg is sent once a prompt has been checked against the
+// budget, before it is sent.
+type BudgetCheckedMsg struct {
+	Text      string           // the prompt as it was typed
+	Expansion attach.Expansion // the prompt with its files attached
+	Status    store.BudgetStatus
+	Err       error
+}
+
+// BudgetMsg reports the spend against the budget so far this month.
+type BudgetMsg struct {
+	Status store.BudgetStatus
+	Err    error
+}
@@ -0,0 +1,32 @@
+package gptea
+
+import "github.com/collinvandyck/gpterm/db/query"
+
+// CommandMsg is sent when a line starting with a slash, such as /tag work, is
+// entered at the prompt instead of a message.
+type CommandMsg struct {
+	Name string
+	Args []string
+}
+
+// NoticeMsg reports the outcome of a command that has nothing else to
+// show.
+type NoticeMsg struct {
+	Text string
+	Err  error
+}
+
+// TagsMsg reports the tags on a conversation after they have changed.
+type TagsMsg struct {
+	ConversationID int64
+	Tags           []string
+	Err            error
+}
+
+// PinnedMsg is sent after a message has been pinned or unpinned. Messages is
+// the reloaded backlog.
+type PinnedMsg struct {
+	Message  query.Message
+	Messages []query.Message
+	Err      error
+}
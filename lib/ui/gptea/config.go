@@ -6,7 +6,10 @@ import (
 )
 
 type ConfigLoadedMsg struct {
+	Conversation query.Conversation
 	Config       store.Config
 	ClientConfig query.ClientConfig
+	Persona      string // name of the persona of the conversation
+	Preamble     string // what the persona tells the model
 	Err          error
 }
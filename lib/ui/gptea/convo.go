@@ -8,6 +8,17 @@ type ConversationHistoryMsg struct {
 }
 
 type ConversationSwitchedMsg struct {
+	Messages []query.Message
+	Dropped  int64 // the id of the conversation moved to the trash, if any
+	Err      error
+}
+
+// WatchMsg is sent periodically to check whether the conversation was
+// changed by another gpterm.
+type WatchMsg struct{}
+
+type ConversationChangedMsg struct {
+	Changed  bool
 	Messages []query.Message
 	Err      error
 }
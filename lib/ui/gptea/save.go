@@ -0,0 +1,10 @@
+package gptea
+
+// CodeSavedMsg is sent after a code block was saved to Path. If the file was
+// already there and would have changed, nothing was saved and Diff shows the
+// changes for the user to confirm.
+type CodeSavedMsg struct {
+	Path string
+	Diff string
+	Err  error
+}
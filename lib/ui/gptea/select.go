@@ -0,0 +1,20 @@
+package gptea
+
+import (
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/store"
+)
+
+// MessageEditRequestMsg requests that a message be edited in place in the
+// user's editor.
+type MessageEditRequestMsg struct {
+	Message query.Message
+}
+
+// MessageUpdatedMsg is sent after a message in the current conversation was
+// edited, deleted, or excluded from or included in the context.
+type MessageUpdatedMsg struct {
+	Thread   store.Thread
+	Selected int64
+	Err      error
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/collinvandyck/gpterm/db/query"
 )
 
 type StreamCompletionReq struct {
@@ -113,3 +114,11 @@ func StaticStream(text string) tea.Cmd {
 		return csm
 	}
 }
+
+// ResponseSavedMsg carries a response as it was saved to the store, once
+// streaming has finished.
+type ResponseSavedMsg struct {
+	Message query.Message
+	Cost    float64 // in USD, or 0 if the model has no price
+	Err     error
+}
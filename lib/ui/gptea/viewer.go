@@ -0,0 +1,15 @@
+package gptea
+
+import "github.com/collinvandyck/gpterm/db/query"
+
+// ViewerPageMsg carries a page of messages for the history viewer, oldest
+// first. More reports whether there are older messages still to load.
+type ViewerPageMsg struct {
+	Messages []query.Message
+	More     bool
+	Err      error
+}
+
+// ViewerClosedMsg is sent after the history viewer has been closed and the
+// terminal is back on the main screen.
+type ViewerClosedMsg struct{}
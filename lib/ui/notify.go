@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/collinvandyck/gpterm/lib/term"
+	"github.com/collinvandyck/gpterm/lib/ui/gptea"
+)
+
+// notify notifies that the response finished if it took long enough that the
+// user may have gone to do something else. Nothing is notified while the
+// terminal has focus.
+func (m controlModel) notify(msg query.Message) tea.Cmd {
+	if m.terminal.focused() {
+		return nil
+	}
+	return func() tea.Msg {
+		ctx := m.storeContext()
+		method, err := m.store.GetConfigString(ctx, store.ConfigNotify, term.DefaultNotify)
+		if err != nil {
+			return gptea.ErrorMsg{Err: err}
+		}
+		if method == term.NotifyOff {
+			return nil
+		}
+		after, err := m.store.GetConfigInt(ctx, store.ConfigNotifyAfter, store.DefaultNotifyAfter)
+		if err != nil {
+			return gptea.ErrorMsg{Err: err}
+		}
+		if store.GetMessageMeta(msg).Duration < time.Duration(after)*time.Second {
+			return nil
+		}
+		convo, err := m.store.ActiveConversation(ctx)
+		if err != nil {
+			return gptea.ErrorMsg{Err: err}
+		}
+		title := fmt.Sprintf("gpterm: conversation %d", convo.ID)
+		if convo.Name.Valid {
+			title = "gpterm: " + convo.Name.String
+		}
+		err = term.Notify(os.Stdout, method, title, firstLine(msg.Content))
+		if err != nil {
+			return gptea.ErrorMsg{Err: fmt.Errorf("notify: %w", err)}
+		}
+		return nil
+	}
+}
+
+// firstLine returns the first line of text that isn't blank.
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
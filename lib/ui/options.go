@@ -1,7 +1,10 @@
 package ui
 
 import (
+	"github.com/collinvandyck/gpterm/lib/credential"
+	"github.com/collinvandyck/gpterm/lib/keymap"
 	"github.com/collinvandyck/gpterm/lib/log"
+	"github.com/collinvandyck/gpterm/lib/theme"
 )
 
 type Option func(*console)
@@ -11,3 +14,21 @@ func WithLogger(logger log.Logger) Option {
 		c.Logger = logger
 	}
 }
+
+func WithKeymap(keys keymap.Keymap) Option {
+	return func(c *console) {
+		c.keys = keys
+	}
+}
+
+func WithTheme(t theme.Theme) Option {
+	return func(c *console) {
+		c.theme = t
+	}
+}
+
+func WithCredentials(creds *credential.Credentials) Option {
+	return func(c *console) {
+		c.credentials = creds
+	}
+}
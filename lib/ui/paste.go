@@ -0,0 +1,278 @@
+package ui
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
+)
+
+const (
+	enableBracketedPaste  = "\x1b[?2004h"
+	disableBracketedPaste = "\x1b[?2004l"
+	enableFocusReporting  = "\x1b[?1004h"
+	disableFocusReporting = "\x1b[?1004l"
+	pasteTabWidth         = 4
+)
+
+// the markers a terminal puts around pasted text in bracketed paste mode
+var (
+	pasteStart = []byte("\x1b[200~")
+	pasteEnd   = []byte("\x1b[201~")
+)
+
+// what a terminal sends when it gains and loses focus, with focus reporting on
+var (
+	focusIn  = []byte("\x1b[I")
+	focusOut = []byte("\x1b[O")
+)
+
+// terminal puts the terminal in raw mode with bracketed paste and focus
+// reporting on, and reads its input through a pasteReader. bubbletea only
+// puts the terminal in raw mode itself when it reads the terminal directly,
+// so that is done here, along with putting it back in the mode it was in for
+// the editor.
+type terminal struct {
+	in       *os.File
+	out      io.Writer
+	state    *term.State // the mode the terminal was in
+	reported atomic.Bool // the terminal has reported its focus
+	focus    atomic.Bool // the terminal has focus, if it has reported it
+}
+
+// newTerminal returns the terminal on stdin, or nil if stdin isn't one.
+func newTerminal() *terminal {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil
+	}
+	return &terminal{in: os.Stdin, out: os.Stdout}
+}
+
+// raw puts the terminal in raw mode with bracketed paste and focus reporting
+// on.
+func (t *terminal) raw() error {
+	state, err := term.MakeRaw(int(t.in.Fd()))
+	if err != nil {
+		return err
+	}
+	t.state = state
+	_, err = io.WriteString(t.out, enableBracketedPaste+enableFocusReporting)
+	return err
+}
+
+// restore puts the terminal back in the mode it was in before raw.
+func (t *terminal) restore() error {
+	if t.state == nil {
+		return nil
+	}
+	io.WriteString(t.out, disableBracketedPaste+disableFocusReporting)
+	err := term.Restore(int(t.in.Fd()), t.state)
+	t.state = nil
+	return err
+}
+
+// input returns the reader bubbletea should read the terminal with.
+func (t *terminal) input() io.Reader {
+	return &pasteReader{File: t.in, focus: t.setFocus}
+}
+
+func (t *terminal) setFocus(focus bool) {
+	t.focus.Store(focus)
+	t.reported.Store(true)
+}
+
+// focused returns whether the terminal is known to have focus. Terminals that
+// don't report their focus, and those that aren't terminals, never have it.
+func (t *terminal) focused() bool {
+	return t != nil && t.reported.Load() && t.focus.Load()
+}
+
+// exec returns a tea.Cmd that runs cmd with the terminal in the mode it was
+// in before gpterm started, such as for the editor.
+func (t *terminal) exec(cmd *exec.Cmd, fn tea.ExecCallback) tea.Cmd {
+	if t == nil {
+		return tea.ExecProcess(cmd, fn)
+	}
+	// bubbletea would give the command the pasteReader, which exec copies
+	// through a pipe that stays blocked on the terminal after the command
+	// exits
+	if cmd.Stdin == nil {
+		cmd.Stdin = t.in
+	}
+	return tea.Exec(cookedCommand{Cmd: cmd, terminal: t}, fn)
+}
+
+// cookedCommand is a tea.ExecCommand that restores the terminal while the
+// command runs.
+type cookedCommand struct {
+	*exec.Cmd
+	terminal *terminal
+}
+
+func (c cookedCommand) Run() error {
+	if err := c.terminal.restore(); err != nil {
+		return err
+	}
+	err := c.Cmd.Run()
+	if rawErr := c.terminal.raw(); err == nil {
+		err = rawErr
+	}
+	return err
+}
+
+func (c cookedCommand) SetStdin(r io.Reader) {
+	if c.Stdin == nil {
+		c.Stdin = r
+	}
+}
+
+func (c cookedCommand) SetStdout(w io.Writer) {
+	if c.Stdout == nil {
+		c.Stdout = w
+	}
+}
+
+func (c cookedCommand) SetStderr(w io.Writer) {
+	if c.Stderr == nil {
+		c.Stderr = w
+	}
+}
+
+// pasteReader reads the terminal for bubbletea, which doesn't know about
+// bracketed paste and would drop pasted text along with the markers around
+// it. The markers are removed, line breaks in pasted text are turned into
+// Ctrl-J, which the prompt inserts as a newline instead of sending the
+// prompt, and tabs are turned into spaces. Focus reports, which bubbletea
+// would take as keys, are removed too and passed to focus.
+//
+// It embeds the file so that bubbletea can still cancel reads. Bytes are only
+// held back when the rest of them is still to be read, so that bubbletea
+// never waits on the file while there is input here.
+type pasteReader struct {
+	*os.File
+	pending []byte           // the start of a rune or marker, waiting for the rest
+	focus   func(focus bool) // called with each focus report, if set
+	pasting bool             // between the paste markers
+	cr      bool             // the last pasted byte was a carriage return
+}
+
+func (r *pasteReader) Read(p []byte) (int, error) {
+	// tabs grow when they are pasted, so read little enough that the
+	// result fits
+	buf := make([]byte, max(1, (len(p)-len(r.pending))/pasteTabWidth))
+	n, err := r.File.Read(buf)
+	data := append(r.pending, buf[:n]...)
+	r.pending = nil
+	out := r.filter(p[:0], data)
+	return len(out), err
+}
+
+func (r *pasteReader) filter(out []byte, data []byte) []byte {
+	if keep := partialRune(data); keep > 0 {
+		r.hold(data[len(data)-keep:])
+		data = data[:len(data)-keep]
+	}
+	for len(data) > 0 {
+		if !r.pasting {
+			i := bytes.Index(data, pasteStart)
+			if i < 0 {
+				// a lone escape may be the Esc key, so only hold back
+				// what can't be a key on its own
+				keep := partialMarker(data, pasteStart, 3)
+				r.hold(data[len(data)-keep:])
+				return r.typed(out, data[:len(data)-keep])
+			}
+			out = r.typed(out, data[:i])
+			data = data[i+len(pasteStart):]
+			r.pasting = true
+			continue
+		}
+		i := bytes.Index(data, pasteEnd)
+		if i < 0 {
+			keep := partialMarker(data, pasteEnd, 1)
+			r.hold(data[len(data)-keep:])
+			return r.pasted(out, data[:len(data)-keep])
+		}
+		out = r.pasted(out, data[:i])
+		data = data[i+len(pasteEnd):]
+		r.pasting = false
+	}
+	return out
+}
+
+// hold keeps bs to be read again with what follows. bs come before anything
+// already held.
+func (r *pasteReader) hold(bs []byte) {
+	r.pending = append(append([]byte{}, bs...), r.pending...)
+}
+
+// typed appends text that was typed rather than pasted to out, without the
+// focus reports in it.
+func (r *pasteReader) typed(out []byte, text []byte) []byte {
+	for {
+		i, focus := bytes.Index(text, focusIn), true
+		if j := bytes.Index(text, focusOut); j >= 0 && (i < 0 || j < i) {
+			i, focus = j, false
+		}
+		if i < 0 {
+			return append(out, text...)
+		}
+		out = append(out, text[:i]...)
+		text = text[i+len(focusIn):]
+		if r.focus != nil {
+			r.focus(focus)
+		}
+	}
+}
+
+// pasted appends pasted text to out.
+func (r *pasteReader) pasted(out []byte, text []byte) []byte {
+	for _, b := range text {
+		switch {
+		case b == '\r':
+			out = append(out, '\n')
+		case b == '\n':
+			if !r.cr {
+				out = append(out, '\n')
+			}
+		case b == '\t':
+			out = append(out, bytes.Repeat([]byte{' '}, pasteTabWidth)...)
+		case b < ' ' || b == 0x7f:
+			// other control characters would be taken as keys
+		default:
+			out = append(out, b)
+		}
+		r.cr = b == '\r'
+	}
+	return out
+}
+
+// partialRune returns how many bytes at the end of data are the start of a
+// rune whose other bytes haven't been read yet.
+func partialRune(data []byte) int {
+	for i := 1; i <= utf8.UTFMax && i <= len(data); i++ {
+		if start := len(data) - i; utf8.RuneStart(data[start]) {
+			if utf8.FullRune(data[start:]) {
+				return 0
+			}
+			return i
+		}
+	}
+	return 0
+}
+
+// partialMarker returns how many bytes at the end of data are the start of
+// the marker, or 0 if there are fewer than least of them.
+func partialMarker(data []byte, marker []byte, least int) int {
+	for n := len(marker) - 1; n >= least; n-- {
+		if bytes.HasSuffix(data, marker[:n]) {
+			return n
+		}
+	}
+	return 0
+}
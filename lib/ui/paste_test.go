@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasteFilter(t *testing.T) {
+	filter := func(chunks ...string) string {
+		r := &pasteReader{}
+		var res []byte
+		for _, chunk := range chunks {
+			data := append(r.pending, chunk...)
+			r.pending = nil
+			res = r.filter(res, data)
+		}
+		return string(res)
+	}
+	require.Equal(t, "abc", filter("abc"))
+	require.Equal(t, "\x1b[A\x1b", filter("\x1b[A\x1b"))
+	require.Equal(t, "xone\ntwo\n    threey", filter("x\x1b[200~one\r\ntwo\r\tthree\x1b[201~y"))
+	// markers and line breaks split across reads
+	require.Equal(t, "a\nb\n\rc", filter("\x1b[2", "00~a\r", "\nb\r\x1b", "[201~\rc"))
+	// escapes in pasted text aren't taken as keys
+	require.Equal(t, "a[31mb", filter("\x1b[200~a\x1b[31mb\x1b[201~"))
+	require.Equal(t, "héllo", filter("h\xc3", "\xa9llo"))
+}
+
+func TestFocusReports(t *testing.T) {
+	var reports []bool
+	r := &pasteReader{focus: func(focus bool) { reports = append(reports, focus) }}
+	require.Equal(t, "ab\x1b[Ac", string(r.filter(nil, []byte("a\x1b[Ob\x1b[A\x1b[Ic"))))
+	require.Equal(t, []bool{false, true}, reports)
+	// focus reports only come from the terminal, not in pasted text
+	require.Equal(t, "x[Iy", string(r.filter(nil, []byte("\x1b[200~x\x1b[Iy\x1b[201~"))))
+	require.Equal(t, []bool{false, true}, reports)
+
+	var term *terminal
+	require.False(t, term.focused())
+	term = &terminal{}
+	require.False(t, term.focused())
+	term.setFocus(true)
+	require.True(t, term.focused())
+	term.setFocus(false)
+	require.False(t, term.focused())
+}
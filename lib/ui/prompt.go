@@ -2,14 +2,29 @@ package ui
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"strings"
+	"unicode"
 
 	"github.com/charmbracelet/bubbles/cursor"
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/collinvandyck/gpterm/lib/attach"
 	"github.com/collinvandyck/gpterm/lib/errs"
 	"github.com/collinvandyck/gpterm/lib/ui/gptea"
+	"github.com/muesli/reflow/truncate"
+)
+
+const (
+	// commandHelpLines is the most lines of help shown while a command is
+	// being typed.
+	commandHelpLines = 6
+
+	// promptMaxHeight is the most lines the prompt grows to, unless the
+	// terminal is too short for it.
+	promptMaxHeight = 12
 )
 
 type promptModel struct {
@@ -17,7 +32,9 @@ type promptModel struct {
 	ta           textarea.Model
 	initialized  bool
 	ready        bool
-	height       int
+	height       int // the least lines the prompt takes up
+	maxHeight    int
+	multiline    bool   // enter adds a line, and alt+enter sends the prompt
 	idx          int    // 0 means current, positive is index in history
 	save         string // the current prompt, saved
 	inflight     bool   // if a client command is in flight
@@ -41,6 +58,8 @@ func (m promptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		taCmd tea.Cmd
 		cmds  commands
 	)
+	// the cursor line decides whether up and down are for history
+	line, lines := m.ta.Line(), m.ta.LineCount()
 	m.ta, taCmd = m.ta.Update(msg)
 
 	switch msg := msg.(type) {
@@ -57,15 +76,16 @@ func (m promptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.ta.Placeholder = "..."
 			cmds.Add(m.ta.Focus())
 			m.ta.Prompt = "┃ "
-			m.ta.CharLimit = 4096
+			m.ta.CharLimit = 0
+			m.ta.MaxHeight = 0
 			m.ta.FocusedStyle.CursorLine = lipgloss.NewStyle()
 			m.ta.ShowLineNumbers = false
-			m.ta.KeyMap.InsertNewline.SetEnabled(false)
+			m.ta.KeyMap.InsertNewline.SetKeys(m.newlineKeys()...)
 			m.initialized = true
 		}
 		m.width = msg.Width
+		m.maxHeight = max(m.height, min(promptMaxHeight, msg.Height/2))
 		m.ta.SetWidth(m.width)
-		m.ta.SetHeight(m.height)
 		m.ready = true
 
 	case promptHistory:
@@ -89,6 +109,12 @@ func (m promptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case gptea.ConversationSwitchedMsg:
 		m.idx = 0
 
+	case gptea.SetPromptMsg:
+		if m.initialized {
+			m.ta.SetValue(msg.Text)
+			m.idx = 0
+		}
+
 	case gptea.EditorResultMsg:
 		text := strings.TrimSpace(msg.Text)
 		taval := strings.TrimSpace(m.ta.Value())
@@ -108,18 +134,19 @@ func (m promptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		switch msg.Type {
 
-		case tea.KeyCtrlY:
-			if m.ready && !m.inflight {
-				cmds.Add(gptea.StartEditorCmd(m.ta.Value()))
-			}
-
 		case tea.KeyUp:
+			if line > 0 {
+				break
+			}
 			if m.idx == 0 {
 				m.save = m.ta.Value()
 			}
 			cmds.Add(m.getPrevious(1))
 
 		case tea.KeyDown:
+			if line < lines-1 {
+				break
+			}
 			switch m.idx {
 			case 1:
 				m.ta.SetValue(m.save)
@@ -129,27 +156,112 @@ func (m promptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				cmds.Add(m.getPrevious(-1))
 			}
 
+		case tea.KeyTab:
+			if text := m.ta.Value(); strings.HasPrefix(text, "/") {
+				m.ta.SetValue(completeCommand(m.uiOpts, text))
+			} else if dir, err := os.Getwd(); err == nil {
+				m.ta.SetValue(completeMention(dir, text))
+			}
+
 		case tea.KeyEnter:
+			if msg.Alt != m.multiline {
+				// the textarea took it as a newline
+				break
+			}
 			if !m.ready || m.inflight {
 				break
 			}
 			text := strings.TrimSpace(m.ta.Value())
+			if name, args, ok := parseCommand(text); ok {
+				cmds.Add(gptea.MessageCmd(gptea.CommandMsg{Name: name, Args: args}))
+				m.ta.Reset()
+				break
+			}
+			if text == "/" {
+				break
+			}
 			if text != "" {
-				req := gptea.MessageCmd(gptea.StreamCompletionReq{Text: text})
+				req := gptea.MessageCmd(gptea.StreamCompletionReq{Text: promptText(text)})
 				cmds.Add(req)
 				m.ta.Reset()
 				m.inflight = true
 			}
 		}
 	}
+	if m.initialized {
+		m = m.fit()
+	}
 	return m, cmds.BatchWith(taCmd)
 }
 
+// setMultiline switches between sending the prompt with enter, where
+// alt+enter adds a line, and the other way around.
+func (m promptModel) setMultiline(multiline bool) promptModel {
+	m.multiline = multiline
+	m.ta.KeyMap.InsertNewline.SetKeys(m.newlineKeys()...)
+	return m
+}
+
+// newlineKeys are the keys that add a line to the prompt. Ctrl+j is always
+// one of them, since pasted line breaks are read as ctrl+j.
+func (m promptModel) newlineKeys() []string {
+	if m.multiline {
+		return []string{"enter", "ctrl+j"}
+	}
+	return []string{"alt+enter", "ctrl+j"}
+}
+
+// fit grows or shrinks the prompt to the lines of its text, wrapped to its
+// width.
+func (m promptModel) fit() promptModel {
+	width := max(1, m.ta.Width())
+	rows := 0
+	for _, line := range strings.Split(m.ta.Value(), "\n") {
+		rows += max(1, (lipgloss.Width(line)+width-1)/width)
+	}
+	height := max(m.height, min(rows, m.maxHeight))
+	if height == m.ta.Height() {
+		return m
+	}
+	m.ta.SetHeight(height)
+	// the textarea only scrolls to keep the cursor in view, so lines that
+	// scrolled out while it was shorter stay hidden. Going to the top and
+	// back to the cursor shows as many of them as fit.
+	row := m.ta.Line()
+	li := m.ta.LineInfo()
+	col := li.StartColumn + li.CharOffset
+	for m.ta.Line() > 0 || m.ta.LineInfo().RowOffset > 0 {
+		m.ta.CursorUp()
+	}
+	m.ta, _ = m.ta.Update(nil)
+	for m.ta.Line() < row {
+		m.ta.CursorDown()
+	}
+	m.ta.SetCursor(col)
+	m.ta, _ = m.ta.Update(nil)
+	return m
+}
+
 func (m promptModel) View() string {
 	if !m.ready {
 		return ""
 	}
-	return m.ta.View()
+	help := commandHelp(m.ta.Value())
+	if len(help) == 0 {
+		return m.ta.View()
+	}
+	if len(help) > commandHelpLines {
+		more := len(help) - commandHelpLines + 1
+		help = append(help[:commandHelpLines-1], fmt.Sprintf("… %d more, Tab completes", more))
+	}
+	style := lipgloss.NewStyle().Foreground(m.theme.Secondary)
+	for i, line := range help {
+		if m.width > 0 {
+			line = truncate.StringWithTail(line, uint(m.width), "…")
+		}
+		help[i] = style.Render(line)
+	}
+	return strings.Join(help, "\n") + "\n" + m.ta.View()
 }
 
 func (m promptModel) getPrevious(inc int) tea.Cmd {
@@ -172,9 +284,10 @@ func (m promptModel) getPrevious(inc int) tea.Cmd {
 					err: err,
 					idx: idx,
 				}
-			case msg.Content != taVal:
+			case attach.Strip(msg.Content) != taVal:
+				// the files are attached again when it is sent
 				return promptHistory{
-					prompt: msg.Content,
+					prompt: attach.Strip(msg.Content),
 					found:  true,
 					idx:    idx,
 				}
@@ -187,3 +300,24 @@ func (m promptModel) update(msg tea.Msg) (promptModel, tea.Cmd) {
 	res, cmd := m.Update(msg)
 	return res.(promptModel), cmd
 }
+
+// completeMention completes the path of the file or directory mentioned at
+// the end of the prompt, relative to dir. Text that doesn't end in a mention
+// is returned as is. When there is more than one candidate the path is
+// completed as far as they agree.
+func completeMention(dir string, text string) string {
+	i := strings.LastIndexFunc(text, unicode.IsSpace) + 1
+	partial, ok := strings.CutPrefix(text[i:], "@")
+	if !ok {
+		return text
+	}
+	candidates := attach.Complete(dir, partial)
+	if len(candidates) == 0 {
+		return text
+	}
+	completed := commonPrefix(candidates)
+	if len(candidates) == 1 && !strings.HasSuffix(completed, "/") {
+		completed += " "
+	}
+	return text[:i] + "@" + completed
+}
@@ -0,0 +1,25 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompleteMention(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "lib", "store"), 0o755))
+	for _, file := range []string{"lib/store/memory.go", "lib/store/store.go", "main.go"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), nil, 0o644))
+	}
+	require.Equal(t, "explain @main.go ", completeMention(dir, "explain @ma"))
+	require.Equal(t, "explain\n@lib/", completeMention(dir, "explain\n@l"))
+	require.Equal(t, "@lib/store/", completeMention(dir, "@lib/st"))
+	require.Equal(t, "@lib/store/", completeMention(dir, "@lib/store/"))
+	require.Equal(t, "@lib/store/store.go ", completeMention(dir, "@lib/store/s"))
+	require.Equal(t, "me@ma", completeMention(dir, "me@ma"))
+	require.Equal(t, "@missing", completeMention(dir, "@missing"))
+	require.Equal(t, "explain ", completeMention(dir, "explain "))
+}
@@ -0,0 +1,303 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/collinvandyck/gpterm/lib/markdown"
+	"github.com/collinvandyck/gpterm/lib/ui/gptea"
+	"github.com/muesli/reflow/truncate"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const saveDiffLines = 12
+
+// saveModel saves the code blocks of a response to files, one after the
+// other. Each block is offered with a file name that the user can change,
+// and a file that is already there is only overwritten once the user has
+// seen the changes.
+type saveModel struct {
+	uiOpts
+	active  bool
+	blocks  []codeFile // the blocks left to save, starting with the current one
+	saved   []string   // what was saved so far
+	path    textinput.Model
+	diff    string // the changes to confirm before overwriting
+	err     error  // why the current block couldn't be saved
+	writing bool
+	width   int
+}
+
+// codeFile is a code block to save, and its number in the response.
+type codeFile struct {
+	markdown.CodeBlock
+	n int
+}
+
+func newSaveModel(uiOpts uiOpts) saveModel {
+	path := textinput.New()
+	path.Prompt = "Save to: "
+	return saveModel{
+		uiOpts: uiOpts,
+		path:   path,
+	}
+}
+
+func (m saveModel) Init() tea.Cmd {
+	return nil
+}
+
+// open starts saving blocks, offering a file name for the first of them.
+func (m saveModel) open(blocks []codeFile) (saveModel, tea.Cmd) {
+	m.active = true
+	m.blocks = blocks
+	m.saved = nil
+	return m.offer()
+}
+
+// save saves a single block to path, asking first if that would change a
+// file that is already there.
+func (m saveModel) save(block codeFile, path string) (saveModel, tea.Cmd) {
+	m, _ = m.open([]codeFile{block})
+	m.path.SetValue(path)
+	return m.write(false)
+}
+
+func (m saveModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds commands
+
+	switch msg := msg.(type) {
+
+	case gptea.WindowSizeMsg:
+		m.width = msg.Width
+		m.path.Width = max(m.width-len(m.path.Prompt)-2, 1)
+
+	case gptea.CodeSavedMsg:
+		if !m.active {
+			break
+		}
+		m.writing = false
+		m.path.SetValue(msg.Path)
+		m.path.CursorEnd()
+		switch {
+		case msg.Err != nil:
+			m.err = msg.Err
+		case msg.Diff != "":
+			m.diff = msg.Diff
+		default:
+			m.saved = append(m.saved, fmt.Sprintf("code block %d to %s", m.blocks[0].n, msg.Path))
+			var cmd tea.Cmd
+			m.blocks = m.blocks[1:]
+			m, cmd = m.offer()
+			cmds.Add(cmd)
+		}
+
+	case tea.KeyMsg:
+		if !m.active || m.writing {
+			break
+		}
+		if m.diff != "" {
+			if msg.String() == "y" {
+				var cmd tea.Cmd
+				m, cmd = m.write(true)
+				cmds.Add(cmd)
+				break
+			}
+			// back to changing the path
+			m.diff = ""
+			break
+		}
+		switch msg.Type {
+
+		case tea.KeyEnter:
+			if strings.TrimSpace(m.path.Value()) == "" {
+				break
+			}
+			var cmd tea.Cmd
+			m, cmd = m.write(false)
+			cmds.Add(cmd)
+
+		case tea.KeyTab:
+			var cmd tea.Cmd
+			m.blocks = m.blocks[1:]
+			m, cmd = m.offer()
+			cmds.Add(cmd)
+
+		case tea.KeyEsc:
+			m.blocks = nil
+			var cmd tea.Cmd
+			m, cmd = m.offer()
+			cmds.Add(cmd)
+
+		default:
+			var cmd tea.Cmd
+			m.path, cmd = m.path.Update(msg)
+			cmds.Add(cmd)
+		}
+	}
+	return m, cmds.BatchWith()
+}
+
+// offer offers the next block to be saved, or reports what was saved once
+// there are no more.
+func (m saveModel) offer() (saveModel, tea.Cmd) {
+	m.diff = ""
+	m.err = nil
+	if len(m.blocks) == 0 {
+		m.active = false
+		m.path.Blur()
+		text := "No code blocks were saved."
+		if len(m.saved) > 0 {
+			text = "Saved " + strings.Join(m.saved, ", ") + "."
+		}
+		return m, gptea.MessageCmd(gptea.NoticeMsg{Text: text})
+	}
+	block := m.blocks[0]
+	m.path.SetValue(codeFileName(block.n, block.Language))
+	m.path.CursorEnd()
+	return m, m.path.Focus()
+}
+
+// write saves the current block to the path that was entered.
+func (m saveModel) write(overwrite bool) (saveModel, tea.Cmd) {
+	m.writing = true
+	m.diff = ""
+	m.err = nil
+	block := m.blocks[0]
+	path := strings.TrimSpace(m.path.Value())
+	return m, func() tea.Msg {
+		if info, err := os.Stat(path); strings.HasSuffix(path, string(filepath.Separator)) || err == nil && info.IsDir() {
+			path = filepath.Join(path, codeFileName(block.n, block.Language))
+		}
+		diff, err := saveCode(path, block.Source, overwrite)
+		return gptea.CodeSavedMsg{Path: path, Diff: diff, Err: err}
+	}
+}
+
+func (m saveModel) View() string {
+	if !m.active {
+		return ""
+	}
+	block := m.blocks[0]
+	header := fmt.Sprintf("Code block %d", block.n)
+	if block.Language != "" {
+		header += " (" + block.Language + ")"
+	}
+	if len(m.blocks) > 1 {
+		header += fmt.Sprintf(", %d more after it", len(m.blocks)-1)
+	}
+	headerStyle := lipgloss.NewStyle().Bold(true)
+	secondary := lipgloss.NewStyle().Foreground(m.theme.Secondary)
+	alert := lipgloss.NewStyle().Foreground(m.theme.Alert)
+	lines := []string{headerStyle.Render(header)}
+	if m.diff != "" {
+		lines = append(lines, m.renderDiff()...)
+		lines = append(lines, alert.Render("CONFIRM")+secondary.Render(" y: Overwrite "+m.path.Value()+" | any other key: Change the path"))
+		return strings.Join(lines, "\n") + "\n"
+	}
+	lines = append(lines, m.path.View())
+	if m.err != nil {
+		lines = append(lines, alert.Render(m.err.Error()))
+	}
+	lines = append(lines, secondary.Render("Enter: Save | Tab: Skip | Esc: Done"))
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// renderDiff renders the changes to confirm, colored, up to saveDiffLines
+// of them.
+func (m saveModel) renderDiff() []string {
+	lines := strings.Split(strings.TrimRight(m.diff, "\n"), "\n")
+	more := 0
+	if len(lines) > saveDiffLines {
+		more = len(lines) - saveDiffLines
+		lines = lines[:saveDiffLines]
+	}
+	width := m.width - m.rhsPadding
+	for i, line := range lines {
+		style := lipgloss.NewStyle()
+		switch {
+		case strings.HasPrefix(line, "+"):
+			style = style.Foreground(m.theme.Added)
+		case strings.HasPrefix(line, "-"):
+			style = style.Foreground(m.theme.Removed)
+		case strings.HasPrefix(line, "@@"):
+			style = style.Foreground(m.theme.Secondary)
+		}
+		if width > 0 {
+			line = truncate.StringWithTail(line, uint(width), "…")
+		}
+		lines[i] = style.Render(line)
+	}
+	if more > 0 {
+		lines = append(lines, lipgloss.NewStyle().Foreground(m.theme.Secondary).Render(fmt.Sprintf("… %d more lines", more)))
+	}
+	return lines
+}
+
+// codeFileName is the name a code block is saved to unless the user picks
+// another one. The extension is the one chroma knows for the language of the
+// block, or .txt if there is none.
+func codeFileName(n int, language string) string {
+	ext := ".txt"
+	if lexer := lexers.Get(language); language != "" && lexer != nil {
+		for _, name := range lexer.Config().Filenames {
+			if !strings.ContainsAny(name, "*?[") {
+				// a language such as dockerfile whose files have a name
+				// rather than an extension
+				return name
+			}
+			if strings.HasPrefix(name, "*.") && !strings.ContainsAny(name[2:], "*?[") {
+				ext = name[1:]
+				break
+			}
+		}
+	}
+	return fmt.Sprintf("code-%d%s", n, ext)
+}
+
+// saveCode writes source to path, creating its directory if needed. A file
+// that is already there is only overwritten if overwrite is set. Otherwise
+// nothing is written and the returned diff shows how the file would change.
+func saveCode(path string, source string, overwrite bool) (diff string, err error) {
+	old, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return "", err
+	case string(old) == source:
+		return "", nil
+	case !overwrite:
+		return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        diffLines(string(old)),
+			B:        diffLines(source),
+			FromFile: path,
+			ToFile:   path,
+			Context:  3,
+		})
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	return "", os.WriteFile(path, []byte(source), 0o644)
+}
+
+// diffLines splits s into lines that each end in a newline, as difflib
+// expects. Unlike difflib.SplitLines it adds no empty line after the last.
+// A last line without a newline is marked the way git marks it, so that a
+// missing newline still shows up as a change.
+func diffLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n\\ No newline at end of file\n"
+	return lines
+}
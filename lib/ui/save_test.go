@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeFileName(t *testing.T) {
+	for _, tc := range []struct {
+		language string
+		want     string
+	}{
+		{"go", "code-1.go"},
+		{"python", "code-1.py"},
+		{"bash", "code-1.sh"},
+		{"yaml", "code-1.yaml"},
+		{"dockerfile", "Dockerfile"},
+		{"", "code-1.txt"},
+		{"no-such-language", "code-1.txt"},
+	} {
+		require.Equal(t, tc.want, codeFileName(1, tc.language), tc.language)
+	}
+}
+
+func TestSaveCode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "main.go")
+
+	diff, err := saveCode(path, "package main\n", false)
+	require.NoError(t, err)
+	require.Empty(t, diff)
+	bs, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "package main\n", string(bs))
+
+	// the same content is left alone
+	diff, err = saveCode(path, "package main\n", false)
+	require.NoError(t, err)
+	require.Empty(t, diff)
+
+	// other content is only written once confirmed
+	diff, err = saveCode(path, "package other\n", false)
+	require.NoError(t, err)
+	require.Contains(t, diff, "@@ -1 +1 @@\n")
+	require.Contains(t, diff, "-package main\n")
+	require.Contains(t, diff, "+package other\n")
+	bs, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "package main\n", string(bs))
+
+	diff, err = saveCode(path, "package other\n", true)
+	require.NoError(t, err)
+	require.Empty(t, diff)
+	bs, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "package other\n", string(bs))
+
+	// so is a change to only the newline at the end
+	require.NoError(t, os.WriteFile(path, []byte("package other"), 0o644))
+	diff, err = saveCode(path, "package other\n", false)
+	require.NoError(t, err)
+	require.Equal(t, "--- "+path+"\n+++ "+path+"\n@@ -1 +1 @@\n-package other\n\\ No newline at end of file\n+package other\n", diff)
+	bs, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "package other", string(bs))
+
+	_, err = saveCode(dir, "package main\n", true)
+	require.Error(t, err)
+}
@@ -0,0 +1,199 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/keymap"
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/collinvandyck/gpterm/lib/ui/gptea"
+	"github.com/muesli/reflow/truncate"
+)
+
+// selectModel lets the user walk every message on the active branch of the
+// conversation and pick one to edit, delete, or exclude from the context
+// sent with future requests.
+type selectModel struct {
+	uiOpts
+	active   bool
+	thread   store.Thread
+	selected int64 // the id of the selected message
+	deleting bool  // delete was pressed once and needs confirming
+	width    int
+}
+
+func (m selectModel) Init() tea.Cmd {
+	return nil
+}
+
+// open activates the selector on the last message of the thread. If there
+// are no messages there is nothing to select.
+func (m selectModel) open(thread store.Thread) selectModel {
+	m.thread = thread
+	m.deleting = false
+	path := thread.Path()
+	if len(path) == 0 {
+		m.active = false
+		return m
+	}
+	m.selected = path[len(path)-1].ID
+	m.active = true
+	return m
+}
+
+func (m selectModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds commands
+
+	switch msg := msg.(type) {
+
+	case gptea.WindowSizeMsg:
+		m.width = msg.Width
+
+	case gptea.MessageUpdatedMsg:
+		if msg.Err != nil {
+			break
+		}
+		m.thread = msg.Thread
+		m.selected = msg.Selected
+		if len(msg.Thread.Path()) == 0 {
+			m.active = false
+		}
+
+	case tea.KeyMsg:
+		if !m.active {
+			break
+		}
+		deleting := m.deleting
+		m.deleting = false
+		path := m.thread.Path()
+		idx := m.pathIndex(path)
+		sel, ok := m.thread.Get(m.selected)
+		switch {
+
+		case msg.Type == tea.KeyUp:
+			if idx > 0 {
+				m.selected = path[idx-1].ID
+			}
+
+		case msg.Type == tea.KeyDown:
+			if idx >= 0 && idx < len(path)-1 {
+				m.selected = path[idx+1].ID
+			}
+
+		case msg.Type == tea.KeyEsc, m.keys.Is(msg.String(), keymap.Select):
+			m.active = false
+
+		case !ok:
+
+		case msg.String() == "e":
+			cmds.Add(gptea.MessageCmd(gptea.MessageEditRequestMsg{Message: sel}))
+
+		case msg.String() == "c":
+			cmds.Add(m.copyText(sel.Content, fmt.Sprintf("message %d", idx+1)))
+
+		case msg.String() == "x":
+			cmds.Add(m.setExcluded(sel.ID, sel.Excluded == 0))
+
+		case msg.String() == "d":
+			if !deleting {
+				m.deleting = true
+				break
+			}
+			// select the message before the deleted one, or after it if
+			// it was the first
+			next := int64(0)
+			if idx > 0 {
+				next = path[idx-1].ID
+			} else if idx < len(path)-1 {
+				next = path[idx+1].ID
+			}
+			cmds.Add(m.delete(sel.ID, next))
+		}
+	}
+	return m, cmds.BatchWith()
+}
+
+func (m selectModel) View() string {
+	if !m.active {
+		return ""
+	}
+	path := m.thread.Path()
+	idx := m.pathIndex(path)
+	sel, _ := m.thread.Get(m.selected)
+
+	header := fmt.Sprintf("Message %d of %d (%s)", idx+1, len(path), m.styles.Name(sel.Role))
+	if sel.Pinned != 0 {
+		header += ", pinned"
+	}
+	if sel.Excluded != 0 {
+		header += ", excluded"
+	}
+	headerStyle := lipgloss.NewStyle().Bold(true)
+	previewStyle := lipgloss.NewStyle().Foreground(m.theme.Secondary)
+	lines := strings.Split(strings.TrimSpace(sel.Content), "\n")
+	if len(lines) > branchPreviewLines {
+		lines = append(lines[:branchPreviewLines], "…")
+	}
+	width := m.width - m.rhsPadding
+	for i, line := range lines {
+		if width > 0 {
+			line = truncate.StringWithTail(line, uint(width), "…")
+		}
+		lines[i] = previewStyle.Render(line)
+	}
+	exclude := "x: Exclude"
+	if sel.Excluded != 0 {
+		exclude = "x: Include"
+	}
+	help := previewStyle.Render("↑/↓: Message | e: Edit | c: Copy | d: Delete | " + exclude + " | Esc: Done")
+	if m.deleting {
+		confirm := lipgloss.NewStyle().Foreground(m.theme.Alert).Render("CONFIRM")
+		help = confirm + previewStyle.Render(" d: Delete this message | any other key: Cancel")
+	}
+	return strings.Join([]string{
+		headerStyle.Render(header),
+		strings.Join(lines, "\n"),
+		help,
+	}, "\n") + "\n"
+}
+
+func (m selectModel) pathIndex(path []query.Message) int {
+	for i, msg := range path {
+		if msg.ID == m.selected {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m selectModel) setExcluded(id int64, excluded bool) tea.Cmd {
+	return func() tea.Msg {
+		ctx := m.storeContext()
+		err := m.store.SetMessageExcluded(ctx, id, excluded)
+		if err != nil {
+			return gptea.MessageUpdatedMsg{Err: err}
+		}
+		thread, err := m.store.GetThread(ctx)
+		return gptea.MessageUpdatedMsg{Thread: thread, Selected: id, Err: err}
+	}
+}
+
+func (m selectModel) delete(id int64, next int64) tea.Cmd {
+	return func() tea.Msg {
+		ctx := m.storeContext()
+		err := m.store.DeleteMessage(ctx, id)
+		if err != nil {
+			return gptea.MessageUpdatedMsg{Err: err}
+		}
+		thread, err := m.store.GetThread(ctx)
+		return gptea.MessageUpdatedMsg{Thread: thread, Selected: next, Err: err}
+	}
+}
+
+func (m selectModel) storeContext() context.Context {
+	return context.Background()
+}
@@ -0,0 +1,183 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// slashCommand is a command entered at the prompt with a leading slash, such
+// as /tag work. Commands are registered with registerCommand, so that the
+// prompt can complete and describe them without knowing what they do.
+type slashCommand interface {
+	Name() string
+	Usage() string // the arguments the command takes, e.g. "[name]"
+	Help() string  // what the command does, in a few words
+	Run(m controlModel, args []string) (controlModel, tea.Cmd)
+}
+
+// slashCompleter is implemented by commands whose arguments can be
+// completed. Complete returns the candidates for the argument that follows
+// args.
+type slashCompleter interface {
+	Complete(o uiOpts, args []string) []string
+}
+
+// slashInfo implements the descriptive part of slashCommand, for commands to
+// embed.
+type slashInfo struct {
+	name  string
+	usage string
+	help  string
+}
+
+func (i slashInfo) Name() string  { return i.name }
+func (i slashInfo) Usage() string { return i.usage }
+func (i slashInfo) Help() string  { return i.help }
+
+var slashCommands = map[string]slashCommand{}
+
+// registerCommand makes the commands available at the prompt. It panics if
+// a name is taken, since that is a programming error.
+func registerCommand(cmds ...slashCommand) {
+	for _, cmd := range cmds {
+		if _, ok := slashCommands[cmd.Name()]; ok {
+			panic(fmt.Sprintf("command /%s is registered twice", cmd.Name()))
+		}
+		slashCommands[cmd.Name()] = cmd
+	}
+}
+
+// commandsWithPrefix returns the commands whose names start with prefix,
+// sorted by name.
+func commandsWithPrefix(prefix string) []slashCommand {
+	var res []slashCommand
+	for name, cmd := range slashCommands {
+		if strings.HasPrefix(name, prefix) {
+			res = append(res, cmd)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name() < res[j].Name() })
+	return res
+}
+
+// parseCommand splits a line entered at the prompt into the name of a
+// command and its arguments. ok is false if the line isn't a command, which
+// includes lines that start with a slash but not with the name of a command,
+// such as a path, and lines that start with two slashes.
+func parseCommand(text string) (name string, args []string, ok bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") || strings.HasPrefix(text, "//") || strings.Contains(text, "\n") {
+		return "", nil, false
+	}
+	fields := strings.Fields(text[1:])
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	if _, ok := slashCommands[fields[0]]; !ok {
+		return "", nil, false
+	}
+	return fields[0], fields[1:], true
+}
+
+// promptText returns the text to send for a line entered at the prompt that
+// isn't a command. Two slashes at the start send a prompt that would
+// otherwise be taken as a command, without the first of them.
+func promptText(text string) string {
+	if strings.HasPrefix(text, "//") {
+		return text[1:]
+	}
+	return text
+}
+
+// completeCommand completes the name of the command being typed at the
+// prompt, or the argument being typed if the command knows how. Text that
+// can't be completed is returned as is. When there is more than one
+// candidate the text is completed as far as they agree.
+func completeCommand(o uiOpts, text string) string {
+	if !strings.HasPrefix(text, "/") || strings.Contains(text, "\n") {
+		return text
+	}
+	fields := strings.Fields(text[1:])
+	partial := ""
+	if len(fields) > 0 && !strings.HasSuffix(text, " ") {
+		partial = fields[len(fields)-1]
+		fields = fields[:len(fields)-1]
+	}
+	var candidates []string
+	if len(fields) == 0 {
+		for _, cmd := range commandsWithPrefix(partial) {
+			candidates = append(candidates, cmd.Name())
+		}
+	} else {
+		cmd, ok := slashCommands[fields[0]]
+		if !ok {
+			return text
+		}
+		completer, ok := cmd.(slashCompleter)
+		if !ok {
+			return text
+		}
+		for _, c := range completer.Complete(o, fields[1:]) {
+			if strings.HasPrefix(c, partial) {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return text
+	}
+	completed := commonPrefix(candidates)
+	if len(candidates) == 1 {
+		completed += " "
+	}
+	return strings.TrimSuffix(text, partial) + completed
+}
+
+func commonPrefix(strs []string) string {
+	res := strs[0]
+	for _, s := range strs[1:] {
+		for !strings.HasPrefix(s, res) {
+			res = res[:len(res)-1]
+		}
+	}
+	return res
+}
+
+// commandHelp describes the commands that match what is being typed at the
+// prompt, one line each. It is empty if no command is being typed.
+func commandHelp(text string) []string {
+	if !strings.HasPrefix(text, "/") || strings.Contains(text, "\n") {
+		return nil
+	}
+	fields := strings.Fields(text[1:])
+	var cmds []slashCommand
+	switch {
+	case len(fields) == 0:
+		cmds = commandsWithPrefix("")
+	case len(fields) == 1 && !strings.HasSuffix(text, " "):
+		cmds = commandsWithPrefix(fields[0])
+	default:
+		if cmd, ok := slashCommands[fields[0]]; ok {
+			cmds = []slashCommand{cmd}
+		}
+	}
+	if len(cmds) == 0 {
+		return []string{"No such command. Try /help."}
+	}
+	res := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		res = append(res, commandUsage(cmd)+" · "+cmd.Help())
+	}
+	return res
+}
+
+func commandUsage(cmd slashCommand) string {
+	res := "/" + cmd.Name()
+	if cmd.Usage() != "" {
+		res += " " + cmd.Usage()
+	}
+	return res
+}
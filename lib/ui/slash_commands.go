@@ -0,0 +1,410 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/collinvandyck/gpterm/lib/keymap"
+	"github.com/collinvandyck/gpterm/lib/markdown"
+	"github.com/collinvandyck/gpterm/lib/persona"
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/collinvandyck/gpterm/lib/ui/gptea"
+	"github.com/sashabaranov/go-openai"
+)
+
+func init() {
+	registerCommand(
+		helpCommand{slashInfo{"help", "", "List the commands"}},
+		newCommand{slashInfo{"new", "", "Start a new conversation"}},
+		clearCommand{slashInfo{"clear", "", "Clear the screen, keeping the conversation"}},
+		titleCommand{slashInfo{"title", "[title]", "Show or set the title of the conversation"}},
+		exportCommand{slashInfo{"export", "[path]", "Write the conversation to a markdown file"}},
+		modelCommand{slashInfo{"model", "[name]", "Show or switch the model"}},
+		contextCommand{slashInfo{"context", "[n]", "Show or set how many messages are sent as context"}},
+		personaCommand{slashInfo{"persona", "[name]", "Show or switch the persona"}},
+		tagCommand{slashInfo{"tag", "<tag>...", "Tag the conversation"}, false},
+		tagCommand{slashInfo{"untag", "<tag>...", "Remove tags from the conversation"}, true},
+		tagsCommand{slashInfo{"tags", "", "Show the tags of the conversation"}},
+		filterCommand{slashInfo{"filter", "[tag]", "Only visit conversations with the tag"}},
+		pinCommand{slashInfo{"pin", "[n]", "Keep the nth latest message in context"}, true},
+		pinCommand{slashInfo{"unpin", "[n]", "Unpin the nth latest message"}, false},
+		copyCommand{slashInfo{"copy", "[n|all]", "Copy the latest response, its nth code block or all of its code"}},
+		saveCommand{slashInfo{"save", "[n [path]]", "Save the code blocks of the latest response to files"}},
+	)
+}
+
+// command runs a slash command entered at the prompt.
+func (m controlModel) command(msg gptea.CommandMsg) (controlModel, tea.Cmd) {
+	cmd, ok := slashCommands[msg.Name]
+	if !ok {
+		return m, m.error(fmt.Errorf("unknown command /%s. /help lists the commands", msg.Name))
+	}
+	return cmd.Run(m, msg.Args)
+}
+
+// usageError reports that a command was given the wrong arguments.
+func usageError(cmd slashCommand) error {
+	return fmt.Errorf("usage: %s", commandUsage(cmd))
+}
+
+type helpCommand struct{ slashInfo }
+
+func (c helpCommand) Run(m controlModel, args []string) (controlModel, tea.Cmd) {
+	var lines []string
+	for _, cmd := range commandsWithPrefix("") {
+		lines = append(lines, fmt.Sprintf("- `%s` %s", commandUsage(cmd), cmd.Help()))
+	}
+	return m, m.notice("Commands, which Tab completes:\n\n" + strings.Join(lines, "\n"))
+}
+
+type newCommand struct{ slashInfo }
+
+func (c newCommand) Run(m controlModel, args []string) (controlModel, tea.Cmd) {
+	if m.inflight {
+		return m, m.error(errors.New("wait for the response to finish before starting a new conversation"))
+	}
+	return m, func() tea.Msg {
+		ctx := m.storeContext()
+		err := m.store.NewConversation(ctx)
+		if err != nil {
+			return gptea.ConversationSwitchedMsg{Err: err}
+		}
+		msgs, err := m.store.GetLastMessages(ctx, defaultChatlogMaxSize)
+		return gptea.ConversationSwitchedMsg{Messages: msgs, Err: err}
+	}
+}
+
+type clearCommand struct{ slashInfo }
+
+func (c clearCommand) Run(m controlModel, args []string) (controlModel, tea.Cmd) {
+	m.backlog.messages = nil
+	m.backlog.set = true
+	m.backlog.printed = false
+	return m, tea.Sequence(gptea.ClearScrollback, m.printBacklog())
+}
+
+type titleCommand struct{ slashInfo }
+
+func (c titleCommand) Run(m controlModel, args []string) (controlModel, tea.Cmd) {
+	title := strings.Join(args, " ")
+	show := func() tea.Msg {
+		ctx := m.storeContext()
+		convo, err := m.store.ActiveConversation(ctx)
+		if err != nil {
+			return gptea.NoticeMsg{Err: err}
+		}
+		if title == "" {
+			if !convo.Name.Valid {
+				return gptea.NoticeMsg{Text: fmt.Sprintf("Conversation %d has no title.", convo.ID)}
+			}
+			return gptea.NoticeMsg{Text: fmt.Sprintf("Conversation %d is titled %q.", convo.ID, convo.Name.String)}
+		}
+		err = m.store.SetConversationName(ctx, convo.ID, title)
+		return gptea.NoticeMsg{Text: fmt.Sprintf("Conversation %d is now titled %q.", convo.ID, title), Err: err}
+	}
+	if title == "" {
+		return m, show
+	}
+	// the status bar shows the title
+	return m, tea.Sequence(show, m.loadConfig)
+}
+
+type exportCommand struct{ slashInfo }
+
+func (c exportCommand) Run(m controlModel, args []string) (controlModel, tea.Cmd) {
+	path := strings.Join(args, " ")
+	return m, func() tea.Msg {
+		ctx := m.storeContext()
+		convo, err := m.store.ActiveConversation(ctx)
+		if err != nil {
+			return gptea.NoticeMsg{Err: err}
+		}
+		thread, err := m.store.GetThread(ctx)
+		if err != nil {
+			return gptea.NoticeMsg{Err: err}
+		}
+		tags, err := m.store.GetConversationTags(ctx, convo.ID)
+		if err != nil {
+			return gptea.NoticeMsg{Err: err}
+		}
+		if path == "" {
+			path = fmt.Sprintf("conversation-%d.md", convo.ID)
+		}
+		export := store.NewExport(convo, tags, thread)
+		err = os.WriteFile(path, []byte(export.Markdown()), 0o644)
+		if err != nil {
+			return gptea.NoticeMsg{Err: err}
+		}
+		return gptea.NoticeMsg{Text: fmt.Sprintf("Exported %d messages to %s.", len(export.Messages), path)}
+	}
+}
+
+type modelCommand struct{ slashInfo }
+
+func (c modelCommand) Run(m controlModel, args []string) (controlModel, tea.Cmd) {
+	if len(args) > 1 {
+		return m, m.error(usageError(c))
+	}
+	if len(args) == 0 {
+		return m, func() tea.Msg {
+			names := c.Complete(m.uiOpts, nil)
+			return gptea.NoticeMsg{Text: fmt.Sprintf("Using %s. The models are %s.",
+				m.config.ClientConfig.Name, strings.Join(names, ", "))}
+		}
+	}
+	return m, func() tea.Msg {
+		ctx := m.storeContext()
+		convo, err := m.store.ActiveConversation(ctx)
+		if err != nil {
+			return gptea.ConfigLoadedMsg{Err: err}
+		}
+		err = m.store.SetConversationClientConfig(ctx, convo.ID, args[0])
+		if err != nil {
+			return gptea.ConfigLoadedMsg{Err: err}
+		}
+		return m.loadConfig()
+	}
+}
+
+func (c modelCommand) Complete(o uiOpts, args []string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+	configs, err := o.store.GetClientConfigs(context.Background())
+	if err != nil {
+		return nil
+	}
+	var res []string
+	for _, cc := range configs {
+		res = append(res, cc.Name)
+	}
+	return res
+}
+
+type contextCommand struct{ slashInfo }
+
+func (c contextCommand) Run(m controlModel, args []string) (controlModel, tea.Cmd) {
+	if len(args) == 0 {
+		return m, m.notice(fmt.Sprintf("Sending %d messages as context.", m.config.ClientConfig.MessageContext))
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || len(args) > 1 {
+		return m, m.error(usageError(c))
+	}
+	if n < minMessageContext || n > maxMessageContext {
+		return m, m.error(fmt.Errorf("the context must be between %d and %d messages", minMessageContext, maxMessageContext))
+	}
+	return m, m.changeConvoHistory(n - int(m.config.ClientConfig.MessageContext))
+}
+
+type personaCommand struct{ slashInfo }
+
+func (c personaCommand) Run(m controlModel, args []string) (controlModel, tea.Cmd) {
+	if len(args) > 1 {
+		return m, m.error(usageError(c))
+	}
+	if len(args) == 0 {
+		return m, func() tea.Msg {
+			current, err := m.store.GetPersona(m.storeContext())
+			if err != nil {
+				return gptea.NoticeMsg{Err: err}
+			}
+			names, err := persona.List(m.store.Dir())
+			return gptea.NoticeMsg{Text: fmt.Sprintf("Using the %s persona. The personas are %s.",
+				current, strings.Join(names, ", ")), Err: err}
+		}
+	}
+	name := args[0]
+	return m, func() tea.Msg {
+		ctx := m.storeContext()
+		if _, err := persona.Load(m.store.Dir(), name); err != nil {
+			return gptea.ConfigLoadedMsg{Err: err}
+		}
+		convo, err := m.store.ActiveConversation(ctx)
+		if err != nil {
+			return gptea.ConfigLoadedMsg{Err: err}
+		}
+		err = m.store.SetConversationPersona(ctx, convo.ID, name)
+		if err != nil {
+			return gptea.ConfigLoadedMsg{Err: err}
+		}
+		return m.loadConfig()
+	}
+}
+
+func (c personaCommand) Complete(o uiOpts, args []string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+	names, _ := persona.List(o.store.Dir())
+	return names
+}
+
+type tagCommand struct {
+	slashInfo
+	remove bool
+}
+
+func (c tagCommand) Run(m controlModel, args []string) (controlModel, tea.Cmd) {
+	if len(args) == 0 {
+		return m, m.error(usageError(c))
+	}
+	return m, m.tag(c.remove, args)
+}
+
+// Complete offers the tags of the conversation to be removed.
+func (c tagCommand) Complete(o uiOpts, args []string) []string {
+	if !c.remove {
+		return nil
+	}
+	ctx := context.Background()
+	convo, err := o.store.ActiveConversation(ctx)
+	if err != nil {
+		return nil
+	}
+	tags, _ := o.store.GetConversationTags(ctx, convo.ID)
+	return tags
+}
+
+type tagsCommand struct{ slashInfo }
+
+func (c tagsCommand) Run(m controlModel, args []string) (controlModel, tea.Cmd) {
+	return m, m.tag(false, nil)
+}
+
+type filterCommand struct{ slashInfo }
+
+func (c filterCommand) Run(m controlModel, args []string) (controlModel, tea.Cmd) {
+	if len(args) == 0 {
+		m.tagFilter = ""
+		m.status.setTagFilter("")
+		return m, m.notice("Navigating between all conversations.")
+	}
+	tag, err := store.NormalizeTag(args[0])
+	if err != nil {
+		return m, m.error(err)
+	}
+	m.tagFilter = tag
+	m.status.setTagFilter(tag)
+	var keys []string
+	for _, action := range []keymap.Action{keymap.PrevConversation, keymap.NextConversation} {
+		if key := m.keys.Help(action); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return m, m.notice(fmt.Sprintf("Only conversations tagged %s are visited. Use /filter to clear.", tag))
+	}
+	return m, m.notice(fmt.Sprintf("%s only visit conversations tagged %s. Use /filter to clear.", strings.Join(keys, "/"), tag))
+}
+
+type pinCommand struct {
+	slashInfo
+	pinned bool
+}
+
+func (c pinCommand) Run(m controlModel, args []string) (controlModel, tea.Cmd) {
+	n := 1
+	if len(args) > 0 {
+		var err error
+		n, err = strconv.Atoi(args[0])
+		if err != nil {
+			return m, m.error(fmt.Errorf("usage: /%s [n], where n counts back from the latest message", c.name))
+		}
+	}
+	if m.inflight {
+		return m, m.error(errors.New("wait for the response to finish before pinning"))
+	}
+	return m, m.pin(n, c.pinned)
+}
+
+type copyCommand struct{ slashInfo }
+
+func (c copyCommand) Run(m controlModel, args []string) (controlModel, tea.Cmd) {
+	if len(args) > 1 {
+		return m, m.error(usageError(c))
+	}
+	response := m.latestResponse()
+	if response == "" {
+		return m, m.error(errors.New("there is no response to copy"))
+	}
+	if len(args) == 0 {
+		return m, m.copyText(response, "the response")
+	}
+	blocks := markdown.CodeBlocks(response)
+	if len(blocks) == 0 {
+		return m, m.error(errors.New("the response has no code blocks"))
+	}
+	if args[0] == "all" {
+		sources := make([]string, 0, len(blocks))
+		for _, block := range blocks {
+			sources = append(sources, block.Source)
+		}
+		return m, m.copyText(strings.Join(sources, "\n"), fmt.Sprintf("%d code blocks", len(blocks)))
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return m, m.error(usageError(c))
+	}
+	if n < 1 || n > len(blocks) {
+		return m, m.error(fmt.Errorf("the response has code blocks 1 to %d", len(blocks)))
+	}
+	return m, m.copyText(blocks[n-1].Source, fmt.Sprintf("code block %d", n))
+}
+
+func (c copyCommand) Complete(o uiOpts, args []string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+	return []string{"all"}
+}
+
+type saveCommand struct{ slashInfo }
+
+func (c saveCommand) Run(m controlModel, args []string) (controlModel, tea.Cmd) {
+	response := m.latestResponse()
+	if response == "" {
+		return m, m.error(errors.New("there is no response to save"))
+	}
+	var blocks []codeFile
+	for i, block := range markdown.CodeBlocks(response) {
+		blocks = append(blocks, codeFile{CodeBlock: block, n: i + 1})
+	}
+	if len(blocks) == 0 {
+		return m, m.error(errors.New("the response has no code blocks"))
+	}
+	var cmd tea.Cmd
+	if len(args) == 0 {
+		m.saver, cmd = m.saver.open(blocks)
+		return m, cmd
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return m, m.error(usageError(c))
+	}
+	if n < 1 || n > len(blocks) {
+		return m, m.error(fmt.Errorf("the response has code blocks 1 to %d", len(blocks)))
+	}
+	if len(args) == 1 {
+		m.saver, cmd = m.saver.open(blocks[n-1 : n])
+		return m, cmd
+	}
+	m.saver, cmd = m.saver.save(blocks[n-1], strings.Join(args[1:], " "))
+	return m, cmd
+}
+
+// latestResponse returns the content of the latest response in the backlog,
+// or the empty string if there is none.
+func (m controlModel) latestResponse() string {
+	for i := len(m.backlog.messages) - 1; i >= 0; i-- {
+		if msg := m.backlog.messages[i]; msg.Role == openai.ChatMessageRoleAssistant {
+			return msg.Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/collinvandyck/gpterm/lib/keymap"
+	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompleteCommand(t *testing.T) {
+	o := uiOpts{store: store.NewMemory()}
+	for _, tc := range []struct{ text, want string }{
+		{"/mo", "/model "},
+		{"/t", "/t"}, // tag, tags and title
+		{"/ta", "/tag"},
+		{"/tags", "/tags "},
+		{"/zzz", "/zzz"},
+		{"/model gpt-4-", "/model gpt-4-turbo-preview "},
+		{"/model gpt-", "/model gpt-"},
+		{"/model gpt-3", "/model gpt-3.5-turbo "},
+		{"/model gpt-4o extra", "/model gpt-4o extra"},
+		{"/clear ", "/clear "},
+		{"hello", "hello"},
+	} {
+		require.Equal(t, tc.want, completeCommand(o, tc.text), tc.text)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	name, args, ok := parseCommand(" /title a new  title ")
+	require.True(t, ok)
+	require.Equal(t, "title", name)
+	require.Equal(t, []string{"a", "new", "title"}, args)
+	_, _, ok = parseCommand("/")
+	require.False(t, ok)
+	_, _, ok = parseCommand("/tag\nwork")
+	require.False(t, ok)
+	// a path or anything else that isn't a command is a prompt
+	_, _, ok = parseCommand("/usr/lib/libfoo.so fails to load")
+	require.False(t, ok)
+	_, _, ok = parseCommand("/nosuchcommand")
+	require.False(t, ok)
+	_, _, ok = parseCommand("//help me with this")
+	require.False(t, ok)
+	require.Equal(t, "/help me with this", promptText("//help me with this"))
+	require.Equal(t, "/usr/lib/libfoo.so", promptText("/usr/lib/libfoo.so"))
+	require.Len(t, commandHelp("/ta"), 2)
+	require.Equal(t, []string{"/model [name] · Show or switch the model"}, commandHelp("/model gp"))
+}
+
+func TestRunCommand(t *testing.T) {
+	run := func(m controlModel, text string) string {
+		t.Helper()
+		name, args, ok := parseCommand(text)
+		require.True(t, ok, text)
+		_, cmd := slashCommands[name].Run(m, args)
+		require.NotNil(t, cmd, text)
+		return fmt.Sprint(cmd())
+	}
+	m := newTestModel(keymap.Default())
+	require.Contains(t, run(m, "/context 0"), "between 1 and 20")
+	require.Contains(t, run(m, "/context 50"), "between 1 and 20")
+	require.Contains(t, run(m, "/filter work"), "Ctrl+p/Ctrl+n only visit conversations tagged work")
+
+	// the notice names the keys that are bound
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(keymap.Path(dir), []byte(`{"prev-conversation": [], "next-conversation": ["alt+j"]}`), 0o600))
+	keys, err := keymap.Load(dir)
+	require.NoError(t, err)
+	require.Contains(t, run(newTestModel(keys), "/filter work"), "Alt+j only visit conversations tagged work")
+}
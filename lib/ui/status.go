@@ -2,14 +2,23 @@ package ui
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/keymap"
+	"github.com/collinvandyck/gpterm/lib/persona"
 	"github.com/collinvandyck/gpterm/lib/store"
 	"github.com/collinvandyck/gpterm/lib/ui/gptea"
+	"github.com/muesli/reflow/truncate"
+)
+
+const (
+	statusSeparator = " │ "
+	statusMinTitle  = 12 // the title is only shortened this far before segments are dropped
 )
 
 type statusModel struct {
@@ -18,13 +27,27 @@ type statusModel struct {
 	width        int
 	spin         bool
 	ready        bool
+	conversation query.Conversation
 	config       store.Config
 	clientConfig query.ClientConfig
+	persona      string
+	promptTokens int       // the estimated size of the next prompt
+	sent         time.Time // when the request in flight was sent
+	firstToken   time.Time // when its first token arrived
+	streamed     int       // the characters of the response streamed so far
+	response     store.MessageMeta
+	cost         float64 // what the last response cost
 	drop         int
+	editing      bool
+	tagFilter    string
+	details      bool
+	multiline    bool
+	budget       store.BudgetStatus
+	chord        []string // keys pressed so far of a chord
 }
 
 func newStatusModel(uiOpts uiOpts) statusModel {
-	style := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF00"))
+	style := lipgloss.NewStyle().Foreground(uiOpts.theme.Spinner)
 	return statusModel{
 		uiOpts: uiOpts,
 		spinner: spinner.NewModel(
@@ -38,6 +61,41 @@ func (m *statusModel) setDrop(drop int) {
 	m.drop = drop
 }
 
+func (m *statusModel) setEditing(editing bool) {
+	m.editing = editing
+}
+
+func (m *statusModel) setMultiline(multiline bool) {
+	m.multiline = multiline
+}
+
+func (m *statusModel) setDetails(details bool) {
+	m.details = details
+}
+
+func (m *statusModel) setChord(chord []string) {
+	m.chord = chord
+}
+
+func (m *statusModel) setTagFilter(tag string) {
+	m.tagFilter = tag
+}
+
+func (m *statusModel) setPromptTokens(tokens int) {
+	m.promptTokens = tokens
+}
+
+// setStreamed records how much of the response has been streamed.
+func (m *statusModel) setStreamed(n int) {
+	if !m.spin || n <= m.streamed {
+		return
+	}
+	if m.streamed == 0 {
+		m.firstToken = time.Now()
+	}
+	m.streamed = n
+}
+
 func (m statusModel) Init() tea.Cmd {
 	return tea.Batch(m.tick())
 }
@@ -55,15 +113,38 @@ func (m statusModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case gptea.StreamCompletionReq:
 		m.spin = true
+		m.sent = time.Now()
+		m.firstToken = time.Time{}
+		m.streamed = 0
+		m.response = store.MessageMeta{}
+		m.cost = 0
 		cmds.Add(m.tick())
 
 	case gptea.StreamCompletionResult:
 		m.spin = false
 
+	case gptea.ResponseSavedMsg:
+		if msg.Err == nil {
+			m.response = store.GetMessageMeta(msg.Message)
+			m.cost = msg.Cost
+		}
+
 	case gptea.ConfigLoadedMsg:
 		if msg.Err == nil {
+			if msg.Conversation.ID != m.conversation.ID {
+				// the last response was in another conversation
+				m.response = store.MessageMeta{}
+				m.cost = 0
+			}
+			m.conversation = msg.Conversation
 			m.config = msg.Config
 			m.clientConfig = msg.ClientConfig
+			m.persona = msg.Persona
+		}
+
+	case gptea.BudgetMsg:
+		if msg.Err == nil {
+			m.budget = msg.Status
 		}
 
 	case spinner.TickMsg:
@@ -80,8 +161,130 @@ func (m statusModel) View() string {
 		return ""
 	}
 	spin := m.spinView()
+	bar := m.bar(m.width - lipgloss.Width(spin) - 1)
 	help := m.help(m.width - 1)
-	return spin + help
+	return spin + bar + "\n" + help
+}
+
+// statusSegment is a part of the status bar. When the bar is too narrow,
+// the segment that can shrink is shortened first, and then segments are
+// dropped, lowest priority first.
+type statusSegment struct {
+	text     string
+	priority int
+	shrink   bool
+}
+
+// bar renders the status bar, which describes the conversation, the next
+// request and the one in flight or the last response.
+func (m statusModel) bar(width int) string {
+	style := lipgloss.NewStyle().Background(m.theme.StatusBackground).Foreground(m.theme.StatusForeground)
+	title := fmt.Sprintf("#%d", m.conversation.ID)
+	if m.conversation.Name.Valid {
+		title += " " + m.conversation.Name.String
+	}
+	segments := []statusSegment{
+		{text: title, priority: 3, shrink: true},
+		{text: m.clientConfig.Model, priority: 4},
+		{text: fmt.Sprintf("context %d", m.clientConfig.MessageContext), priority: 1},
+		{text: "~" + formatTokens(m.promptTokens) + " tokens", priority: 2},
+	}
+	if metrics := m.metrics(); metrics != "" {
+		segments = append(segments, statusSegment{text: metrics, priority: 5})
+	}
+	return style.Width(width).Render(joinSegments(segments, width))
+}
+
+// metrics describes the request in flight as it streams, or the last
+// response once it has been saved.
+func (m statusModel) metrics() string {
+	var parts []string
+	switch {
+	case m.spin && m.firstToken.IsZero():
+		parts = append(parts, "waiting "+formatDuration(time.Since(m.sent).Truncate(100*time.Millisecond)))
+	case m.spin:
+		parts = append(parts, "first token "+formatDuration(m.firstToken.Sub(m.sent)))
+		if elapsed := time.Since(m.firstToken); elapsed >= time.Second {
+			tokens := float64(m.streamed) / 4 // as pricing.EstimateTokens counts them
+			parts = append(parts, fmt.Sprintf("%.0f tok/s", tokens/elapsed.Seconds()))
+		}
+	case !m.response.IsZero():
+		if m.response.TTFT > 0 {
+			parts = append(parts, "first token "+formatDuration(m.response.TTFT))
+		}
+		if streaming := m.response.Duration - m.response.TTFT; m.response.CompletionTokens > 0 && streaming > 0 {
+			parts = append(parts, fmt.Sprintf("%.0f tok/s", float64(m.response.CompletionTokens)/streaming.Seconds()))
+		}
+		if m.cost > 0 {
+			parts = append(parts, formatCost(m.cost))
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+// joinSegments joins the segments that fit in width, in their order.
+func joinSegments(segments []statusSegment, width int) string {
+	for len(segments) > 0 {
+		over := segmentsWidth(segments) - width
+		if over <= 0 {
+			break
+		}
+		shrunk := false
+		for i, s := range segments {
+			if w := lipgloss.Width(s.text); s.shrink && w > statusMinTitle {
+				segments[i].text = truncate.StringWithTail(s.text, uint(max(w-over, statusMinTitle)), "…")
+				shrunk = true
+				break
+			}
+		}
+		if shrunk {
+			continue
+		}
+		if len(segments) == 1 {
+			segments[0].text = truncate.StringWithTail(segments[0].text, uint(max(width, 0)), "…")
+			break
+		}
+		lowest := 0
+		for i, s := range segments {
+			if s.priority < segments[lowest].priority {
+				lowest = i
+			}
+		}
+		segments = append(segments[:lowest:lowest], segments[lowest+1:]...)
+	}
+	texts := make([]string, 0, len(segments))
+	for _, s := range segments {
+		texts = append(texts, s.text)
+	}
+	return strings.Join(texts, statusSeparator)
+}
+
+func segmentsWidth(segments []statusSegment) int {
+	res := 0
+	for i, s := range segments {
+		if i > 0 {
+			res += lipgloss.Width(statusSeparator)
+		}
+		res += lipgloss.Width(s.text)
+	}
+	return res
+}
+
+// formatTokens formats a token count compactly, such as 950 or 12.3k.
+func formatTokens(n int) string {
+	if n < 1000 {
+		return fmt.Sprint(n)
+	}
+	return fmt.Sprintf("%.1fk", float64(n)/1000)
+}
+
+// formatCost formats a cost in USD with enough precision for the fractions
+// of a cent that most responses cost.
+func formatCost(cost float64) string {
+	if cost < 0.01 {
+		return fmt.Sprintf("$%.4f", cost)
+	}
+	return fmt.Sprintf("$%.2f", cost)
 }
 
 func (m statusModel) tick() tea.Cmd {
@@ -95,15 +298,74 @@ func (m statusModel) spinView() string {
 }
 
 func (m statusModel) help(width int) string {
-	style := lipgloss.NewStyle().Background(lipgloss.Color("#222222")).Foreground(lipgloss.Color("#dddddd"))
+	style := lipgloss.NewStyle().Background(m.theme.StatusBackground).Foreground(m.theme.StatusForeground)
 	mc := m.clientConfig.MessageContext
 	model := m.clientConfig.Model
+	if m.persona != "" && m.persona != persona.Default {
+		model += ", " + m.persona
+	}
 	drop := ""
 	if m.drop == 1 {
-		style := lipgloss.NewStyle().Background(lipgloss.Color("#222222")).Foreground(lipgloss.Color("#dd0000"))
+		style := lipgloss.NewStyle().Background(m.theme.StatusBackground).Foreground(m.theme.Alert)
 		drop = " " + style.Render("CONFIRM")
 	}
-	text := fmt.Sprintf("↑/↓: History | Ctrl+y Editor | Ctrl+[p/n] Convo | Ctrl-x Drop%s | F1/F2 Context (%d) | F3 (%s)",
-		drop, mc, model)
+	edit := ""
+	if m.editing {
+		style := lipgloss.NewStyle().Background(m.theme.StatusBackground).Foreground(m.theme.Alert)
+		edit = style.Render("EDITING") + " Esc Cancel | "
+	}
+	details := ""
+	if m.details {
+		details = " (on)"
+	}
+	multiline := ""
+	if m.multiline {
+		multiline = " (on, Alt+Enter sends)"
+	}
+	convo := "Convo"
+	if m.tagFilter != "" {
+		convo = "Convo #" + m.tagFilter
+	}
+	if m.budget.Warning() {
+		color := m.theme.Warning
+		if m.budget.Exceeded || m.budget.Percent >= 100 {
+			color = m.theme.Alert
+		}
+		style := lipgloss.NewStyle().Background(m.theme.StatusBackground).Foreground(color)
+		scope := "Budget"
+		if m.budget.Model != "" {
+			scope = m.budget.Model + " budget"
+		}
+		edit = style.Render(fmt.Sprintf("%s %.0f%% of $%.2f", scope, m.budget.Percent, m.budget.Limit)) + " | " + edit
+	}
+	if profile := m.store.Profile(); profile != store.DefaultProfile {
+		edit = "[" + profile + "] " + edit
+	}
+	if len(m.chord) > 0 {
+		edit = keymap.Display(strings.Join(m.chord, " ")) + " … | " + edit
+	}
+	items := []string{"↑/↓: History"}
+	bind := func(text string, actions ...keymap.Action) {
+		var keys []string
+		for _, action := range actions {
+			if key := m.keys.Help(action); key != "" {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			items = append(items, strings.Join(keys, "/")+" "+text)
+		}
+	}
+	bind("Editor", keymap.Editor)
+	bind(convo, keymap.PrevConversation, keymap.NextConversation)
+	bind("Drop"+drop, keymap.DropConversation)
+	bind(fmt.Sprintf("Context (%d)", mc), keymap.LessContext, keymap.MoreContext)
+	bind("("+model+")", keymap.CycleModel)
+	bind("Branch", keymap.Branches)
+	bind("Details"+details, keymap.Details)
+	bind("Select", keymap.Select)
+	bind("History", keymap.History)
+	bind("Multi-line"+multiline, keymap.Multiline)
+	text := edit + strings.Join(items, " | ")
 	return style.Width(width).Render(text)
 }
@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinSegments(t *testing.T) {
+	segments := func() []statusSegment {
+		return []statusSegment{
+			{text: "#3 Planning the trip to Lisbon", priority: 3, shrink: true},
+			{text: "gpt-4o", priority: 4},
+			{text: "context 3", priority: 1},
+			{text: "~1.2k tokens", priority: 2},
+		}
+	}
+	for _, tc := range []struct {
+		width int
+		want  string
+	}{
+		{80, "#3 Planning the trip to Lisbon │ gpt-4o │ context 3 │ ~1.2k tokens"},
+		{60, "#3 Planning the trip to… │ gpt-4o │ context 3 │ ~1.2k tokens"},
+		{50, "#3 Planning t… │ gpt-4o │ context 3 │ ~1.2k tokens"},
+		{40, "#3 Planning… │ gpt-4o │ ~1.2k tokens"},
+		{30, "#3 Planning… │ gpt-4o"},
+		{12, "gpt-4o"},
+		{4, "gpt…"},
+	} {
+		got := joinSegments(segments(), tc.width)
+		require.Equal(t, tc.want, got, tc.width)
+		require.LessOrEqual(t, len([]rune(got)), tc.width)
+	}
+}
+
+func TestFormatTokens(t *testing.T) {
+	require.Equal(t, "950", formatTokens(950))
+	require.Equal(t, "12.3k", formatTokens(12345))
+	require.Equal(t, "$0.0031", formatCost(0.0031))
+	require.Equal(t, "$1.25", formatCost(1.25))
+}
@@ -1,20 +1,29 @@
 package ui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/collinvandyck/gpterm/lib/theme"
+)
 
 type styles interface {
 	Role(sender string) string
 	Name(sender string) string
+	Pin() string
+	Excluded() string
+	Details(text string) string
 }
 
 type staticStyles struct {
-	senders      map[string]lipgloss.Style
-	names        map[string]string
-	defaultStyle lipgloss.Style
+	senders       map[string]lipgloss.Style
+	names         map[string]string
+	defaultStyle  lipgloss.Style
+	pinStyle      lipgloss.Style
+	excludedStyle lipgloss.Style
+	detailsStyle  lipgloss.Style
 }
 
-func newStaticStyles() staticStyles {
-	senderStyle := func(color lipgloss.Color) lipgloss.Style {
+func newStaticStyles(t theme.Theme) staticStyles {
+	senderStyle := func(color lipgloss.TerminalColor) lipgloss.Style {
 		return lipgloss.NewStyle().
 			Bold(true).
 			Underline(true).
@@ -22,16 +31,21 @@ func newStaticStyles() staticStyles {
 	}
 	return staticStyles{
 		senders: map[string]lipgloss.Style{
-			"user":      senderStyle(lipgloss.Color("2")),
-			"assistant": senderStyle(lipgloss.Color("4")),
-			"error":     senderStyle(lipgloss.Color("#ff0000")),
+			"user":      senderStyle(t.User),
+			"assistant": senderStyle(t.Assistant),
+			"error":     senderStyle(t.Error),
+			"notice":    senderStyle(t.Notice),
 		},
 		names: map[string]string{
 			"user":      "You",
 			"assistant": "ChatGPT",
 			"error":     "Error",
+			"notice":    "gpterm",
 		},
-		defaultStyle: senderStyle(lipgloss.Color("3")),
+		defaultStyle:  senderStyle(t.Notice),
+		pinStyle:      lipgloss.NewStyle().Foreground(t.Pin),
+		excludedStyle: lipgloss.NewStyle().Foreground(t.Faint),
+		detailsStyle:  lipgloss.NewStyle().Foreground(t.Faint),
 	}
 }
 
@@ -54,3 +68,19 @@ func (ss staticStyles) Role(role string) string {
 	}
 	return style.Render(name)
 }
+
+// Pin returns the marker shown next to pinned messages.
+func (ss staticStyles) Pin() string {
+	return ss.pinStyle.Render("📌 pinned")
+}
+
+// Excluded returns the marker shown next to messages that are excluded from
+// the context.
+func (ss staticStyles) Excluded() string {
+	return ss.excludedStyle.Render("⊘ excluded")
+}
+
+// Details styles the metadata line shown under responses.
+func (ss staticStyles) Details(text string) string {
+	return ss.detailsStyle.Render(text)
+}
@@ -96,7 +96,7 @@ func (m *typewriterModel) render() {
 	if width > m.rhsPadding {
 		width -= m.rhsPadding
 	}
-	bs, _ := markdown.RenderString(data, width)
+	bs, _ := markdown.RenderString(data, width, markdown.WithTheme(m.theme.Markdown), markdown.WithCodeNumbers())
 	re := string(bs)
 	re = strings.TrimSpace(re)
 	m.rendered = strings.Split(re, "\n")
@@ -127,7 +127,7 @@ func (m *typewriterModel) reset() {
 }
 
 func (m typewriterModel) truncLines(lines []string) []string {
-	max := m.height - 6 // 3 for prompt, one for status bar, and one for padding
+	max := m.height - 7 // 3 for prompt, two for status bar, and one for padding
 	if max < 1 {
 		max = 1
 	}
@@ -6,8 +6,11 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/collinvandyck/gpterm/lib/client"
+	"github.com/collinvandyck/gpterm/lib/credential"
+	"github.com/collinvandyck/gpterm/lib/keymap"
 	"github.com/collinvandyck/gpterm/lib/log"
 	"github.com/collinvandyck/gpterm/lib/store"
+	"github.com/collinvandyck/gpterm/lib/theme"
 )
 
 type UI interface {
@@ -15,13 +18,15 @@ type UI interface {
 	Run(ctx context.Context) error
 }
 
-func New(store *store.Store, client client.Client, opts ...Option) UI {
+func New(store store.Repository, client client.Client, opts ...Option) UI {
 	console := &console{
 		uiOpts: uiOpts{
 			Logger:        log.Discard,
 			store:         store,
+			credentials:   credential.New(store, credential.WithPassphrase(credential.NoPassphrase)),
 			client:        client,
-			styles:        newStaticStyles(),
+			theme:         theme.Default(),
+			keys:          keymap.Default(),
 			clientTimeout: 5 * time.Minute,
 			rhsPadding:    2,
 		},
@@ -29,14 +34,19 @@ func New(store *store.Store, client client.Client, opts ...Option) UI {
 	for _, o := range opts {
 		o(console)
 	}
+	console.styles = newStaticStyles(console.theme)
 	return console
 }
 
 type uiOpts struct {
 	log.Logger
-	store         *store.Store
+	store         store.Repository
+	credentials   *credential.Credentials
 	client        client.Client
 	styles        styles
+	theme         theme.Theme
+	keys          keymap.Keymap // what the keys do
+	terminal      *terminal     // nil if stdin isn't a terminal
 	clientTimeout time.Duration // how long to wait for a response
 	rhsPadding    int           // RHS padding for rendered markdown
 }
@@ -54,8 +64,17 @@ func (t *console) Run(ctx context.Context) error {
 	t.Log("+-------------------+")
 	t.Log("| gpterm starting...|")
 	t.Log("+-------------------+")
+	var opts []tea.ProgramOption
+	t.terminal = newTerminal()
+	if t.terminal != nil {
+		if err := t.terminal.raw(); err != nil {
+			return err
+		}
+		defer t.terminal.restore()
+		opts = append(opts, tea.WithInput(t.terminal.input()))
+	}
 	model := newControlModel(t.uiOpts)
-	p := tea.NewProgram(model)
+	p := tea.NewProgram(model, opts...)
 	_, err := p.Run()
 	return err
 }
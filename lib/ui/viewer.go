@@ -0,0 +1,377 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/collinvandyck/gpterm/db/query"
+	"github.com/collinvandyck/gpterm/lib/keymap"
+	"github.com/collinvandyck/gpterm/lib/ui/gptea"
+	"github.com/muesli/reflow/truncate"
+)
+
+// historyPageSize is how many messages the history viewer loads at a time.
+// Older messages are loaded when the top of the history is reached.
+const historyPageSize = defaultChatlogMaxSize
+
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;?]*[a-zA-Z]")
+
+// viewerModel is a full screen viewer for the active branch of the current
+// conversation. It runs on the alternate screen so that the whole history
+// can be scrolled, searched and jumped through without leaving gpterm.
+type viewerModel struct {
+	uiOpts
+	active    bool
+	viewport  viewport.Model
+	search    textinput.Model
+	searching bool   // the search is being typed
+	query     string // the last search
+	matches   []int  // lines that match the search
+	match     int    // index of the current match
+	messages  []query.Message
+	rendered  []string // rendered messages, in the same order
+	offsets   []int    // the line each message starts on
+	lines     []string // the content without styling, for searching
+	more      bool     // there are older messages to load
+	loading   bool
+	width     int
+	height    int
+}
+
+func newViewerModel(uiOpts uiOpts) viewerModel {
+	search := textinput.New()
+	search.Prompt = "/"
+	return viewerModel{
+		uiOpts:   uiOpts,
+		viewport: viewport.New(0, 0),
+		search:   search,
+	}
+}
+
+func (m viewerModel) Init() tea.Cmd {
+	return nil
+}
+
+// open switches to the alternate screen and loads the most recent messages.
+func (m viewerModel) open() (viewerModel, tea.Cmd) {
+	m.active = true
+	m.loading = true
+	m.searching = false
+	m.query = ""
+	m.matches = nil
+	m.messages, m.rendered = nil, nil
+	m.more = false
+	m.resize()
+	return m, tea.Batch(tea.EnterAltScreen, tea.EnableMouseCellMotion, m.load(0))
+}
+
+// close leaves the alternate screen.
+func (m viewerModel) close() (viewerModel, tea.Cmd) {
+	m.active = false
+	m.search.Blur()
+	return m, tea.Sequence(
+		tea.DisableMouse,
+		tea.ExitAltScreen,
+		gptea.MessageCmd(gptea.ViewerClosedMsg{}),
+	)
+}
+
+func (m viewerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds commands
+
+	switch msg := msg.(type) {
+
+	case gptea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.resize()
+		if m.active && msg.Ready {
+			// the width changed, so everything has to be rendered again
+			for i, message := range m.messages {
+				m.rendered[i] = m.renderViewed(message)
+			}
+			m.setContent()
+		}
+
+	case gptea.ViewerPageMsg:
+		if !m.active {
+			break
+		}
+		m.loading = false
+		if msg.Err != nil {
+			cmds.Add(gptea.ErrorCmd(msg.Err))
+			break
+		}
+		m.more = msg.More
+		rendered := make([]string, len(msg.Messages))
+		for i, message := range msg.Messages {
+			rendered[i] = m.renderViewed(message)
+		}
+		first := len(m.messages) == 0
+		before := m.viewport.TotalLineCount()
+		m.messages = append(msg.Messages, m.messages...)
+		m.rendered = append(rendered, m.rendered...)
+		m.setContent()
+		if first {
+			m.viewport.GotoBottom()
+		} else {
+			// keep the same lines in view as more are added above them
+			m.viewport.SetYOffset(m.viewport.YOffset + m.viewport.TotalLineCount() - before)
+		}
+
+	case tea.KeyMsg:
+		if !m.active {
+			break
+		}
+		if m.searching {
+			switch msg.Type {
+			case tea.KeyEnter:
+				m.searching = false
+				m.search.Blur()
+				m.query = strings.TrimSpace(m.search.Value())
+				m.findMatches()
+				m.match = m.nextMatch(m.viewport.YOffset)
+				m.showMatch()
+			case tea.KeyEsc:
+				m.searching = false
+				m.search.Blur()
+			default:
+				var cmd tea.Cmd
+				m.search, cmd = m.search.Update(msg)
+				cmds.Add(cmd)
+			}
+			break
+		}
+		key := msg.String()
+		if m.keys.Is(key, keymap.History) {
+			key = "q"
+		}
+		switch key {
+		case "esc", "q":
+			if m.query != "" && key == "esc" {
+				m.query = ""
+				m.matches = nil
+				m.setContent()
+				break
+			}
+			var cmd tea.Cmd
+			m, cmd = m.close()
+			cmds.Add(cmd)
+		case "/":
+			m.searching = true
+			m.search.SetValue("")
+			cmds.Add(m.search.Focus())
+		case "n", "N":
+			if len(m.matches) == 0 {
+				break
+			}
+			dir := 1
+			if key == "N" {
+				dir = -1
+			}
+			m.match = (m.match + dir + len(m.matches)) % len(m.matches)
+			m.showMatch()
+		case "[":
+			m.jump(-1)
+		case "]":
+			m.jump(+1)
+		case "g", "home":
+			m.viewport.GotoTop()
+		case "G", "end":
+			m.viewport.GotoBottom()
+		default:
+			var cmd tea.Cmd
+			m.viewport, cmd = m.viewport.Update(msg)
+			cmds.Add(cmd)
+		}
+		cmds.Add(m.loadMore())
+
+	case tea.MouseMsg:
+		if !m.active {
+			break
+		}
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		cmds.Add(cmd)
+		cmds.Add(m.loadMore())
+	}
+	return m, cmds.BatchWith()
+}
+
+func (m viewerModel) View() string {
+	if !m.active {
+		return ""
+	}
+	headerStyle := lipgloss.NewStyle().Bold(true)
+	footerStyle := lipgloss.NewStyle().Foreground(m.theme.Secondary)
+
+	header := "History"
+	if len(m.messages) > 0 {
+		header += fmt.Sprintf(" · message %d of %d", m.current()+1, len(m.messages))
+		if m.more {
+			header += " (scroll up for older)"
+		}
+	}
+	if m.loading {
+		header += " · loading…"
+	}
+	header += fmt.Sprintf(" · %3.f%%", m.viewport.ScrollPercent()*100)
+
+	var footer string
+	switch {
+	case m.searching:
+		footer = m.search.View()
+	case m.query != "" && len(m.matches) == 0:
+		footer = footerStyle.Render(fmt.Sprintf("No matches for %q | /: Search | Esc: Clear", m.query))
+	case m.query != "":
+		footer = footerStyle.Render(fmt.Sprintf("Match %d of %d for %q | n/N: Next/Previous | Esc: Clear",
+			m.match+1, len(m.matches), m.query))
+	default:
+		footer = footerStyle.Render("↑/↓ PgUp/PgDn: Scroll | [/]: Message | g/G: Top/Bottom | /: Search | q: Close")
+	}
+	if m.width > 0 {
+		header = truncate.StringWithTail(header, uint(m.width), "…")
+		footer = truncate.StringWithTail(footer, uint(m.width), "…")
+	}
+	return strings.Join([]string{
+		headerStyle.Render(header),
+		m.viewport.View(),
+		footer,
+	}, "\n")
+}
+
+// resize fits the viewport between the header and the footer.
+func (m *viewerModel) resize() {
+	m.viewport.Width = m.width
+	m.viewport.Height = max(m.height-2, 1)
+	m.search.Width = max(m.width-2, 1)
+}
+
+func (m viewerModel) renderViewed(msg query.Message) string {
+	return strings.TrimSpace(m.render(msg, m.width))
+}
+
+// setContent joins the rendered messages and hands them to the viewport,
+// highlighting the current search match.
+func (m *viewerModel) setContent() {
+	m.offsets = m.offsets[:0]
+	var lines []string
+	for i, re := range m.rendered {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		m.offsets = append(m.offsets, len(lines))
+		lines = append(lines, strings.Split(re, "\n")...)
+	}
+	m.lines = make([]string, len(lines))
+	for i, line := range lines {
+		m.lines[i] = ansiPattern.ReplaceAllString(line, "")
+	}
+	m.findMatches()
+	if m.match < len(m.matches) {
+		line := m.matches[m.match]
+		lines[line] = lipgloss.NewStyle().Reverse(true).Render(m.lines[line])
+	}
+	m.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// findMatches finds the lines that contain the search, ignoring case.
+func (m *viewerModel) findMatches() {
+	m.matches = m.matches[:0]
+	if m.query == "" {
+		return
+	}
+	query := strings.ToLower(m.query)
+	for i, line := range m.lines {
+		if strings.Contains(strings.ToLower(line), query) {
+			m.matches = append(m.matches, i)
+		}
+	}
+	if m.match >= len(m.matches) {
+		m.match = 0
+	}
+}
+
+// nextMatch returns the index of the first match at or after line, wrapping
+// around to the first match if there is none.
+func (m viewerModel) nextMatch(line int) int {
+	for i, match := range m.matches {
+		if match >= line {
+			return i
+		}
+	}
+	return 0
+}
+
+// showMatch highlights the current match and scrolls it into view.
+func (m *viewerModel) showMatch() {
+	m.setContent()
+	if m.match < len(m.matches) {
+		// with a little context above the match
+		m.viewport.SetYOffset(max(m.matches[m.match]-2, 0))
+	}
+}
+
+// current returns the index of the message at the top of the view.
+func (m viewerModel) current() int {
+	res := 0
+	for i, offset := range m.offsets {
+		if offset <= m.viewport.YOffset {
+			res = i
+		}
+	}
+	return res
+}
+
+// jump scrolls to the start of the previous or next message.
+func (m *viewerModel) jump(delta int) {
+	if len(m.offsets) == 0 {
+		return
+	}
+	idx := m.current()
+	if delta < 0 && m.offsets[idx] < m.viewport.YOffset {
+		// go to the start of the message that is partly in view first
+		delta = 0
+	}
+	idx = min(max(idx+delta, 0), len(m.offsets)-1)
+	m.viewport.SetYOffset(m.offsets[idx])
+}
+
+// loadMore loads older messages once the top of the history is in view.
+func (m *viewerModel) loadMore() tea.Cmd {
+	if !m.more || m.loading || !m.viewport.AtTop() || len(m.messages) == 0 {
+		return nil
+	}
+	m.loading = true
+	return m.load(m.messages[0].ID)
+}
+
+// load loads the page of messages on the active branch that precedes the
+// message with the id, or the most recent page if the id is zero.
+func (m viewerModel) load(before int64) tea.Cmd {
+	return func() tea.Msg {
+		ctx := m.storeContext()
+		thread, err := m.store.GetThread(ctx)
+		if err != nil {
+			return gptea.ViewerPageMsg{Err: err}
+		}
+		path := thread.Path()
+		if before != 0 {
+			path = pathBefore(thread, before)
+		}
+		more := len(path) > historyPageSize
+		if more {
+			path = path[len(path)-historyPageSize:]
+		}
+		return gptea.ViewerPageMsg{Messages: path, More: more}
+	}
+}
+
+func (m viewerModel) storeContext() context.Context {
+	return context.Background()
+}